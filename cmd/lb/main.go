@@ -0,0 +1,101 @@
+package main
+
+import (
+	"context"
+	"log"
+	"net/http"
+	"os"
+	"os/signal"
+	"strconv"
+	"syscall"
+	"time"
+
+	"rinha-de-backend-2025/internal/lb"
+)
+
+// shutdownTimeout limita a espera pelas requisições em andamento no encerramento
+const shutdownTimeout = 10 * time.Second
+
+func main() {
+	port := getEnvOrDefault("PORT", "9999")
+	backendList := getEnvOrDefault("LB_BACKENDS", "unix:/var/run/rinha/api01.sock,unix:/var/run/rinha/api02.sock")
+
+	cfg := lb.DefaultConfig()
+	cfg.HealthPath = getEnvOrDefault("LB_HEALTH_PATH", cfg.HealthPath)
+	cfg.HealthInterval = getEnvDuration("LB_HEALTH_INTERVAL", cfg.HealthInterval)
+	cfg.HealthTimeout = getEnvDuration("LB_HEALTH_TIMEOUT", cfg.HealthTimeout)
+	cfg.DialTimeout = getEnvDuration("LB_DIAL_TIMEOUT", cfg.DialTimeout)
+	cfg.MaxIdleConnsPerBackend = getEnvInt("LB_MAX_IDLE_CONNS", cfg.MaxIdleConnsPerBackend)
+
+	backends, err := lb.ParseBackends(backendList, cfg)
+	if err != nil {
+		log.Fatalf("Erro ao configurar backends: %v", err)
+	}
+
+	log.Printf("=== Load Balancer Rinha (least-connections) ===")
+	log.Printf("Porta: %s", port)
+	for _, backend := range backends {
+		log.Printf("Backend: %s", backend.Address)
+	}
+	log.Printf("Health check: GET %s a cada %v", cfg.HealthPath, cfg.HealthInterval)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	balancer := lb.New(backends, cfg)
+	balancer.Start(ctx)
+	defer balancer.CloseIdleConnections()
+
+	server := &http.Server{Addr: ":" + port, Handler: balancer}
+
+	serveErr := make(chan error, 1)
+	go func() {
+		serveErr <- server.ListenAndServe()
+	}()
+
+	c := make(chan os.Signal, 1)
+	signal.Notify(c, os.Interrupt, syscall.SIGTERM)
+
+	select {
+	case <-c:
+		log.Printf("🛑 Sinal de parada recebido, encerrando load balancer...")
+	case err := <-serveErr:
+		log.Fatalf("Erro ao iniciar load balancer: %v", err)
+	}
+
+	shutdownCtx, cancelShutdown := context.WithTimeout(context.Background(), shutdownTimeout)
+	defer cancelShutdown()
+	if err := server.Shutdown(shutdownCtx); err != nil {
+		log.Printf("⚠️ Requisições não finalizadas no shutdown: %v", err)
+	}
+}
+
+// getEnvOrDefault retorna uma variável de ambiente ou valor padrão
+func getEnvOrDefault(key, defaultValue string) string {
+	if value := os.Getenv(key); value != "" {
+		return value
+	}
+	return defaultValue
+}
+
+// getEnvDuration retorna uma variável de ambiente de duração ou valor padrão
+func getEnvDuration(key string, defaultValue time.Duration) time.Duration {
+	if value := os.Getenv(key); value != "" {
+		if parsed, err := time.ParseDuration(value); err == nil {
+			return parsed
+		}
+		log.Printf("Aviso: valor inválido para %s: %s", key, value)
+	}
+	return defaultValue
+}
+
+// getEnvInt retorna uma variável de ambiente inteira ou valor padrão
+func getEnvInt(key string, defaultValue int) int {
+	if value := os.Getenv(key); value != "" {
+		if parsed, err := strconv.Atoi(value); err == nil {
+			return parsed
+		}
+		log.Printf("Aviso: valor inválido para %s: %s", key, value)
+	}
+	return defaultValue
+}
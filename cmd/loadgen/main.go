@@ -0,0 +1,100 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"flag"
+	"log"
+	"net/http"
+	"os"
+	"os/signal"
+	"strings"
+	"syscall"
+	"time"
+
+	"rinha-de-backend-2025/internal/loadgen"
+)
+
+// failureWindows acumula as flags -fail repetidas
+type failureWindows []loadgen.FailureWindow
+
+func (f *failureWindows) String() string {
+	return ""
+}
+
+func (f *failureWindows) Set(value string) error {
+	window, err := loadgen.ParseFailureWindow(value)
+	if err != nil {
+		return err
+	}
+	*f = append(*f, window)
+	return nil
+}
+
+func main() {
+	var windows failureWindows
+
+	target := flag.String("target", "http://localhost:9999", "URL da API (ou load balancer)")
+	duration := flag.Duration("duration", 60*time.Second, "duração do tráfego")
+	startRate := flag.Float64("start-rate", 50, "req/s no início da rampa")
+	endRate := flag.Float64("end-rate", 500, "req/s no fim da rampa")
+	maxInFlight := flag.Int("max-in-flight", 550, "máximo de requisições simultâneas")
+	amount := flag.Float64("amount", 19.90, "valor de cada pagamento sintetizado")
+	replay := flag.String("replay", "", "arquivo JSONL com payloads {\"correlationId\",\"amount\"} a reproduzir")
+	defaultURL := flag.String("default-url", "http://localhost:8001", "URL do processor default (vazio desativa)")
+	fallbackURL := flag.String("fallback-url", "http://localhost:8002", "URL do processor fallback (vazio desativa)")
+	token := flag.String("token", "123", "X-Rinha-Token dos endpoints /admin dos processors")
+	purge := flag.Bool("purge", true, "limpa os processors antes de começar")
+	jsonOutput := flag.Bool("json", false, "imprime o relatório em JSON")
+	flag.Var(&windows, "fail", "janela de falha processor:inicio-fim (ex: default:10s-25s), pode repetir")
+	flag.Parse()
+
+	client := &http.Client{
+		Timeout: 30 * time.Second,
+		Transport: &http.Transport{
+			MaxIdleConns:        *maxInFlight,
+			MaxIdleConnsPerHost: *maxInFlight,
+		},
+	}
+
+	var processors []*loadgen.ProcessorAdmin
+	if *defaultURL != "" {
+		processors = append(processors, loadgen.NewProcessorAdmin("default", strings.TrimRight(*defaultURL, "/"), *token, client))
+	}
+	if *fallbackURL != "" {
+		processors = append(processors, loadgen.NewProcessorAdmin("fallback", strings.TrimRight(*fallbackURL, "/"), *token, client))
+	}
+
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+
+	runner := loadgen.NewRunner(loadgen.Config{
+		TargetURL:      strings.TrimRight(*target, "/"),
+		Duration:       *duration,
+		StartRate:      *startRate,
+		EndRate:        *endRate,
+		MaxInFlight:    *maxInFlight,
+		Amount:         *amount,
+		ReplayFile:     *replay,
+		Processors:     processors,
+		FailureWindows: windows,
+		Purge:          *purge,
+	}, client)
+
+	report, err := runner.Run(ctx)
+	if report != nil {
+		if *jsonOutput {
+			encoder := json.NewEncoder(os.Stdout)
+			encoder.SetIndent("", "  ")
+			encoder.Encode(report)
+		} else {
+			report.Print(os.Stdout)
+		}
+	}
+	if err != nil {
+		log.Fatalf("Erro na execução da carga: %v", err)
+	}
+	if len(report.Inconsistencies) > 0 {
+		os.Exit(1)
+	}
+}
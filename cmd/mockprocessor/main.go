@@ -0,0 +1,63 @@
+package main
+
+import (
+	"log"
+	"net/http"
+	"os"
+	"strconv"
+	"time"
+
+	"rinha-de-backend-2025/internal/mockprocessor"
+)
+
+func main() {
+	port := getEnvOrDefault("PORT", "8001")
+	name := getEnvOrDefault("PROCESSOR_NAME", "default")
+
+	cfg := mockprocessor.DefaultConfig(name, getEnvFloat("PROCESSOR_FEE", 0.05))
+	cfg.Token = getEnvOrDefault("PROCESSOR_TOKEN", cfg.Token)
+	cfg.Delay = getEnvDuration("PROCESSOR_DELAY", 0)
+	cfg.FailureRate = getEnvFloat("PROCESSOR_FAILURE_RATE", 0)
+	cfg.Failing = os.Getenv("PROCESSOR_FAILING") == "true"
+	cfg.HealthRateLimit = getEnvDuration("PROCESSOR_HEALTH_RATE_LIMIT", cfg.HealthRateLimit)
+
+	log.Printf("=== Mock Payment Processor (%s) ===", name)
+	log.Printf("Porta: %s", port)
+	log.Printf("Taxa: %.2f%%", cfg.Fee*100)
+	log.Printf("Delay: %v, Falha: %t, Taxa de falha: %.2f", cfg.Delay, cfg.Failing, cfg.FailureRate)
+	log.Printf("Rate limit do service-health: %v", cfg.HealthRateLimit)
+
+	if err := http.ListenAndServe(":"+port, mockprocessor.New(cfg)); err != nil {
+		log.Fatalf("Erro ao iniciar mock processor: %v", err)
+	}
+}
+
+// getEnvOrDefault retorna uma variável de ambiente ou valor padrão
+func getEnvOrDefault(key, defaultValue string) string {
+	if value := os.Getenv(key); value != "" {
+		return value
+	}
+	return defaultValue
+}
+
+// getEnvFloat retorna uma variável de ambiente numérica ou valor padrão
+func getEnvFloat(key string, defaultValue float64) float64 {
+	if value := os.Getenv(key); value != "" {
+		if parsed, err := strconv.ParseFloat(value, 64); err == nil {
+			return parsed
+		}
+		log.Printf("Aviso: valor inválido para %s: %s", key, value)
+	}
+	return defaultValue
+}
+
+// getEnvDuration retorna uma variável de ambiente de duração ou valor padrão
+func getEnvDuration(key string, defaultValue time.Duration) time.Duration {
+	if value := os.Getenv(key); value != "" {
+		if parsed, err := time.ParseDuration(value); err == nil {
+			return parsed
+		}
+		log.Printf("Aviso: valor inválido para %s: %s", key, value)
+	}
+	return defaultValue
+}
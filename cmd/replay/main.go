@@ -0,0 +1,92 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"log"
+	"net/http"
+	"os"
+	"os/signal"
+	"strings"
+	"syscall"
+	"time"
+
+	"rinha-de-backend-2025/internal/loadgen"
+	"rinha-de-backend-2025/internal/replay"
+)
+
+func main() {
+	file := flag.String("file", "", "arquivo JSONL com as requisições capturadas")
+	target := flag.String("target", "http://localhost:9999", "URL da API (ou load balancer)")
+	speed := flag.Float64("speed", 1, "escala de tempo: 1 = original, 2 = duas vezes mais rápido, 0 = sem espera")
+	maxInFlight := flag.Int("max-in-flight", 500, "máximo de requisições simultâneas")
+	reportPath := flag.String("report", "replay-report.json", "arquivo onde o relatório de diferenças é gravado")
+	defaultURL := flag.String("default-url", "", "URL do processor default para purge antes da reprodução")
+	fallbackURL := flag.String("fallback-url", "", "URL do processor fallback para purge antes da reprodução")
+	token := flag.String("token", "123", "X-Rinha-Token dos endpoints /admin dos processors")
+	flag.Parse()
+
+	if *file == "" {
+		log.Fatalf("Informe o arquivo de capturas com -file")
+	}
+
+	captureFile, err := os.Open(*file)
+	if err != nil {
+		log.Fatalf("Erro ao abrir capturas: %v", err)
+	}
+	entries, err := replay.ReadCaptures(captureFile)
+	captureFile.Close()
+	if err != nil {
+		log.Fatalf("Erro ao ler capturas: %v", err)
+	}
+
+	client := &http.Client{Timeout: 30 * time.Second}
+
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+
+	// Limpar os processors evita 422 por correlationId repetido
+	for name, processorURL := range map[string]string{"default": *defaultURL, "fallback": *fallbackURL} {
+		if processorURL == "" {
+			continue
+		}
+		admin := loadgen.NewProcessorAdmin(name, strings.TrimRight(processorURL, "/"), *token, client)
+		if err := admin.Purge(ctx); err != nil {
+			log.Fatalf("Erro ao limpar processor %s: %v", name, err)
+		}
+	}
+
+	player := replay.NewPlayer(replay.Config{
+		TargetURL:   strings.TrimRight(*target, "/"),
+		Speed:       *speed,
+		MaxInFlight: *maxInFlight,
+	}, client)
+
+	report, err := player.Play(ctx, entries)
+	if err != nil && report == nil {
+		log.Fatalf("Erro na reprodução: %v", err)
+	}
+
+	data, _ := json.MarshalIndent(report, "", "  ")
+	if writeErr := os.WriteFile(*reportPath, data, 0644); writeErr != nil {
+		log.Printf("Erro ao gravar relatório: %v", writeErr)
+	}
+
+	fmt.Printf("Requisições: %d | OK: %d | status divergente: %d | resumo divergente: %d | erros: %d\n",
+		report.Total, report.Matched, report.StatusMismatches, report.SummaryMismatches, report.Errors)
+	for _, mismatch := range report.Mismatches {
+		fmt.Printf("  linha %d %s %s: esperado %d, obtido %d %s %s\n", mismatch.Line, mismatch.Method,
+			mismatch.Path, mismatch.ExpectedStatus, mismatch.ActualStatus, mismatch.Error,
+			strings.Join(mismatch.Diffs, "; "))
+	}
+	fmt.Printf("Relatório gravado em %s\n", *reportPath)
+
+	if err != nil {
+		log.Fatalf("Reprodução interrompida: %v", err)
+	}
+	if !report.OK() {
+		os.Exit(1)
+	}
+}
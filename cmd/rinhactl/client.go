@@ -0,0 +1,106 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"os"
+	"strings"
+	"text/tabwriter"
+	"time"
+)
+
+// ctl concentra o acesso à API e a formatação da saída
+type ctl struct {
+	apiURL     string
+	jsonOutput bool
+	timeout    time.Duration
+	client     *http.Client
+	redisURL   string
+	adminToken string
+}
+
+func newCtl(apiURL string, jsonOutput bool, timeout time.Duration) *ctl {
+	return &ctl{
+		apiURL:     apiURL,
+		jsonOutput: jsonOutput,
+		timeout:    timeout,
+		client:     &http.Client{},
+	}
+}
+
+// get chama um endpoint GET da API e decodifica a resposta em out
+func (c *ctl) get(path string, query url.Values, out interface{}) error {
+	return c.do(http.MethodGet, path, query, out)
+}
+
+// post chama um endpoint POST da API e decodifica a resposta em out
+func (c *ctl) post(path string, query url.Values, out interface{}) error {
+	return c.do(http.MethodPost, path, query, out)
+}
+
+// delete chama um endpoint DELETE da API e decodifica a resposta em out
+func (c *ctl) delete(path string, query url.Values, out interface{}) error {
+	return c.do(http.MethodDelete, path, query, out)
+}
+
+func (c *ctl) do(method, path string, query url.Values, out interface{}) error {
+	ctx, cancel := context.WithTimeout(context.Background(), c.timeout)
+	defer cancel()
+
+	target := c.apiURL + path
+	if len(query) > 0 {
+		target += "?" + query.Encode()
+	}
+
+	req, err := http.NewRequestWithContext(ctx, method, target, nil)
+	if err != nil {
+		return fmt.Errorf("erro ao criar request: %v", err)
+	}
+	if c.adminToken != "" {
+		req.Header.Set("X-Admin-Token", c.adminToken)
+	}
+
+	resp, err := c.client.Do(req)
+	if err != nil {
+		return fmt.Errorf("erro ao chamar %s: %v", path, err)
+	}
+	defer resp.Body.Close()
+
+	body, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return fmt.Errorf("erro ao ler resposta de %s: %v", path, err)
+	}
+
+	// Alguns endpoints respondem JSON com status de erro (ex.: re-drive que falhou)
+	if resp.StatusCode >= 400 && !json.Valid(body) {
+		return fmt.Errorf("%s respondeu %d: %s", path, resp.StatusCode, strings.TrimSpace(string(body)))
+	}
+	if out == nil {
+		return nil
+	}
+	if err := json.Unmarshal(body, out); err != nil {
+		return fmt.Errorf("resposta inválida de %s: %v", path, err)
+	}
+	return nil
+}
+
+// printJSON escreve o valor indentado na saída padrão
+func printJSON(v interface{}) error {
+	encoder := json.NewEncoder(os.Stdout)
+	encoder.SetIndent("", "  ")
+	return encoder.Encode(v)
+}
+
+// printTable escreve linhas alinhadas por tabulação; a primeira é o cabeçalho
+func printTable(header []string, rows [][]string) {
+	w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(w, strings.Join(header, "\t"))
+	for _, row := range rows {
+		fmt.Fprintln(w, strings.Join(row, "\t"))
+	}
+	w.Flush()
+}
@@ -0,0 +1,119 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"net/url"
+	"strconv"
+	"time"
+
+	"rinha-de-backend-2025/internal/repository"
+	"rinha-de-backend-2025/internal/usecase"
+)
+
+// runDLQ implementa "rinhactl dlq <list|redrive|resolve>"
+func runDLQ(c *ctl, args []string) error {
+	if len(args) == 0 {
+		return fmt.Errorf("uso: rinhactl dlq <list|redrive|resolve> [flags]")
+	}
+
+	flags := flag.NewFlagSet("dlq "+args[0], flag.ExitOnError)
+	limit := flags.Int("limit", 50, "quantidade máxima de dead letters")
+	id := flags.String("id", "", "payment_id da dead letter")
+	flags.Parse(args[1:])
+
+	switch args[0] {
+	case "list":
+		return dlqList(c, *limit)
+	case "redrive":
+		return dlqRedrive(c, *id, *limit)
+	case "resolve":
+		if *id == "" {
+			return fmt.Errorf("informe a dead letter com -id")
+		}
+		return dlqResolve(c, *id)
+	default:
+		return fmt.Errorf("subcomando desconhecido: dlq %s", args[0])
+	}
+}
+
+func dlqList(c *ctl, limit int) error {
+	var resp struct {
+		DeadLetters []*repository.Payment `json:"dead_letters"`
+		Total       int                   `json:"total"`
+	}
+	if err := c.get("/admin/dead-letters", url.Values{"limit": {strconv.Itoa(limit)}}, &resp); err != nil {
+		return err
+	}
+	if c.jsonOutput {
+		return printJSON(resp)
+	}
+
+	rows := make([][]string, 0, len(resp.DeadLetters))
+	for _, deadLetter := range resp.DeadLetters {
+		rows = append(rows, []string{
+			deadLetter.PaymentID,
+			deadLetter.CorrelationID,
+			fmt.Sprintf("%.2f", deadLetter.Amount),
+			deadLetter.PaymentProcessor,
+			deadLetter.CreatedAt.Format(time.RFC3339),
+			deadLetter.ErrorMessage,
+		})
+	}
+	printTable([]string{"PAYMENT_ID", "CORRELATION_ID", "AMOUNT", "PROCESSOR", "FAILED_AT", "ERROR"}, rows)
+	fmt.Printf("\n%d dead letters pendentes\n", resp.Total)
+	return nil
+}
+
+func dlqRedrive(c *ctl, id string, limit int) error {
+	var results []usecase.RedriveResult
+	var report usecase.RedriveReport
+
+	if id != "" {
+		var result usecase.RedriveResult
+		if err := c.post("/admin/dead-letters/redrive", url.Values{"id": {id}}, &result); err != nil {
+			return err
+		}
+		if c.jsonOutput {
+			return printJSON(result)
+		}
+		results = []usecase.RedriveResult{result}
+	} else {
+		if err := c.post("/admin/dead-letters/redrive", url.Values{"limit": {strconv.Itoa(limit)}}, &report); err != nil {
+			return err
+		}
+		if c.jsonOutput {
+			return printJSON(report)
+		}
+		results = report.Results
+	}
+
+	rows := make([][]string, 0, len(results))
+	for _, result := range results {
+		rows = append(rows, []string{
+			result.PaymentID,
+			result.CorrelationID,
+			strconv.FormatBool(result.Resolved),
+			result.Resolution,
+			result.ProcessorUsed,
+			result.Error,
+		})
+	}
+	printTable([]string{"PAYMENT_ID", "CORRELATION_ID", "RESOLVED", "RESOLUTION", "PROCESSOR", "ERROR"}, rows)
+	if id == "" {
+		fmt.Printf("\n%d tentadas, %d resolvidas, %d com falha\n", report.Attempted, report.Resolved, report.Failed)
+	}
+	return nil
+}
+
+func dlqResolve(c *ctl, id string) error {
+	var resp map[string]interface{}
+	if err := c.post("/admin/dead-letters/resolve", url.Values{"id": {id}}, &resp); err != nil {
+		return err
+	}
+	if c.jsonOutput {
+		return printJSON(resp)
+	}
+	fmt.Printf("Dead letter %s descartada\n", id)
+	return nil
+}
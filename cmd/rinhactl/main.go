@@ -0,0 +1,78 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"os"
+	"strings"
+	"time"
+)
+
+// command é um subcomando do rinhactl
+type command struct {
+	name        string
+	description string
+	run         func(ctl *ctl, args []string) error
+}
+
+var commands = []command{
+	{"status", "status dos processors e gateway ativo no Redis", runStatus},
+	{"override", "override de roteamento: force, exclude, pause, clear", runOverride},
+	{"health-check", "executa um health check dos processors agora", runHealthCheck},
+	{"summary", "resumo de pagamentos por processor em uma janela", runSummary},
+	{"fees", "bruto, taxas e líquido por processor e custo do failover", runFees},
+	{"purge", "apaga os pagamentos registrados pela API", runPurge},
+	{"dlq", "dead letters: list, redrive, resolve", runDLQ},
+	{"tail", "acompanha os pagamentos mais recentes", runTail},
+}
+
+func main() {
+	apiURL := flag.String("api", getEnvOrDefault("RINHA_API", "http://localhost:9999"), "URL da API (ou load balancer)")
+	jsonOutput := flag.Bool("json", false, "saída em JSON em vez de tabela")
+	timeout := flag.Duration("timeout", 2*time.Minute, "timeout de cada chamada à API")
+	redisURL := flag.String("redis", getEnvOrDefault("REDIS_URL", "redis://localhost:6379"), "URL do Redis (status e override)")
+	adminToken := flag.String("token", os.Getenv("RINHA_ADMIN_TOKEN"), "token dos endpoints /admin/* (ADMIN_TOKEN da API)")
+	flag.Usage = usage
+	flag.Parse()
+
+	if flag.NArg() == 0 {
+		usage()
+		os.Exit(2)
+	}
+
+	ctl := newCtl(strings.TrimRight(*apiURL, "/"), *jsonOutput, *timeout)
+	ctl.redisURL = *redisURL
+	ctl.adminToken = *adminToken
+	name, args := flag.Arg(0), flag.Args()[1:]
+	for _, cmd := range commands {
+		if cmd.name == name {
+			if err := cmd.run(ctl, args); err != nil {
+				fmt.Fprintf(os.Stderr, "erro: %v\n", err)
+				os.Exit(1)
+			}
+			return
+		}
+	}
+
+	fmt.Fprintf(os.Stderr, "comando desconhecido: %s\n\n", name)
+	usage()
+	os.Exit(2)
+}
+
+func usage() {
+	out := flag.CommandLine.Output()
+	fmt.Fprintf(out, "Uso: rinhactl [flags] <comando> [argumentos]\n\nComandos:\n")
+	for _, cmd := range commands {
+		fmt.Fprintf(out, "  %-13s %s\n", cmd.name, cmd.description)
+	}
+	fmt.Fprintf(out, "\nFlags:\n")
+	flag.PrintDefaults()
+}
+
+// getEnvOrDefault retorna uma variável de ambiente ou valor padrão
+func getEnvOrDefault(key, defaultValue string) string {
+	if value := os.Getenv(key); value != "" {
+		return value
+	}
+	return defaultValue
+}
@@ -0,0 +1,200 @@
+package main
+
+import (
+	"encoding/json"
+	"flag"
+	"fmt"
+	"net/url"
+	"os"
+	"os/signal"
+	"sort"
+	"strconv"
+	"time"
+
+	"rinha-de-backend-2025/internal/repository"
+	"rinha-de-backend-2025/internal/usecase"
+)
+
+// windowFormat é o formato de from/to aceito por /payments-summary
+const windowFormat = "2006-01-02T15:04:05.000Z07:00"
+
+// runSummary implementa "rinhactl summary [-from ... -to ... | -since 1h]"
+func runSummary(c *ctl, args []string) error {
+	flags := flag.NewFlagSet("summary", flag.ExitOnError)
+	fromValue := flags.String("from", "", "início da janela (RFC3339); padrão: to - since")
+	toValue := flags.String("to", "", "fim da janela (RFC3339); padrão: agora")
+	since := flags.Duration("since", time.Hour, "tamanho da janela quando -from não é informado")
+	flags.Parse(args)
+
+	from, to, err := parseWindow(*fromValue, *toValue, *since)
+	if err != nil {
+		return err
+	}
+
+	var summary repository.PaymentSummary
+	query := url.Values{"from": {from.Format(windowFormat)}, "to": {to.Format(windowFormat)}}
+	if err := c.get("/payments-summary", query, &summary); err != nil {
+		return err
+	}
+	if c.jsonOutput {
+		return printJSON(summary)
+	}
+
+	printTable([]string{"PROCESSOR", "TOTAL_REQUESTS", "TOTAL_AMOUNT"}, [][]string{
+		{"default", strconv.FormatInt(summary.Default.TotalRequests, 10), fmt.Sprintf("%.2f", summary.Default.TotalAmount)},
+		{"fallback", strconv.FormatInt(summary.Fallback.TotalRequests, 10), fmt.Sprintf("%.2f", summary.Fallback.TotalAmount)},
+	})
+	fmt.Printf("\nJanela: %s → %s\n", from.Format(windowFormat), to.Format(windowFormat))
+	return nil
+}
+
+// runFees implementa "rinhactl fees [-from ... -to ... | -since 1h]"
+func runFees(c *ctl, args []string) error {
+	flags := flag.NewFlagSet("fees", flag.ExitOnError)
+	fromValue := flags.String("from", "", "início da janela (RFC3339); padrão: to - since")
+	toValue := flags.String("to", "", "fim da janela (RFC3339); padrão: agora")
+	since := flags.Duration("since", time.Hour, "tamanho da janela quando -from não é informado")
+	flags.Parse(args)
+
+	from, to, err := parseWindow(*fromValue, *toValue, *since)
+	if err != nil {
+		return err
+	}
+
+	var report usecase.FeeReport
+	query := url.Values{"from": {from.Format(windowFormat)}, "to": {to.Format(windowFormat)}}
+	if err := c.get("/payments/fees", query, &report); err != nil {
+		return err
+	}
+	if c.jsonOutput {
+		return printJSON(report)
+	}
+
+	row := func(name string, fees usecase.ProcessorFees) []string {
+		return []string{
+			name,
+			strconv.FormatInt(fees.Payments, 10),
+			fmt.Sprintf("%.2f", fees.GrossAmount),
+			fmt.Sprintf("%.2f", fees.Fees),
+			fmt.Sprintf("%.2f", fees.EstimatedFees),
+			fmt.Sprintf("%.2f", fees.Net),
+			fmt.Sprintf("%.2f%%", fees.EffectiveRate*100),
+		}
+	}
+	names := make([]string, 0, len(report.Processors))
+	for name := range report.Processors {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	rows := make([][]string, 0, len(names)+2)
+	for _, name := range names {
+		rows = append(rows, row(name, *report.Processors[name]))
+	}
+	rows = append(rows, row("total", report.Total), row("só default", report.AllDefault))
+	printTable([]string{"PROCESSOR", "PAYMENTS", "GROSS", "FEES", "ESTIMATED", "NET", "RATE"}, rows)
+
+	fmt.Printf("\nCusto do failover: %.2f (taxas pagas - taxas se tudo fosse para o default)\n", report.FailoverCost)
+	fmt.Printf("Taxas configuradas: default %.2f%%, fallback %.2f%%\n", report.Rates.Default*100, report.Rates.Fallback*100)
+	fmt.Printf("Janela: %s → %s\n", from.Format(windowFormat), to.Format(windowFormat))
+	return nil
+}
+
+// parseWindow monta a janela de -from/-to/-since: sem -to vai até agora, sem -from
+// começa since antes do fim
+func parseWindow(fromValue, toValue string, since time.Duration) (from, to time.Time, err error) {
+	to = time.Now().UTC()
+	if toValue != "" {
+		if to, err = time.Parse(time.RFC3339, toValue); err != nil {
+			return from, to, fmt.Errorf("valor inválido para -to: %v", err)
+		}
+	}
+	from = to.Add(-since)
+	if fromValue != "" {
+		if from, err = time.Parse(time.RFC3339, fromValue); err != nil {
+			return from, to, fmt.Errorf("valor inválido para -from: %v", err)
+		}
+	}
+	return from, to, nil
+}
+
+// runPurge implementa "rinhactl purge -yes": apaga os pagamentos da instância que atender (e dos PEERS dela)
+func runPurge(c *ctl, args []string) error {
+	flags := flag.NewFlagSet("purge", flag.ExitOnError)
+	confirmed := flags.Bool("yes", false, "confirma que todos os pagamentos devem ser apagados")
+	flags.Parse(args)
+
+	if !*confirmed {
+		return fmt.Errorf("purge apaga todos os pagamentos; repita com -yes para confirmar")
+	}
+
+	var resp struct {
+		Purged int64 `json:"purged"`
+	}
+	if err := c.post("/admin/purge", nil, &resp); err != nil {
+		return err
+	}
+	if c.jsonOutput {
+		return printJSON(resp)
+	}
+	fmt.Printf("%d pagamentos apagados\n", resp.Purged)
+	return nil
+}
+
+// runTail implementa "rinhactl tail [-n 10] [-f]": mostra os pagamentos mais recentes e,
+// com -f, continua consultando /payments/history e imprimindo os novos
+func runTail(c *ctl, args []string) error {
+	flags := flag.NewFlagSet("tail", flag.ExitOnError)
+	count := flags.Int("n", 10, "quantidade de pagamentos por consulta (máximo 100)")
+	follow := flags.Bool("f", false, "continua acompanhando os novos pagamentos")
+	interval := flags.Duration("interval", time.Second, "intervalo entre consultas com -f")
+	flags.Parse(args)
+
+	interrupt := make(chan os.Signal, 1)
+	signal.Notify(interrupt, os.Interrupt)
+
+	if !c.jsonOutput {
+		fmt.Printf("%-8s %-24s %-38s %-9s %-10s %10s %s\n", "ID", "CREATED_AT", "CORRELATION_ID", "PROCESSOR", "STATUS", "AMOUNT", "ERROR")
+	}
+
+	lastID := 0
+	for {
+		var resp struct {
+			Payments []*repository.Payment `json:"payments"`
+		}
+		if err := c.get("/payments/history", url.Values{"limit": {strconv.Itoa(*count)}}, &resp); err != nil {
+			return err
+		}
+
+		// O histórico vem do mais novo para o mais antigo; imprime em ordem cronológica
+		sort.Slice(resp.Payments, func(i, j int) bool { return resp.Payments[i].ID < resp.Payments[j].ID })
+		for _, p := range resp.Payments {
+			if p.ID <= lastID {
+				continue
+			}
+			lastID = p.ID
+			if err := printTailLine(c, p); err != nil {
+				return err
+			}
+		}
+
+		if !*follow {
+			return nil
+		}
+		select {
+		case <-interrupt:
+			return nil
+		case <-time.After(*interval):
+		}
+	}
+}
+
+// printTailLine imprime um pagamento: uma linha de tabela ou um objeto JSON por linha
+func printTailLine(c *ctl, p *repository.Payment) error {
+	if c.jsonOutput {
+		return json.NewEncoder(os.Stdout).Encode(p)
+	}
+	_, err := fmt.Printf("%-8d %-24s %-38s %-9s %-10s %10.2f %s\n",
+		p.ID, p.CreatedAt.Format(windowFormat), p.CorrelationID, p.PaymentProcessor, p.Status, p.Amount, p.ErrorMessage)
+	return err
+}
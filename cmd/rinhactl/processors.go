@@ -0,0 +1,176 @@
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"net/url"
+	"strconv"
+	"time"
+
+	"rinha-de-backend-2025/internal/cache"
+	"rinha-de-backend-2025/internal/gateway"
+)
+
+// processorNames são os processors conhecidos, na ordem de prioridade
+var processorNames = []string{"default", "fallback"}
+
+// openStatusStore conecta no Redis compartilhado pelas instâncias da API
+func (c *ctl) openStatusStore() (cache.ProcessorStatusStore, error) {
+	stores, err := cache.NewStores(cache.STORE_BACKEND_REDIS, c.redisURL)
+	if err != nil {
+		return nil, err
+	}
+	return stores.Status, nil
+}
+
+// runStatus implementa "rinhactl status": lê o status dos processors direto do Redis
+func runStatus(c *ctl, args []string) error {
+	flags := flag.NewFlagSet("status", flag.ExitOnError)
+	flags.Parse(args)
+
+	store, err := c.openStatusStore()
+	if err != nil {
+		return err
+	}
+	defer store.Close()
+
+	ctx, cancel := context.WithTimeout(context.Background(), c.timeout)
+	defer cancel()
+
+	statuses := store.GetAllProcessorStatus(ctx)
+	active, err := store.GetAvailableGateway(ctx)
+	if err != nil {
+		return fmt.Errorf("erro ao buscar gateway ativo: %v", err)
+	}
+	override, err := store.GetRoutingOverride(ctx)
+	if err != nil {
+		return err
+	}
+
+	if c.jsonOutput {
+		return printJSON(map[string]interface{}{
+			"processors":        statuses,
+			"available_gateway": active,
+			"routing_override":  override,
+		})
+	}
+
+	rows := make([][]string, 0, len(statuses))
+	for _, name := range processorNames {
+		isActive := active != nil && active.IsAvailable && active.Name == name
+		rows = append(rows, []string{name, strconv.FormatBool(statuses[name]), strconv.FormatBool(isActive)})
+	}
+	printTable([]string{"PROCESSOR", "AVAILABLE", "ACTIVE"}, rows)
+
+	if active == nil {
+		fmt.Printf("\nNenhum gateway ativo no cache (instâncias verificam os processors diretamente)\n")
+	} else {
+		fmt.Printf("\nGateway ativo: %s (%s), última verificação %s\n", active.Name, active.URL, active.LastCheck.Format(time.RFC3339))
+	}
+	printOverride(override)
+	return nil
+}
+
+// runOverride implementa "rinhactl override": consulta, aplica ou remove o override
+// manual de roteamento via /admin/override. Sem flags, mostra o override atual.
+func runOverride(c *ctl, args []string) error {
+	flags := flag.NewFlagSet("override", flag.ExitOnError)
+	force := flags.String("force", "", "envia todo o tráfego ao processor (default ou fallback)")
+	exclude := flags.String("exclude", "", "trata o processor como indisponível (default ou fallback)")
+	pause := flags.Bool("pause", false, "pausa a entrada de pagamentos (503)")
+	clear := flags.Bool("clear", false, "remove o override ativo")
+	ttl := flags.Duration("ttl", 5*time.Minute, "validade do override")
+	reason := flags.String("reason", "", "motivo, exibido no /health")
+	flags.Parse(args)
+
+	var resp struct {
+		Override *cache.RoutingOverride `json:"override"`
+	}
+	query := url.Values{"ttl": {ttl.String()}, "reason": {*reason}}
+	var err error
+	switch {
+	case *clear:
+		err = c.delete("/admin/override", nil, &resp)
+	case *force != "":
+		query.Set("mode", cache.OVERRIDE_FORCE)
+		query.Set("processor", *force)
+		err = c.post("/admin/override", query, &resp)
+	case *exclude != "":
+		query.Set("mode", cache.OVERRIDE_EXCLUDE)
+		query.Set("processor", *exclude)
+		err = c.post("/admin/override", query, &resp)
+	case *pause:
+		query.Set("mode", cache.OVERRIDE_PAUSE)
+		err = c.post("/admin/override", query, &resp)
+	default:
+		err = c.get("/admin/override", nil, &resp)
+	}
+	if err != nil {
+		return err
+	}
+
+	if c.jsonOutput {
+		return printJSON(resp)
+	}
+	printOverride(resp.Override)
+	return nil
+}
+
+// printOverride descreve o override ativo em uma linha
+func printOverride(override *cache.RoutingOverride) {
+	if override == nil {
+		fmt.Printf("Nenhum override ativo\n")
+		return
+	}
+	description := override.Mode
+	if override.Processor != "" {
+		description += " " + override.Processor
+	}
+	fmt.Printf("Override ativo: %s até %s", description, override.ExpiresAt.Format(time.RFC3339))
+	if override.Reason != "" {
+		fmt.Printf(" (%s)", override.Reason)
+	}
+	fmt.Println()
+}
+
+// runHealthCheck implementa "rinhactl health-check": pede à API um health check imediato
+func runHealthCheck(c *ctl, args []string) error {
+	flags := flag.NewFlagSet("health-check", flag.ExitOnError)
+	flags.Parse(args)
+
+	var resp struct {
+		Probes     map[string]gateway.ProbeResult `json:"processor_probes"`
+		Processors map[string]bool                `json:"processors"`
+	}
+	if err := c.post("/admin/health-check", nil, &resp); err != nil {
+		return err
+	}
+	if c.jsonOutput {
+		return printJSON(resp)
+	}
+
+	rows := make([][]string, 0, len(resp.Probes))
+	for _, name := range processorNames {
+		probe, ok := resp.Probes[name]
+		if !ok {
+			continue
+		}
+		rows = append(rows, []string{
+			name,
+			strconv.FormatBool(probe.Healthy),
+			formatTime(probe.LastCheck),
+			formatTime(probe.LastSuccess),
+		})
+	}
+	printTable([]string{"PROCESSOR", "HEALTHY", "LAST_CHECK", "LAST_SUCCESS"}, rows)
+	return nil
+}
+
+// formatTime formata um horário para a tabela, com "-" para o valor zero
+func formatTime(t time.Time) string {
+	if t.IsZero() {
+		return "-"
+	}
+	return t.Format(time.RFC3339)
+}
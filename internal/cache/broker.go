@@ -0,0 +1,122 @@
+package cache
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"sync"
+
+	"github.com/go-redis/redis/v8"
+)
+
+// Broker é o pub/sub entre instâncias: mudanças de gateway e override, inscrições
+// de webhook e o fan-out de eventos. RedisBroker alcança todas as instâncias;
+// MemoryBroker só os inscritos do próprio processo.
+type Broker interface {
+	// Publish envia uma mensagem a todas as instâncias inscritas no canal
+	Publish(ctx context.Context, channel string, message []byte) error
+	// Subscribe entrega as mensagens do canal até o ctx ser cancelado
+	Subscribe(ctx context.Context, channel string) (<-chan []byte, error)
+}
+
+// RedisBroker publica e se inscreve em canais do Redis
+type RedisBroker struct {
+	client *redis.Client
+}
+
+// Publish publica uma mensagem em um canal do Redis
+func (r *RedisBroker) Publish(ctx context.Context, channel string, message []byte) error {
+	if err := r.client.Publish(ctx, channel, message).Err(); err != nil {
+		return fmt.Errorf("erro ao publicar no canal %s: %v", channel, err)
+	}
+	return nil
+}
+
+// Subscribe se inscreve em um canal do Redis. O go-redis reconecta a
+// inscrição sozinho; o canal retornado é fechado quando o ctx é cancelado.
+func (r *RedisBroker) Subscribe(ctx context.Context, channel string) (<-chan []byte, error) {
+	pubsub := r.client.Subscribe(ctx, channel)
+	if _, err := pubsub.Receive(ctx); err != nil {
+		pubsub.Close()
+		return nil, fmt.Errorf("erro ao se inscrever no canal %s: %v", channel, err)
+	}
+
+	out := make(chan []byte, 16)
+	go func() {
+		defer close(out)
+		defer pubsub.Close()
+
+		messages := pubsub.Channel()
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case msg, ok := <-messages:
+				if !ok {
+					return
+				}
+				select {
+				case out <- []byte(msg.Payload):
+				case <-ctx.Done():
+					return
+				}
+			}
+		}
+	}()
+
+	log.Printf("📡 Inscrito no canal Redis %s", channel)
+	return out, nil
+}
+
+// MemoryBroker entrega as mensagens aos inscritos do próprio processo
+type MemoryBroker struct {
+	mu          sync.Mutex
+	subscribers map[string][]chan []byte
+}
+
+// NewMemoryBroker cria um broker em memória (modo single-instance)
+func NewMemoryBroker() *MemoryBroker {
+	return &MemoryBroker{subscribers: make(map[string][]chan []byte)}
+}
+
+// Publish entrega a mensagem aos inscritos do canal no próprio processo.
+// Inscritos lentos perdem a mensagem em vez de bloquear quem publica.
+func (m *MemoryBroker) Publish(ctx context.Context, channel string, message []byte) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	for _, subscriber := range m.subscribers[channel] {
+		select {
+		case subscriber <- message:
+		default:
+			log.Printf("⚠️ Inscrito lento no canal %s, mensagem descartada", channel)
+		}
+	}
+	return nil
+}
+
+// Subscribe se inscreve em um canal em memória até o ctx ser cancelado
+func (m *MemoryBroker) Subscribe(ctx context.Context, channel string) (<-chan []byte, error) {
+	subscriber := make(chan []byte, 16)
+
+	m.mu.Lock()
+	m.subscribers[channel] = append(m.subscribers[channel], subscriber)
+	m.mu.Unlock()
+
+	go func() {
+		<-ctx.Done()
+
+		m.mu.Lock()
+		defer m.mu.Unlock()
+		subscribers := m.subscribers[channel]
+		for i, candidate := range subscribers {
+			if candidate == subscriber {
+				m.subscribers[channel] = append(subscribers[:i], subscribers[i+1:]...)
+				break
+			}
+		}
+		close(subscriber)
+	}()
+
+	return subscriber, nil
+}
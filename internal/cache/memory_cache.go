@@ -0,0 +1,159 @@
+package cache
+
+import (
+	"context"
+	"log"
+	"sync"
+	"time"
+)
+
+// memoryEntry é um valor em memória com expiração
+type memoryEntry struct {
+	processor *ProcessorInfo
+	expiresAt time.Time
+}
+
+func (e memoryEntry) expired(now time.Time) bool {
+	return now.After(e.expiresAt)
+}
+
+// MemoryCache implementa ProcessorStatusStore em memória, com o mesmo TTL do Redis
+type MemoryCache struct {
+	mu       sync.RWMutex
+	gateway  *memoryEntry
+	statuses map[string]memoryEntry
+	override *RoutingOverride
+	// Último estado UP/DOWN de cada processor, sem TTL
+	states map[string]bool
+}
+
+// NewMemoryCache cria um novo status store em memória
+func NewMemoryCache() *MemoryCache {
+	log.Printf("✅ Status store em memória inicializado (modo single-instance)")
+	return &MemoryCache{
+		statuses: make(map[string]memoryEntry),
+		states:   make(map[string]bool),
+	}
+}
+
+// GetAvailableGateway retorna o último gateway disponível
+func (m *MemoryCache) GetAvailableGateway(ctx context.Context) (*ProcessorInfo, error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	if m.gateway == nil || m.gateway.expired(time.Now()) {
+		return nil, nil // Cache miss
+	}
+
+	processor := *m.gateway.processor
+	return &processor, nil
+}
+
+// SetAvailableGateway armazena o gateway disponível
+func (m *MemoryCache) SetAvailableGateway(ctx context.Context, processor *ProcessorInfo) error {
+	processor.LastCheck = time.Now()
+	stored := *processor
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.gateway = &memoryEntry{processor: &stored, expiresAt: time.Now().Add(CACHE_TTL)}
+	return nil
+}
+
+// InvalidateGateway remove o gateway se ele for o processor informado
+func (m *MemoryCache) InvalidateGateway(ctx context.Context, processorName string) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if m.gateway != nil && m.gateway.processor.Name == processorName {
+		m.gateway = nil
+		log.Printf("🗑️ Gateway %s invalidado do cache em memória", processorName)
+	}
+	return nil
+}
+
+// SetProcessorStatus armazena o status individual de um processor
+func (m *MemoryCache) SetProcessorStatus(ctx context.Context, processorName string, isAvailable bool) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	m.statuses[processorName] = memoryEntry{
+		processor: &ProcessorInfo{
+			Name:        processorName,
+			IsAvailable: isAvailable,
+			LastCheck:   time.Now(),
+		},
+		expiresAt: time.Now().Add(CACHE_TTL),
+	}
+	return nil
+}
+
+// GetProcessorStatus retorna o status de um processor específico
+func (m *MemoryCache) GetProcessorStatus(ctx context.Context, processorName string) (bool, error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	entry, ok := m.statuses[processorName]
+	if !ok || entry.expired(time.Now()) {
+		return false, nil // Cache miss, assume não disponível
+	}
+	return entry.processor.IsAvailable, nil
+}
+
+// SwapProcessorState troca o estado do processor e retorna true se ele mudou
+func (m *MemoryCache) SwapProcessorState(ctx context.Context, processorName string, isAvailable bool) (bool, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	previous, known := m.states[processorName]
+	m.states[processorName] = isAvailable
+	return known && previous != isAvailable, nil
+}
+
+// GetAllProcessorStatus retorna o status de todos os processors
+func (m *MemoryCache) GetAllProcessorStatus(ctx context.Context) map[string]bool {
+	status := make(map[string]bool)
+	status["default"], _ = m.GetProcessorStatus(ctx, "default")
+	status["fallback"], _ = m.GetProcessorStatus(ctx, "fallback")
+	return status
+}
+
+// GetRoutingOverride retorna o override manual, se ainda não expirou
+func (m *MemoryCache) GetRoutingOverride(ctx context.Context) (*RoutingOverride, error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	if !m.override.Active(time.Now()) {
+		return nil, nil
+	}
+	override := *m.override
+	return &override, nil
+}
+
+// SetRoutingOverride armazena o override manual até o seu ExpiresAt
+func (m *MemoryCache) SetRoutingOverride(ctx context.Context, override *RoutingOverride) error {
+	stored := *override
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.override = &stored
+	return nil
+}
+
+// ClearRoutingOverride remove o override manual
+func (m *MemoryCache) ClearRoutingOverride(ctx context.Context) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.override = nil
+	return nil
+}
+
+// Ping sempre responde: o store vive no próprio processo
+func (m *MemoryCache) Ping(ctx context.Context) error {
+	return nil
+}
+
+// Close não tem recursos para liberar
+func (m *MemoryCache) Close() error {
+	return nil
+}
@@ -0,0 +1,101 @@
+package cache
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/go-redis/redis/v8"
+)
+
+// Prefixo das janelas de métricas publicadas por instância (rinha:metrics:<instância>)
+const CACHE_KEY_METRICS_PREFIX = "rinha:metrics:"
+
+// MetricsStore guarda as janelas de métricas publicadas por instância, com TTL
+type MetricsStore interface {
+	SetInstanceMetrics(ctx context.Context, instanceID string, data []byte, ttl time.Duration) error
+	GetInstanceMetrics(ctx context.Context) (map[string][]byte, error)
+}
+
+// RedisMetricsStore compartilha as janelas de métricas entre instâncias
+type RedisMetricsStore struct {
+	client *redis.Client
+}
+
+// SetInstanceMetrics publica a janela de métricas da instância
+func (r *RedisMetricsStore) SetInstanceMetrics(ctx context.Context, instanceID string, data []byte, ttl time.Duration) error {
+	if err := r.client.Set(ctx, CACHE_KEY_METRICS_PREFIX+instanceID, data, ttl).Err(); err != nil {
+		return fmt.Errorf("erro ao publicar métricas: %v", err)
+	}
+	return nil
+}
+
+// GetInstanceMetrics retorna as janelas de métricas de todas as instâncias ativas
+func (r *RedisMetricsStore) GetInstanceMetrics(ctx context.Context) (map[string][]byte, error) {
+	var keys []string
+	iter := r.client.Scan(ctx, 0, CACHE_KEY_METRICS_PREFIX+"*", 100).Iterator()
+	for iter.Next(ctx) {
+		keys = append(keys, iter.Val())
+	}
+	if err := iter.Err(); err != nil {
+		return nil, fmt.Errorf("erro ao listar métricas: %v", err)
+	}
+	if len(keys) == 0 {
+		return nil, nil
+	}
+
+	values, err := r.client.MGet(ctx, keys...).Result()
+	if err != nil {
+		return nil, fmt.Errorf("erro ao buscar métricas: %v", err)
+	}
+
+	published := make(map[string][]byte, len(keys))
+	for i, value := range values {
+		if data, ok := value.(string); ok { // nil: expirou entre o SCAN e o MGET
+			published[strings.TrimPrefix(keys[i], CACHE_KEY_METRICS_PREFIX)] = []byte(data)
+		}
+	}
+	return published, nil
+}
+
+// metricsEntry é a janela de métricas publicada por uma instância
+type metricsEntry struct {
+	data      []byte
+	expiresAt time.Time
+}
+
+// MemoryMetricsStore guarda as janelas de métricas no próprio processo
+type MemoryMetricsStore struct {
+	mu      sync.RWMutex
+	metrics map[string]metricsEntry
+}
+
+// NewMemoryMetricsStore cria um store de métricas em memória
+func NewMemoryMetricsStore() *MemoryMetricsStore {
+	return &MemoryMetricsStore{metrics: make(map[string]metricsEntry)}
+}
+
+// SetInstanceMetrics guarda a janela de métricas da instância
+func (m *MemoryMetricsStore) SetInstanceMetrics(ctx context.Context, instanceID string, data []byte, ttl time.Duration) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.metrics[instanceID] = metricsEntry{data: data, expiresAt: time.Now().Add(ttl)}
+	return nil
+}
+
+// GetInstanceMetrics retorna as janelas de métricas que ainda não expiraram
+func (m *MemoryMetricsStore) GetInstanceMetrics(ctx context.Context) (map[string][]byte, error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	now := time.Now()
+	published := make(map[string][]byte, len(m.metrics))
+	for instanceID, entry := range m.metrics {
+		if now.Before(entry.expiresAt) {
+			published[instanceID] = entry.data
+		}
+	}
+	return published, nil
+}
@@ -0,0 +1,54 @@
+package cache
+
+import (
+	"fmt"
+	"time"
+)
+
+// Modos do override manual de roteamento
+const (
+	OVERRIDE_FORCE   = "force"   // Todo o tráfego vai para o processor informado
+	OVERRIDE_EXCLUDE = "exclude" // O processor informado é tratado como indisponível
+	OVERRIDE_PAUSE   = "pause"   // A API recusa novos pagamentos com 503
+
+	// Chave do override no Redis (expira junto com o override)
+	CACHE_KEY_ROUTING_OVERRIDE = "rinha:routing_override"
+
+	// Canal de pub/sub que avisa as instâncias quando o override muda ou é removido
+	CHANNEL_OVERRIDE_CHANGES = "rinha:override_changes"
+)
+
+// RoutingOverride é uma decisão manual de roteamento com prazo de validade
+type RoutingOverride struct {
+	Mode      string    `json:"mode"`
+	Processor string    `json:"processor,omitempty"`
+	Reason    string    `json:"reason,omitempty"`
+	CreatedAt time.Time `json:"created_at"`
+	ExpiresAt time.Time `json:"expires_at"`
+}
+
+// Active indica se o override existe e ainda não expirou
+func (o *RoutingOverride) Active(now time.Time) bool {
+	return o != nil && o.Mode != "" && now.Before(o.ExpiresAt)
+}
+
+// Validate confere se o modo e o processor formam um override válido
+func (o *RoutingOverride) Validate() error {
+	switch o.Mode {
+	case OVERRIDE_FORCE, OVERRIDE_EXCLUDE:
+		if o.Processor != "default" && o.Processor != "fallback" {
+			return fmt.Errorf("override %s exige processor default ou fallback", o.Mode)
+		}
+	case OVERRIDE_PAUSE:
+		if o.Processor != "" {
+			return fmt.Errorf("override %s não aceita processor", o.Mode)
+		}
+	default:
+		return fmt.Errorf("modo de override desconhecido: %q (use %s, %s ou %s)",
+			o.Mode, OVERRIDE_FORCE, OVERRIDE_EXCLUDE, OVERRIDE_PAUSE)
+	}
+	if !o.ExpiresAt.After(o.CreatedAt) {
+		return fmt.Errorf("override precisa de uma expiração posterior à criação")
+	}
+	return nil
+}
@@ -22,7 +22,6 @@ type ProcessorInfo struct {
 // RedisCache gerencia o cache de processors no Redis
 type RedisCache struct {
 	client *redis.Client
-	ctx    context.Context
 }
 
 const (
@@ -30,6 +29,8 @@ const (
 	CACHE_KEY_AVAILABLE_GATEWAY = "rinha:available_gateway"
 	CACHE_KEY_DEFAULT_STATUS    = "rinha:default_status"
 	CACHE_KEY_FALLBACK_STATUS   = "rinha:fallback_status"
+	// Último estado UP/DOWN de cada processor, sem TTL (rinha:processor_state:<processor>)
+	CACHE_KEY_PROCESSOR_STATE_PREFIX = "rinha:processor_state:"
 	
 	// TTL do cache
 	CACHE_TTL = 30 * time.Second
@@ -37,30 +38,38 @@ const (
 
 // NewRedisCache cria uma nova instância do cache Redis
 func NewRedisCache(redisURL string) (*RedisCache, error) {
+	client, err := newRedisClient(redisURL)
+	if err != nil {
+		return nil, err
+	}
+	return &RedisCache{client: client}, nil
+}
+
+// newRedisClient conecta no Redis; os stores do mesmo backend compartilham o client
+func newRedisClient(redisURL string) (*redis.Client, error) {
 	opts, err := redis.ParseURL(redisURL)
 	if err != nil {
 		return nil, fmt.Errorf("erro ao parsear Redis URL: %v", err)
 	}
 
 	client := redis.NewClient(opts)
-	ctx := context.Background()
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
 
 	// Testar conexão
 	if err := client.Ping(ctx).Err(); err != nil {
+		client.Close()
 		return nil, fmt.Errorf("erro ao conectar com Redis: %v", err)
 	}
 
 	log.Printf("✅ Redis Cache conectado com sucesso: %s", redisURL)
 
-	return &RedisCache{
-		client: client,
-		ctx:    ctx,
-	}, nil
+	return client, nil
 }
 
 // GetAvailableGateway retorna o último gateway disponível do cache
-func (r *RedisCache) GetAvailableGateway() (*ProcessorInfo, error) {
-	data, err := r.client.Get(r.ctx, CACHE_KEY_AVAILABLE_GATEWAY).Result()
+func (r *RedisCache) GetAvailableGateway(ctx context.Context) (*ProcessorInfo, error) {
+	data, err := r.client.Get(ctx, CACHE_KEY_AVAILABLE_GATEWAY).Result()
 	if err == redis.Nil {
 		log.Printf("🔍 Cache miss: nenhum gateway disponível no cache")
 		return nil, nil // Cache miss
@@ -81,7 +90,7 @@ func (r *RedisCache) GetAvailableGateway() (*ProcessorInfo, error) {
 }
 
 // SetAvailableGateway armazena o gateway disponível no cache
-func (r *RedisCache) SetAvailableGateway(processor *ProcessorInfo) error {
+func (r *RedisCache) SetAvailableGateway(ctx context.Context, processor *ProcessorInfo) error {
 	processor.LastCheck = time.Now()
 	
 	data, err := json.Marshal(processor)
@@ -89,7 +98,7 @@ func (r *RedisCache) SetAvailableGateway(processor *ProcessorInfo) error {
 		return fmt.Errorf("erro ao serializar gateway para cache: %v", err)
 	}
 
-	err = r.client.Set(r.ctx, CACHE_KEY_AVAILABLE_GATEWAY, data, CACHE_TTL).Err()
+	err = r.client.Set(ctx, CACHE_KEY_AVAILABLE_GATEWAY, data, CACHE_TTL).Err()
 	if err != nil {
 		return fmt.Errorf("erro ao salvar gateway no cache: %v", err)
 	}
@@ -101,15 +110,15 @@ func (r *RedisCache) SetAvailableGateway(processor *ProcessorInfo) error {
 }
 
 // InvalidateGateway remove o gateway específico do cache
-func (r *RedisCache) InvalidateGateway(processorName string) error {
+func (r *RedisCache) InvalidateGateway(ctx context.Context, processorName string) error {
 	// Verificar se o gateway no cache é o que está falhando
-	currentGateway, err := r.GetAvailableGateway()
+	currentGateway, err := r.GetAvailableGateway(ctx)
 	if err != nil {
 		return err
 	}
 	
 	if currentGateway != nil && currentGateway.Name == processorName {
-		err = r.client.Del(r.ctx, CACHE_KEY_AVAILABLE_GATEWAY).Err()
+		err = r.client.Del(ctx, CACHE_KEY_AVAILABLE_GATEWAY).Err()
 		if err != nil {
 			return fmt.Errorf("erro ao invalidar gateway %s do cache: %v", processorName, err)
 		}
@@ -121,7 +130,7 @@ func (r *RedisCache) InvalidateGateway(processorName string) error {
 }
 
 // SetProcessorStatus armazena o status individual de um processor
-func (r *RedisCache) SetProcessorStatus(processorName string, isAvailable bool) error {
+func (r *RedisCache) SetProcessorStatus(ctx context.Context, processorName string, isAvailable bool) error {
 	key := r.getProcessorStatusKey(processorName)
 	
 	statusData := map[string]interface{}{
@@ -135,7 +144,7 @@ func (r *RedisCache) SetProcessorStatus(processorName string, isAvailable bool)
 		return fmt.Errorf("erro ao serializar status do processor: %v", err)
 	}
 	
-	err = r.client.Set(r.ctx, key, data, CACHE_TTL).Err()
+	err = r.client.Set(ctx, key, data, CACHE_TTL).Err()
 	if err != nil {
 		return fmt.Errorf("erro ao salvar status do processor no cache: %v", err)
 	}
@@ -144,10 +153,10 @@ func (r *RedisCache) SetProcessorStatus(processorName string, isAvailable bool)
 }
 
 // GetProcessorStatus retorna o status de um processor específico
-func (r *RedisCache) GetProcessorStatus(processorName string) (bool, error) {
+func (r *RedisCache) GetProcessorStatus(ctx context.Context, processorName string) (bool, error) {
 	key := r.getProcessorStatusKey(processorName)
 	
-	data, err := r.client.Get(r.ctx, key).Result()
+	data, err := r.client.Get(ctx, key).Result()
 	if err == redis.Nil {
 		return false, nil // Cache miss, assume não disponível
 	}
@@ -168,19 +177,37 @@ func (r *RedisCache) GetProcessorStatus(processorName string) (bool, error) {
 	return isAvailable, nil
 }
 
+// SwapProcessorState troca o estado do processor com GETSET; só a instância que lê o
+// estado anterior diferente vê a transição
+func (r *RedisCache) SwapProcessorState(ctx context.Context, processorName string, isAvailable bool) (bool, error) {
+	state := "down"
+	if isAvailable {
+		state = "up"
+	}
+
+	previous, err := r.client.GetSet(ctx, CACHE_KEY_PROCESSOR_STATE_PREFIX+processorName, state).Result()
+	if err == redis.Nil {
+		return false, nil // Primeiro estado observado
+	}
+	if err != nil {
+		return false, fmt.Errorf("erro ao trocar estado do processor %s: %v", processorName, err)
+	}
+	return previous != state, nil
+}
+
 // GetAllProcessorStatus retorna o status de todos os processors
-func (r *RedisCache) GetAllProcessorStatus() map[string]bool {
+func (r *RedisCache) GetAllProcessorStatus(ctx context.Context) map[string]bool {
 	status := make(map[string]bool)
 	
 	// Buscar status do default
-	if defaultStatus, err := r.GetProcessorStatus("default"); err == nil {
+	if defaultStatus, err := r.GetProcessorStatus(ctx, "default"); err == nil {
 		status["default"] = defaultStatus
 	} else {
 		status["default"] = false
 	}
 	
 	// Buscar status do fallback
-	if fallbackStatus, err := r.GetProcessorStatus("fallback"); err == nil {
+	if fallbackStatus, err := r.GetProcessorStatus(ctx, "fallback"); err == nil {
 		status["fallback"] = fallbackStatus
 	} else {
 		status["fallback"] = false
@@ -189,6 +216,61 @@ func (r *RedisCache) GetAllProcessorStatus() map[string]bool {
 	return status
 }
 
+// GetRoutingOverride retorna o override manual de roteamento (nil se não houver)
+func (r *RedisCache) GetRoutingOverride(ctx context.Context) (*RoutingOverride, error) {
+	data, err := r.client.Get(ctx, CACHE_KEY_ROUTING_OVERRIDE).Result()
+	if err == redis.Nil {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("erro ao buscar override de roteamento: %v", err)
+	}
+
+	var override RoutingOverride
+	if err := json.Unmarshal([]byte(data), &override); err != nil {
+		return nil, fmt.Errorf("erro ao deserializar override de roteamento: %v", err)
+	}
+	if !override.Active(time.Now()) {
+		return nil, nil
+	}
+	return &override, nil
+}
+
+// SetRoutingOverride grava o override com TTL até o seu ExpiresAt
+func (r *RedisCache) SetRoutingOverride(ctx context.Context, override *RoutingOverride) error {
+	ttl := time.Until(override.ExpiresAt)
+	if ttl <= 0 {
+		return fmt.Errorf("override de roteamento já expirado")
+	}
+
+	data, err := json.Marshal(override)
+	if err != nil {
+		return fmt.Errorf("erro ao serializar override de roteamento: %v", err)
+	}
+	if err := r.client.Set(ctx, CACHE_KEY_ROUTING_OVERRIDE, data, ttl).Err(); err != nil {
+		return fmt.Errorf("erro ao salvar override de roteamento: %v", err)
+	}
+
+	log.Printf("💾 Override de roteamento salvo: %s %s (TTL: %v)", override.Mode, override.Processor, ttl.Round(time.Second))
+	return nil
+}
+
+// ClearRoutingOverride remove o override manual de roteamento
+func (r *RedisCache) ClearRoutingOverride(ctx context.Context) error {
+	if err := r.client.Del(ctx, CACHE_KEY_ROUTING_OVERRIDE).Err(); err != nil {
+		return fmt.Errorf("erro ao remover override de roteamento: %v", err)
+	}
+	return nil
+}
+
+// Ping verifica se o Redis está respondendo
+func (r *RedisCache) Ping(ctx context.Context) error {
+	if err := r.client.Ping(ctx).Err(); err != nil {
+		return fmt.Errorf("erro ao pingar Redis: %v", err)
+	}
+	return nil
+}
+
 // Close fecha a conexão com o Redis
 func (r *RedisCache) Close() error {
 	return r.client.Close()
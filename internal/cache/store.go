@@ -0,0 +1,82 @@
+package cache
+
+import (
+	"context"
+	"fmt"
+)
+
+// ProcessorStatusStore guarda o gateway disponível, o status de cada processor e o
+// override de roteamento. RedisCache compartilha o estado entre instâncias; MemoryCache
+// mantém tudo no processo (modo single-instance e testes). Pub/sub, métricas e webhooks
+// ficam em Broker, MetricsStore e WebhookStore.
+type ProcessorStatusStore interface {
+	GetAvailableGateway(ctx context.Context) (*ProcessorInfo, error)
+	SetAvailableGateway(ctx context.Context, processor *ProcessorInfo) error
+	InvalidateGateway(ctx context.Context, processorName string) error
+	SetProcessorStatus(ctx context.Context, processorName string, isAvailable bool) error
+	GetProcessorStatus(ctx context.Context, processorName string) (bool, error)
+	GetAllProcessorStatus(ctx context.Context) map[string]bool
+	// SwapProcessorState grava o último estado UP/DOWN observado (sem TTL) e retorna true
+	// se ele mudou o estado compartilhado. A troca é atômica: entre as instâncias que
+	// observam a mesma transição, só uma recebe true. O primeiro estado não conta.
+	SwapProcessorState(ctx context.Context, processorName string, isAvailable bool) (bool, error)
+	// Override manual de roteamento; nil quando não há override ativo
+	GetRoutingOverride(ctx context.Context) (*RoutingOverride, error)
+	// SetRoutingOverride grava o override até o seu ExpiresAt
+	SetRoutingOverride(ctx context.Context, override *RoutingOverride) error
+	ClearRoutingOverride(ctx context.Context) error
+	Ping(ctx context.Context) error
+	Close() error
+}
+
+const (
+	// Canal de pub/sub com as mudanças de roteamento publicadas pelo Gateway Instance
+	CHANNEL_GATEWAY_CHANGES = "rinha:gateway_changes"
+
+	// Canal de pub/sub com os eventos de todas as instâncias (GET /events com EVENTS_FANOUT)
+	CHANNEL_EVENTS = "rinha:events"
+
+	// Backends suportados para o status store
+	STORE_BACKEND_REDIS  = "redis"
+	STORE_BACKEND_MEMORY = "memory"
+)
+
+// Stores reúne os stores de um backend. No Redis todos compartilham a mesma conexão.
+type Stores struct {
+	Status   ProcessorStatusStore
+	Broker   Broker
+	Metrics  MetricsStore
+	Webhooks WebhookStore
+}
+
+// Close fecha a conexão compartilhada pelos stores
+func (s *Stores) Close() error {
+	return s.Status.Close()
+}
+
+// NewStores cria os stores do backend configurado
+func NewStores(backend, redisURL string) (*Stores, error) {
+	switch backend {
+	case STORE_BACKEND_REDIS, "":
+		client, err := newRedisClient(redisURL)
+		if err != nil {
+			return nil, err
+		}
+		return &Stores{
+			Status:   &RedisCache{client: client},
+			Broker:   &RedisBroker{client: client},
+			Metrics:  &RedisMetricsStore{client: client},
+			Webhooks: &RedisWebhookStore{client: client},
+		}, nil
+	case STORE_BACKEND_MEMORY:
+		return &Stores{
+			Status:   NewMemoryCache(),
+			Broker:   NewMemoryBroker(),
+			Metrics:  NewMemoryMetricsStore(),
+			Webhooks: NewMemoryWebhookStore(),
+		}, nil
+	default:
+		return nil, fmt.Errorf("status store desconhecido: %s (use %s ou %s)",
+			backend, STORE_BACKEND_REDIS, STORE_BACKEND_MEMORY)
+	}
+}
@@ -0,0 +1,159 @@
+package cache
+
+import (
+	"context"
+	"fmt"
+	"sync"
+
+	"github.com/go-redis/redis/v8"
+)
+
+const (
+	// Hash com as inscrições de webhook (campo = id, valor = inscrição em JSON)
+	CACHE_KEY_WEBHOOKS = "rinha:webhooks"
+
+	// Lista com as últimas tentativas de entrega de webhook, mais recentes primeiro
+	CACHE_KEY_WEBHOOK_DELIVERIES = "rinha:webhook_deliveries"
+
+	// Canal de pub/sub que avisa as instâncias quando as inscrições de webhook mudam
+	CHANNEL_WEBHOOK_CHANGES = "rinha:webhook_changes"
+)
+
+// WebhookStore guarda as inscrições de webhook (JSON por id) e o log de entregas
+type WebhookStore interface {
+	SetWebhook(ctx context.Context, id string, data []byte) error
+	GetWebhooks(ctx context.Context) (map[string][]byte, error)
+	// DeleteWebhook retorna false se a inscrição não existia
+	DeleteWebhook(ctx context.Context, id string) (bool, error)
+	// Log de entregas de webhook, limitado às max mais recentes
+	AppendWebhookDelivery(ctx context.Context, data []byte, max int) error
+	GetWebhookDeliveries(ctx context.Context, limit int) ([][]byte, error)
+}
+
+// RedisWebhookStore compartilha as inscrições e o log de entregas entre instâncias
+type RedisWebhookStore struct {
+	client *redis.Client
+}
+
+// SetWebhook grava (ou substitui) uma inscrição de webhook
+func (r *RedisWebhookStore) SetWebhook(ctx context.Context, id string, data []byte) error {
+	if err := r.client.HSet(ctx, CACHE_KEY_WEBHOOKS, id, data).Err(); err != nil {
+		return fmt.Errorf("erro ao salvar webhook: %v", err)
+	}
+	return nil
+}
+
+// GetWebhooks retorna todas as inscrições de webhook
+func (r *RedisWebhookStore) GetWebhooks(ctx context.Context) (map[string][]byte, error) {
+	values, err := r.client.HGetAll(ctx, CACHE_KEY_WEBHOOKS).Result()
+	if err != nil {
+		return nil, fmt.Errorf("erro ao buscar webhooks: %v", err)
+	}
+
+	webhooks := make(map[string][]byte, len(values))
+	for id, data := range values {
+		webhooks[id] = []byte(data)
+	}
+	return webhooks, nil
+}
+
+// DeleteWebhook remove uma inscrição de webhook
+func (r *RedisWebhookStore) DeleteWebhook(ctx context.Context, id string) (bool, error) {
+	removed, err := r.client.HDel(ctx, CACHE_KEY_WEBHOOKS, id).Result()
+	if err != nil {
+		return false, fmt.Errorf("erro ao remover webhook: %v", err)
+	}
+	return removed > 0, nil
+}
+
+// AppendWebhookDelivery registra uma tentativa de entrega, mantendo só as max mais recentes
+func (r *RedisWebhookStore) AppendWebhookDelivery(ctx context.Context, data []byte, max int) error {
+	pipe := r.client.TxPipeline()
+	pipe.LPush(ctx, CACHE_KEY_WEBHOOK_DELIVERIES, data)
+	pipe.LTrim(ctx, CACHE_KEY_WEBHOOK_DELIVERIES, 0, int64(max-1))
+	if _, err := pipe.Exec(ctx); err != nil {
+		return fmt.Errorf("erro ao registrar entrega de webhook: %v", err)
+	}
+	return nil
+}
+
+// GetWebhookDeliveries retorna as últimas tentativas de entrega, mais recentes primeiro
+func (r *RedisWebhookStore) GetWebhookDeliveries(ctx context.Context, limit int) ([][]byte, error) {
+	values, err := r.client.LRange(ctx, CACHE_KEY_WEBHOOK_DELIVERIES, 0, int64(limit-1)).Result()
+	if err != nil {
+		return nil, fmt.Errorf("erro ao buscar entregas de webhook: %v", err)
+	}
+
+	deliveries := make([][]byte, len(values))
+	for i, data := range values {
+		deliveries[i] = []byte(data)
+	}
+	return deliveries, nil
+}
+
+// MemoryWebhookStore guarda as inscrições e o log de entregas no próprio processo
+type MemoryWebhookStore struct {
+	mu       sync.RWMutex
+	webhooks map[string][]byte
+	// Entregas de webhook, mais antigas primeiro
+	deliveries [][]byte
+}
+
+// NewMemoryWebhookStore cria um store de webhooks em memória
+func NewMemoryWebhookStore() *MemoryWebhookStore {
+	return &MemoryWebhookStore{webhooks: make(map[string][]byte)}
+}
+
+// SetWebhook grava (ou substitui) uma inscrição de webhook
+func (m *MemoryWebhookStore) SetWebhook(ctx context.Context, id string, data []byte) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.webhooks[id] = data
+	return nil
+}
+
+// GetWebhooks retorna todas as inscrições de webhook
+func (m *MemoryWebhookStore) GetWebhooks(ctx context.Context) (map[string][]byte, error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	webhooks := make(map[string][]byte, len(m.webhooks))
+	for id, data := range m.webhooks {
+		webhooks[id] = data
+	}
+	return webhooks, nil
+}
+
+// DeleteWebhook remove uma inscrição de webhook
+func (m *MemoryWebhookStore) DeleteWebhook(ctx context.Context, id string) (bool, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	_, ok := m.webhooks[id]
+	delete(m.webhooks, id)
+	return ok, nil
+}
+
+// AppendWebhookDelivery registra uma tentativa de entrega, mantendo só as max mais recentes
+func (m *MemoryWebhookStore) AppendWebhookDelivery(ctx context.Context, data []byte, max int) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	m.deliveries = append(m.deliveries, data)
+	if len(m.deliveries) > max {
+		m.deliveries = append([][]byte(nil), m.deliveries[len(m.deliveries)-max:]...)
+	}
+	return nil
+}
+
+// GetWebhookDeliveries retorna as últimas tentativas de entrega, mais recentes primeiro
+func (m *MemoryWebhookStore) GetWebhookDeliveries(ctx context.Context, limit int) ([][]byte, error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	deliveries := make([][]byte, 0, limit)
+	for i := len(m.deliveries) - 1; i >= 0 && len(deliveries) < limit; i-- {
+		deliveries = append(deliveries, m.deliveries[i])
+	}
+	return deliveries, nil
+}
@@ -0,0 +1,36 @@
+package dashboard
+
+import (
+	"embed"
+	"log"
+	"net/http"
+)
+
+// files guarda o painel compilado no binário: HTML, CSS e JS em um único arquivo,
+// sem assets externos (funciona sem acesso à internet)
+//
+//go:embed index.html
+var files embed.FS
+
+var page = mustRead("index.html")
+
+func mustRead(name string) []byte {
+	data, err := files.ReadFile(name)
+	if err != nil {
+		log.Fatalf("❌ Erro ao carregar %s do dashboard: %v", name, err)
+	}
+	return data
+}
+
+// Page serve o painel de operações (GET /dashboard). O painel consulta /dashboard/overview,
+// /health, /payments-summary e /payments/stats e acompanha as falhas por /events.
+func Page(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet && r.Method != http.MethodHead {
+		http.Error(w, "Método não permitido", http.StatusMethodNotAllowed)
+		return
+	}
+
+	w.Header().Set("Content-Type", "text/html; charset=utf-8")
+	w.Header().Set("Cache-Control", "no-cache")
+	w.Write(page)
+}
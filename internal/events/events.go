@@ -0,0 +1,153 @@
+package events
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"log"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// Tipos de evento publicados no bus
+const (
+	PAYMENT_SUCCEEDED        = "payment.succeeded"        // Pagamento aceito pelo processor e gravado
+	PAYMENT_FAILED           = "payment.failed"           // Pagamento registrado pelo fail safe (dead letter)
+	PROCESSOR_STATUS_CHANGED = "processor.status_changed" // Health check do processor mudou de UP/DOWN
+	GATEWAY_CHANGED          = "gateway.changed"          // Gateway Instance trocou o processor escolhido
+	LIMITER_STATE_CHANGED    = "limiter.state_changed"    // Limitador do processor estrangulou ou se recuperou (limiter.State)
+)
+
+// Types são todos os tipos de evento conhecidos
+var Types = []string{PAYMENT_SUCCEEDED, PAYMENT_FAILED, PROCESSOR_STATUS_CHANGED, GATEWAY_CHANGED, LIMITER_STATE_CHANGED}
+
+// ValidType indica se o tipo de evento é conhecido
+func ValidType(eventType string) bool {
+	for _, known := range Types {
+		if known == eventType {
+			return true
+		}
+	}
+	return false
+}
+
+// Event é um acontecimento da instância, entregue aos inscritos do bus
+type Event struct {
+	ID       string          `json:"id"`
+	Type     string          `json:"type"`
+	Instance string          `json:"instance"`
+	Time     time.Time       `json:"time"`
+	Data     json.RawMessage `json:"data"`
+}
+
+// ProcessorStatus é o dado de PROCESSOR_STATUS_CHANGED e GATEWAY_CHANGED (processor
+// escolhido; available=false quando todos ficaram DOWN)
+type ProcessorStatus struct {
+	Processor string `json:"processor"`
+	Available bool   `json:"available"`
+}
+
+// Bus distribui eventos aos inscritos (webhooks, streams). Publish nunca bloqueia:
+// inscritos lentos perdem eventos, contados em Subscription.Dropped.
+type Bus struct {
+	instanceID string
+
+	mu          sync.RWMutex
+	subscribers map[*Subscription]struct{}
+}
+
+// Subscription é a inscrição em um bus; C é fechado quando o ctx do Subscribe é cancelado
+type Subscription struct {
+	C       <-chan Event
+	ch      chan Event
+	types   map[string]bool // Vazio: todos os tipos
+	dropped atomic.Int64
+}
+
+// Dropped retorna quantos eventos foram descartados por C estar cheio
+func (s *Subscription) Dropped() int64 {
+	return s.dropped.Load()
+}
+
+// NewBus cria o bus de eventos da instância
+func NewBus(instanceID string) *Bus {
+	return &Bus{instanceID: instanceID, subscribers: make(map[*Subscription]struct{})}
+}
+
+// Publish serializa o dado e entrega o evento aos inscritos do tipo (nil-safe).
+// Sem inscritos, não há custo de serialização.
+func (b *Bus) Publish(eventType string, data interface{}) {
+	if b == nil {
+		return
+	}
+
+	b.mu.RLock()
+	defer b.mu.RUnlock()
+	if len(b.subscribers) == 0 {
+		return
+	}
+
+	raw, err := json.Marshal(data)
+	if err != nil {
+		log.Printf("❌ Erro ao serializar evento %s: %v", eventType, err)
+		return
+	}
+	b.deliver(Event{ID: NewID(), Type: eventType, Instance: b.instanceID, Time: time.Now(), Data: raw})
+}
+
+// Deliver entrega um evento já montado (recebido de outra instância) aos inscritos do tipo
+func (b *Bus) Deliver(event Event) {
+	b.mu.RLock()
+	defer b.mu.RUnlock()
+	b.deliver(event)
+}
+
+// deliver é chamado com o lock de leitura
+func (b *Bus) deliver(event Event) {
+	for sub := range b.subscribers {
+		if len(sub.types) > 0 && !sub.types[event.Type] {
+			continue
+		}
+		select {
+		case sub.ch <- event:
+		default:
+			if sub.dropped.Add(1)%100 == 1 {
+				log.Printf("⚠️ Inscrito lento no bus de eventos, %d eventos descartados", sub.dropped.Load())
+			}
+		}
+	}
+}
+
+// Subscribe entrega os eventos dos tipos informados (todos, se nenhum) até o ctx ser
+// cancelado. buffer é quantos eventos podem ficar pendentes antes do descarte.
+func (b *Bus) Subscribe(ctx context.Context, buffer int, types ...string) *Subscription {
+	ch := make(chan Event, buffer)
+	sub := &Subscription{C: ch, ch: ch, types: make(map[string]bool, len(types))}
+	for _, eventType := range types {
+		sub.types[eventType] = true
+	}
+
+	b.mu.Lock()
+	b.subscribers[sub] = struct{}{}
+	b.mu.Unlock()
+
+	go func() {
+		<-ctx.Done()
+
+		b.mu.Lock()
+		delete(b.subscribers, sub)
+		b.mu.Unlock()
+		close(sub.ch)
+	}()
+
+	return sub
+}
+
+// NewID gera um identificador aleatório de 16 bytes em hex (eventos, webhooks, entregas)
+func NewID() string {
+	var id [16]byte
+	rand.Read(id[:])
+	return hex.EncodeToString(id[:])
+}
@@ -0,0 +1,57 @@
+package events
+
+import (
+	"context"
+	"encoding/json"
+	"log"
+
+	"rinha-de-backend-2025/internal/cache"
+)
+
+// Broker é o pub/sub entre instâncias (cache.RedisBroker ou cache.MemoryBroker)
+type Broker interface {
+	Publish(ctx context.Context, channel string, message []byte) error
+	Subscribe(ctx context.Context, channel string) (<-chan []byte, error)
+}
+
+// Fanout publica os eventos do bus local no canal rinha:events e retorna um bus com os
+// eventos de todas as instâncias (inclusive desta), recebidos pelo mesmo canal. O bus
+// local passa a ter um inscrito permanente: cada evento custa um PUBLISH no Redis.
+func Fanout(ctx context.Context, local *Bus, broker Broker, buffer int) (*Bus, error) {
+	messages, err := broker.Subscribe(ctx, cache.CHANNEL_EVENTS)
+	if err != nil {
+		return nil, err
+	}
+
+	cluster := NewBus(local.instanceID)
+	go func() {
+		for message := range messages {
+			var event Event
+			if err := json.Unmarshal(message, &event); err != nil {
+				log.Printf("⚠️ Evento inválido no canal %s: %v", cache.CHANNEL_EVENTS, err)
+				continue
+			}
+			cluster.Deliver(event)
+		}
+	}()
+
+	outgoing := local.Subscribe(ctx, buffer)
+	go func() {
+		failing := false
+		for event := range outgoing.C {
+			data, err := json.Marshal(event)
+			if err == nil {
+				err = broker.Publish(ctx, cache.CHANNEL_EVENTS, data)
+			}
+			if err != nil && !failing {
+				log.Printf("⚠️ Erro ao publicar eventos no canal %s: %v", cache.CHANNEL_EVENTS, err)
+			} else if err == nil && failing {
+				log.Printf("✅ Publicação de eventos no canal %s restabelecida", cache.CHANNEL_EVENTS)
+			}
+			failing = err != nil
+		}
+	}()
+
+	log.Printf("📡 Eventos compartilhados entre instâncias pelo canal %s", cache.CHANNEL_EVENTS)
+	return cluster, nil
+}
@@ -2,44 +2,84 @@ package gateway
 
 import (
 	"context"
+	"encoding/json"
 	"fmt"
+	"io"
 	"log"
 	"net/http"
 	"sync"
 	"time"
 
 	"rinha-de-backend-2025/internal/cache"
+	"rinha-de-backend-2025/internal/events"
+	"rinha-de-backend-2025/internal/metrics"
 )
 
 // GatewayInstance representa uma instância do gateway que roda em paralelo
 type GatewayInstance struct {
-	defaultURL   string
-	fallbackURL  string
-	redisCache   *cache.RedisCache
-	httpClient   *http.Client
-	ctx          context.Context
-	cancel       context.CancelFunc
-	wg           sync.WaitGroup
-	isRunning    bool
-	mu           sync.RWMutex
+	defaultURL    string
+	fallbackURL   string
+	statusStore   cache.ProcessorStatusStore
+	broker        cache.Broker
+	httpClient    *http.Client
+	healthTimeout time.Duration
+	ctx           context.Context
+	cancel        context.CancelFunc
+	wg            sync.WaitGroup
+	isRunning     bool
+	mu            sync.RWMutex
+	interval      time.Duration
+	probes        map[string]ProbeResult
+	probesMu      sync.RWMutex
+	checkMu       sync.Mutex
+	expiryTimer   *time.Timer // Reavaliação na expiração do override (protegido por checkMu)
+	metrics       *metrics.Registry
+	events        *events.Bus
+}
+
+// ProbeResult guarda o resultado do último health check de um processor
+type ProbeResult struct {
+	Healthy     bool      `json:"healthy"`
+	LastCheck   time.Time `json:"last_check"`
+	LastSuccess time.Time `json:"last_success"`
 }
 
 // NewGatewayInstance cria uma nova instância do gateway
-func NewGatewayInstance(defaultURL, fallbackURL string, redisCache *cache.RedisCache) *GatewayInstance {
+func NewGatewayInstance(
+	defaultURL, fallbackURL string,
+	statusStore cache.ProcessorStatusStore,
+	broker cache.Broker,
+	httpClient *http.Client,
+	healthTimeout time.Duration,
+) *GatewayInstance {
 	ctx, cancel := context.WithCancel(context.Background())
 	
 	return &GatewayInstance{
-		defaultURL:  defaultURL,
-		fallbackURL: fallbackURL,
-		redisCache:  redisCache,
-		httpClient: &http.Client{
-			Timeout: 5 * time.Second,
-		},
-		ctx:    ctx,
-		cancel: cancel,
+		defaultURL:    defaultURL,
+		fallbackURL:   fallbackURL,
+		statusStore:   statusStore,
+		broker:        broker,
+		httpClient:    httpClient,
+		healthTimeout: healthTimeout,
+		ctx:           ctx,
+		cancel:        cancel,
+		interval:      30 * time.Second,
+		probes:        make(map[string]ProbeResult),
 	}
 }
 
+// SetMetrics faz os health checks alimentarem as janelas de métricas dos processors.
+// Deve ser chamado antes do Start.
+func (gi *GatewayInstance) SetMetrics(registry *metrics.Registry) {
+	gi.metrics = registry
+}
+
+// SetEvents faz as transições UP/DOWN dos processors e as trocas de gateway serem
+// publicadas no bus de eventos. Deve ser chamado antes do Start.
+func (gi *GatewayInstance) SetEvents(bus *events.Bus) {
+	gi.events = bus
+}
+
 // Start inicia o Gateway Instance em background
 func (gi *GatewayInstance) Start() {
 	gi.mu.Lock()
@@ -55,12 +95,12 @@ func (gi *GatewayInstance) Start() {
 	log.Printf("🚀 Iniciando Gateway Instance...")
 	log.Printf("   - Default Processor: %s", gi.defaultURL)
 	log.Printf("   - Fallback Processor: %s", gi.fallbackURL)
-	log.Printf("   - Health Check Interval: 5s")
+	log.Printf("   - Health Check Interval: %v", gi.interval)
 	
 	// Fazer um health check inicial imediato
 	go gi.performInitialHealthCheck()
 	
-	// Iniciar loop de health checks a cada 5 segundos
+	// Iniciar loop de health checks a cada gi.interval
 	gi.wg.Add(1)
 	go gi.healthCheckLoop()
 }
@@ -77,6 +117,11 @@ func (gi *GatewayInstance) Stop() {
 	log.Printf("🛑 Parando Gateway Instance...")
 	gi.cancel()
 	gi.wg.Wait()
+	gi.checkMu.Lock()
+	if gi.expiryTimer != nil {
+		gi.expiryTimer.Stop()
+	}
+	gi.checkMu.Unlock()
 	gi.isRunning = false
 	log.Printf("✅ Gateway Instance parado")
 }
@@ -88,17 +133,66 @@ func (gi *GatewayInstance) IsRunning() bool {
 	return gi.isRunning
 }
 
+// Interval retorna o intervalo entre health checks automáticos
+func (gi *GatewayInstance) Interval() time.Duration {
+	return gi.interval
+}
+
+// ProbeStatus retorna uma cópia do último resultado de health check de cada processor
+func (gi *GatewayInstance) ProbeStatus() map[string]ProbeResult {
+	gi.probesMu.RLock()
+	defer gi.probesMu.RUnlock()
+
+	status := make(map[string]ProbeResult, len(gi.probes))
+	for name, probe := range gi.probes {
+		status[name] = probe
+	}
+	return status
+}
+
+// recordProbe registra o resultado de um health check, grava o status no store e publica
+// a transição, se houver. Todas as instâncias fazem health checks; só a que troca o estado
+// compartilhado publica processor.status_changed, para o evento (e o webhook) sair uma vez.
+func (gi *GatewayInstance) recordProbe(processorName string, healthy bool) {
+	gi.probesMu.Lock()
+	probe, checked := gi.probes[processorName]
+	changed := checked && probe.Healthy != healthy
+	probe.Healthy = healthy
+	probe.LastCheck = time.Now()
+	if healthy {
+		probe.LastSuccess = probe.LastCheck
+	}
+	gi.probes[processorName] = probe
+	gi.probesMu.Unlock()
+
+	gi.statusStore.SetProcessorStatus(gi.ctx, processorName, healthy)
+	if swapped, err := gi.statusStore.SwapProcessorState(gi.ctx, processorName, healthy); err != nil {
+		log.Printf("⚠️ Erro ao trocar estado compartilhado de %s, usando a transição local: %v", processorName, err)
+	} else {
+		changed = swapped
+	}
+
+	if changed {
+		status := "DOWN"
+		if healthy {
+			status = "UP"
+		}
+		log.Printf("🔀 Processor %s mudou para %s", processorName, status)
+		gi.events.Publish(events.PROCESSOR_STATUS_CHANGED, events.ProcessorStatus{Processor: processorName, Available: healthy})
+	}
+}
+
 // performInitialHealthCheck faz uma verificação inicial dos processors
 func (gi *GatewayInstance) performInitialHealthCheck() {
 	log.Printf("🔍 Executando health check inicial...")
 	
 	// Verificar Default Processor
-	defaultUp := gi.checkProcessorHealth(gi.defaultURL)
-	gi.redisCache.SetProcessorStatus("default", defaultUp)
+	defaultUp := gi.checkProcessorHealth("default", gi.defaultURL)
+	gi.recordProbe("default", defaultUp)
 	
 	// Verificar Fallback Processor
-	fallbackUp := gi.checkProcessorHealth(gi.fallbackURL)
-	gi.redisCache.SetProcessorStatus("fallback", fallbackUp)
+	fallbackUp := gi.checkProcessorHealth("fallback", gi.fallbackURL)
+	gi.recordProbe("fallback", fallbackUp)
 	
 	// Atualizar cache com o melhor processor disponível
 	gi.updateAvailableGateway(defaultUp, fallbackUp)
@@ -106,11 +200,11 @@ func (gi *GatewayInstance) performInitialHealthCheck() {
 	log.Printf("✅ Health check inicial concluído: Default=%t, Fallback=%t", defaultUp, fallbackUp)
 }
 
-// healthCheckLoop executa health checks a cada 5 segundos
+// healthCheckLoop executa health checks a cada gi.interval (30s por padrão)
 func (gi *GatewayInstance) healthCheckLoop() {
 	defer gi.wg.Done()
 	
-	ticker := time.NewTicker(30 * time.Second)
+	ticker := time.NewTicker(gi.interval)
 	defer ticker.Stop()
 	
 	for {
@@ -124,17 +218,29 @@ func (gi *GatewayInstance) healthCheckLoop() {
 	}
 }
 
+// CheckNow executa um ciclo de health check imediatamente (acionado pelo admin)
+// e retorna o resultado das probes
+func (gi *GatewayInstance) CheckNow() map[string]ProbeResult {
+	log.Printf("🔄 Health check acionado manualmente")
+	gi.performHealthCheck()
+	return gi.ProbeStatus()
+}
+
 // performHealthCheck executa um ciclo completo de health check
 func (gi *GatewayInstance) performHealthCheck() {
+	// O ciclo manual e o automático não rodam ao mesmo tempo
+	gi.checkMu.Lock()
+	defer gi.checkMu.Unlock()
+
 	log.Printf("🔄 Executando health check automático...")
 	
 	// Verificar Default Processor
-	defaultUp := gi.checkProcessorHealth(gi.defaultURL)
-	gi.redisCache.SetProcessorStatus("default", defaultUp)
+	defaultUp := gi.checkProcessorHealth("default", gi.defaultURL)
+	gi.recordProbe("default", defaultUp)
 	
 	// Verificar Fallback Processor
-	fallbackUp := gi.checkProcessorHealth(gi.fallbackURL)
-	gi.redisCache.SetProcessorStatus("fallback", fallbackUp)
+	fallbackUp := gi.checkProcessorHealth("fallback", gi.fallbackURL)
+	gi.recordProbe("fallback", fallbackUp)
 	
 	// Atualizar cache com o melhor processor disponível
 	gi.updateAvailableGateway(defaultUp, fallbackUp)
@@ -142,16 +248,28 @@ func (gi *GatewayInstance) performHealthCheck() {
 	log.Printf("🔍 Health check concluído: Default=%t, Fallback=%t", defaultUp, fallbackUp)
 }
 
-// checkProcessorHealth verifica se um processor específico está healthy
-func (gi *GatewayInstance) checkProcessorHealth(url string) bool {
+// checkProcessorHealth verifica se um processor específico está healthy e registra a probe nas métricas
+func (gi *GatewayInstance) checkProcessorHealth(name, url string) bool {
+	ctx, cancel := context.WithTimeout(gi.ctx, gi.healthTimeout)
+	defer cancel()
+
 	healthURL := fmt.Sprintf("%s/payments/service-health", url)
-	
-	resp, err := gi.httpClient.Get(healthURL)
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, healthURL, nil)
 	if err != nil {
+		log.Printf("❌ Erro ao criar health check para %s: %v", url, err)
+		return false
+	}
+
+	start := time.Now()
+	resp, err := gi.httpClient.Do(req)
+	if err != nil {
+		gi.metrics.ObserveProbe(name, time.Since(start), 0, err)
 		log.Printf("❌ Health check falhou para %s: %v", url, err)
 		return false
 	}
 	defer resp.Body.Close()
+	io.Copy(io.Discard, resp.Body)
+	gi.metrics.ObserveProbe(name, time.Since(start), resp.StatusCode, nil)
 	
 	isHealthy := resp.StatusCode == http.StatusOK
 	if isHealthy {
@@ -163,10 +281,56 @@ func (gi *GatewayInstance) checkProcessorHealth(url string) bool {
 	return isHealthy
 }
 
+// Reevaluate recalcula o gateway disponível com o resultado das últimas probes,
+// sem novas chamadas aos processors (usado quando o override muda). Com um override
+// ativo, (re)agenda a reavaliação para quando ele expirar, para o roteamento não
+// ficar preso ao override até o próximo health check. Há um único timer por instância.
+func (gi *GatewayInstance) Reevaluate() {
+	gi.checkMu.Lock()
+	defer gi.checkMu.Unlock()
+
+	probes := gi.ProbeStatus()
+	gi.updateAvailableGateway(probes["default"].Healthy, probes["fallback"].Healthy)
+
+	override, err := gi.statusStore.GetRoutingOverride(gi.ctx)
+	if err != nil || !override.Active(time.Now()) {
+		if gi.expiryTimer != nil {
+			gi.expiryTimer.Stop()
+		}
+		return
+	}
+
+	delay := time.Until(override.ExpiresAt) + 100*time.Millisecond
+	if gi.expiryTimer == nil {
+		gi.expiryTimer = time.AfterFunc(delay, func() {
+			if gi.ctx.Err() == nil {
+				gi.Reevaluate()
+			}
+		})
+		return
+	}
+	gi.expiryTimer.Reset(delay)
+}
+
 // updateAvailableGateway atualiza o cache com o melhor processor disponível
 func (gi *GatewayInstance) updateAvailableGateway(defaultUp, fallbackUp bool) {
+	// Override manual: force vale mesmo com o processor DOWN, exclude o trata como DOWN
+	if override, err := gi.statusStore.GetRoutingOverride(gi.ctx); err == nil && override != nil {
+		switch override.Mode {
+		case cache.OVERRIDE_FORCE:
+			defaultUp = override.Processor == "default"
+			fallbackUp = override.Processor == "fallback"
+		case cache.OVERRIDE_EXCLUDE:
+			if override.Processor == "default" {
+				defaultUp = false
+			} else {
+				fallbackUp = false
+			}
+		}
+	}
+
 	// Buscar o gateway atual do cache
-	currentGateway, _ := gi.redisCache.GetAvailableGateway()
+	currentGateway, _ := gi.statusStore.GetAvailableGateway(gi.ctx)
 	
 	var newGateway *cache.ProcessorInfo
 	
@@ -191,17 +355,40 @@ func (gi *GatewayInstance) updateAvailableGateway(defaultUp, fallbackUp bool) {
 	if newGateway == nil {
 		if currentGateway != nil {
 			log.Printf("⚠️ Todos os processors estão DOWN, invalidando cache")
-			gi.redisCache.InvalidateGateway(currentGateway.Name)
+			gi.statusStore.InvalidateGateway(gi.ctx, currentGateway.Name)
+			gi.publishGatewayChange(&cache.ProcessorInfo{
+				Name:        currentGateway.Name,
+				URL:         currentGateway.URL,
+				IsDefault:   currentGateway.IsDefault,
+				IsAvailable: false,
+				LastCheck:   time.Now(),
+			})
 		}
 		return
 	}
 	
 	// Se o gateway mudou ou não há gateway no cache, atualizar
 	if currentGateway == nil || currentGateway.Name != newGateway.Name {
-		if err := gi.redisCache.SetAvailableGateway(newGateway); err != nil {
+		if err := gi.statusStore.SetAvailableGateway(gi.ctx, newGateway); err != nil {
 			log.Printf("❌ Erro ao atualizar gateway no cache: %v", err)
 		} else {
 			log.Printf("🔄 Gateway atualizado no cache: %s (%s)", newGateway.Name, newGateway.URL)
+			gi.publishGatewayChange(newGateway)
 		}
 	}
+}
+
+// publishGatewayChange avisa todas as instâncias sobre a mudança de roteamento
+func (gi *GatewayInstance) publishGatewayChange(processor *cache.ProcessorInfo) {
+	gi.events.Publish(events.GATEWAY_CHANGED, events.ProcessorStatus{Processor: processor.Name, Available: processor.IsAvailable})
+
+	data, err := json.Marshal(processor)
+	if err != nil {
+		log.Printf("❌ Erro ao serializar mudança de gateway: %v", err)
+		return
+	}
+
+	if err := gi.broker.Publish(gi.ctx, cache.CHANNEL_GATEWAY_CHANGES, data); err != nil {
+		log.Printf("❌ Erro ao publicar mudança de gateway: %v", err)
+	}
 } 
\ No newline at end of file
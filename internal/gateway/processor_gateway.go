@@ -1,14 +1,23 @@
 package gateway
 
 import (
+	"context"
+	"encoding/json"
+	"errors"
 	"fmt"
+	"io"
 	"log"
 	"net/http"
+	"sync"
+	"sync/atomic"
 	"time"
 
 	"rinha-de-backend-2025/internal/cache"
 )
 
+// ErrPaused é retornado enquanto um override de pausa estiver ativo
+var ErrPaused = errors.New("entrada de pagamentos pausada pelo admin")
+
 // ProcessorInfo representa informações sobre um payment processor
 type ProcessorInfo struct {
 	URL       string `json:"url"`
@@ -16,62 +25,304 @@ type ProcessorInfo struct {
 	IsDefault bool   `json:"is_default"`
 }
 
-// ProcessorGateway gerencia a decisão de qual processor usar (Arquitetura 2 com Redis Cache)
+// ProcessorGateway gerencia a decisão de qual processor usar (Arquitetura 2 com status store)
 type ProcessorGateway struct {
-	defaultURL  string
-	fallbackURL string
-	httpClient  *http.Client
-	redisCache  *cache.RedisCache // Arquitetura 2: Usar Redis cache
+	defaultURL    string
+	fallbackURL   string
+	httpClient    *http.Client
+	healthTimeout time.Duration
+	statusStore   cache.ProcessorStatusStore // Arquitetura 2: Redis ou memória
+	broker        cache.Broker               // Mudanças de gateway e override entre instâncias
+
+	// Snapshot local do gateway disponível, atualizado via pub/sub e lido sem locks
+	snapshot   atomic.Pointer[routeSnapshot]
+	refreshing atomic.Bool
+
+	// Override manual de roteamento, atualizado junto com o snapshot e via pub/sub
+	override atomic.Pointer[cache.RoutingOverride]
+
+	// Health checks diretos por URL, usados quando o store não tem gateway disponível
+	directProbes map[string]*directProbe
+}
+
+// DIRECT_CHECK_TTL é o intervalo mínimo entre health checks diretos de um mesmo processor
+// (o service-health da Rinha aceita uma chamada a cada 5s)
+const DIRECT_CHECK_TTL = 5 * time.Second
+
+// routeSnapshot é a última decisão de roteamento conhecida pela instância.
+// processor nil significa que nenhum gateway está disponível no store.
+type routeSnapshot struct {
+	processor *ProcessorInfo
+	fetchedAt time.Time
 }
 
 // NewProcessorGateway cria uma nova instância do gateway (Arquitetura 2)
-func NewProcessorGateway(defaultURL, fallbackURL string, redisCache *cache.RedisCache) *ProcessorGateway {
+func NewProcessorGateway(
+	defaultURL, fallbackURL string,
+	statusStore cache.ProcessorStatusStore,
+	broker cache.Broker,
+	httpClient *http.Client,
+	healthTimeout time.Duration,
+) *ProcessorGateway {
 	return &ProcessorGateway{
-		defaultURL:  defaultURL,
-		fallbackURL: fallbackURL,
-		httpClient: &http.Client{
-			Timeout: 5 * time.Second,
+		defaultURL:    defaultURL,
+		fallbackURL:   fallbackURL,
+		httpClient:    httpClient,
+		healthTimeout: healthTimeout,
+		statusStore:   statusStore,
+		broker:        broker,
+		directProbes: map[string]*directProbe{
+			defaultURL:  {},
+			fallbackURL: {},
 		},
-		redisCache: redisCache,
 	}
 }
 
-// DecideProcessor escolhe qual processor usar baseado no cache Redis (Arquitetura 2)
-func (pg *ProcessorGateway) DecideProcessor() (*ProcessorInfo, error) {
-	log.Printf("🚀 Arquitetura 2: Iniciando decisão de processor via Redis Cache...")
-	
-	// 1. Primeiro, tentar obter do cache Redis
-	cachedGateway, err := pg.redisCache.GetAvailableGateway()
+// Watch se inscreve nas mudanças de roteamento publicadas pelo Gateway Instance e
+// nas mudanças de override feitas pelo admin. Se a inscrição falhar, DecideProcessor
+// continua consultando o store a cada CACHE_TTL.
+func (pg *ProcessorGateway) Watch(ctx context.Context) error {
+	changes, err := pg.broker.Subscribe(ctx, cache.CHANNEL_GATEWAY_CHANGES)
 	if err != nil {
-		log.Printf("⚠️ Erro ao consultar cache Redis: %v", err)
-		// Fallback para verificação direta se cache falhar
-		return pg.decideFallbackWithoutCache()
+		return err
 	}
-	
-	// 2. Se há gateway no cache, usar ele
+	overrides, err := pg.broker.Subscribe(ctx, cache.CHANNEL_OVERRIDE_CHANGES)
+	if err != nil {
+		return err
+	}
+
+	go func() {
+		for data := range changes {
+			var processor cache.ProcessorInfo
+			if err := json.Unmarshal(data, &processor); err != nil {
+				log.Printf("⚠️ Mudança de gateway inválida recebida: %v", err)
+				continue
+			}
+			pg.storeSnapshot(&processor)
+			log.Printf("📡 Mudança de gateway recebida: %s (disponível=%t)", processor.Name, processor.IsAvailable)
+		}
+		log.Printf("📡 Inscrição de mudanças de gateway encerrada")
+	}()
+
+	go func() {
+		for data := range overrides {
+			// Mensagem vazia (ou sem modo) significa override removido
+			var override cache.RoutingOverride
+			if len(data) > 0 {
+				if err := json.Unmarshal(data, &override); err != nil {
+					log.Printf("⚠️ Mudança de override inválida recebida: %v", err)
+					continue
+				}
+			}
+			pg.storeOverride(&override)
+			log.Printf("📡 Mudança de override recebida: %s", describeOverride(pg.Override()))
+		}
+		log.Printf("📡 Inscrição de mudanças de override encerrada")
+	}()
+
+	return nil
+}
+
+// DecideProcessor escolhe qual processor usar baseado no snapshot local,
+// consultando o status store apenas quando o snapshot expira (Arquitetura 2).
+// Um override manual ativo tem prioridade sobre o gateway do store.
+func (pg *ProcessorGateway) DecideProcessor(ctx context.Context) (*ProcessorInfo, error) {
+	// 1. Caminho rápido: snapshot local ainda válido
+	snapshot := pg.snapshot.Load()
+	if snapshot == nil || time.Since(snapshot.fetchedAt) >= cache.CACHE_TTL {
+		snapshot = pg.refreshSnapshot(ctx, snapshot)
+	}
+
+	// 2. Override manual: pausa recusa, force ignora o health check
+	excluded := ""
+	if override := pg.Override(); override != nil {
+		switch override.Mode {
+		case cache.OVERRIDE_PAUSE:
+			return nil, ErrPaused
+		case cache.OVERRIDE_FORCE:
+			return pg.processorByName(override.Processor), nil
+		case cache.OVERRIDE_EXCLUDE:
+			excluded = override.Processor
+		}
+	}
+
+	// 3. Se há gateway no snapshot (e ele não foi excluído), usar ele
+	if snapshot != nil && snapshot.processor != nil && snapshot.processor.Name != excluded {
+		processor := *snapshot.processor
+		return &processor, nil
+	}
+
+	// 4. Se não há gateway disponível, verificar diretamente (fallback, com resultado compartilhado)
+	return pg.decideFallbackWithoutCache(ctx, excluded)
+}
+
+// refreshSnapshot consulta o status store. Enquanto uma goroutine atualiza,
+// as demais seguem com o snapshot anterior (se houver) em vez de repetir a consulta.
+func (pg *ProcessorGateway) refreshSnapshot(ctx context.Context, stale *routeSnapshot) *routeSnapshot {
+	if stale != nil && !pg.refreshing.CompareAndSwap(false, true) {
+		return stale
+	}
+	if stale != nil {
+		defer pg.refreshing.Store(false)
+	}
+
+	if override, err := pg.statusStore.GetRoutingOverride(ctx); err == nil {
+		pg.storeOverride(override)
+	}
+
+	cachedGateway, err := pg.statusStore.GetAvailableGateway(ctx)
+	if err != nil {
+		log.Printf("⚠️ Erro ao consultar status store: %v", err)
+		return nil
+	}
+
+	return pg.storeSnapshot(cachedGateway)
+}
+
+// storeSnapshot converte o gateway do store e publica o novo snapshot
+func (pg *ProcessorGateway) storeSnapshot(cachedGateway *cache.ProcessorInfo) *routeSnapshot {
+	snapshot := &routeSnapshot{fetchedAt: time.Now()}
 	if cachedGateway != nil && cachedGateway.IsAvailable {
-		log.Printf("📋 Cache hit: usando processor %s (%s) do cache", 
-			cachedGateway.Name, cachedGateway.URL)
-		
-		return &ProcessorInfo{
+		snapshot.processor = &ProcessorInfo{
 			URL:       cachedGateway.URL,
 			Name:      cachedGateway.Name,
 			IsDefault: cachedGateway.IsDefault,
-		}, nil
+		}
 	}
-	
-	// 3. Se não há gateway no cache, verificar diretamente (fallback)
-	log.Printf("🔍 Cache miss: nenhum gateway disponível no cache, verificando diretamente...")
-	return pg.decideFallbackWithoutCache()
+	pg.snapshot.Store(snapshot)
+	return snapshot
 }
 
-// decideFallbackWithoutCache é usado quando o cache não está disponível (fallback da Arquitetura 2)
-func (pg *ProcessorGateway) decideFallbackWithoutCache() (*ProcessorInfo, error) {
-	log.Printf("⚠️ Fallback: verificando processors diretamente sem cache...")
-	
+// storeOverride publica o override localmente; nil ou sem modo remove o override
+func (pg *ProcessorGateway) storeOverride(override *cache.RoutingOverride) {
+	if override == nil || override.Mode == "" {
+		pg.override.Store(nil)
+		return
+	}
+	pg.override.Store(override)
+}
+
+// Override retorna o override manual ativo, ou nil se não houver (ou se já expirou)
+func (pg *ProcessorGateway) Override() *cache.RoutingOverride {
+	override := pg.override.Load()
+	if !override.Active(time.Now()) {
+		return nil
+	}
+	return override
+}
+
+// Paused indica se a entrada de pagamentos está pausada pelo admin
+func (pg *ProcessorGateway) Paused() bool {
+	override := pg.Override()
+	return override != nil && override.Mode == cache.OVERRIDE_PAUSE
+}
+
+// Current retorna o gateway escolhido pelo Gateway Instance segundo o snapshot local
+// (atualizado se expirado), sem considerar o override. nil: nenhum disponível.
+func (pg *ProcessorGateway) Current(ctx context.Context) *ProcessorInfo {
+	snapshot := pg.snapshot.Load()
+	if snapshot == nil || time.Since(snapshot.fetchedAt) >= cache.CACHE_TTL {
+		snapshot = pg.refreshSnapshot(ctx, snapshot)
+	}
+	if snapshot == nil || snapshot.processor == nil {
+		return nil
+	}
+	processor := *snapshot.processor
+	return &processor
+}
+
+// Allows indica se o override permite enviar pagamentos ao processor (usado pelo
+// hedge e pelo desvio do limitador, que escolhem o processor fora do DecideProcessor)
+func (pg *ProcessorGateway) Allows(name string) bool {
+	override := pg.Override()
+	if override == nil {
+		return true
+	}
+	switch override.Mode {
+	case cache.OVERRIDE_FORCE:
+		return name == override.Processor
+	case cache.OVERRIDE_EXCLUDE:
+		return name != override.Processor
+	}
+	return true
+}
+
+// SetOverride grava o override no status store e avisa as demais instâncias
+func (pg *ProcessorGateway) SetOverride(ctx context.Context, override *cache.RoutingOverride) error {
+	if err := override.Validate(); err != nil {
+		return err
+	}
+	if err := pg.statusStore.SetRoutingOverride(ctx, override); err != nil {
+		return err
+	}
+	pg.storeOverride(override)
+
+	data, err := json.Marshal(override)
+	if err != nil {
+		return fmt.Errorf("erro ao serializar override: %v", err)
+	}
+	if err := pg.broker.Publish(ctx, cache.CHANNEL_OVERRIDE_CHANGES, data); err != nil {
+		log.Printf("⚠️ Erro ao publicar override (instâncias verão no próximo refresh): %v", err)
+	}
+
+	log.Printf("🎛️ Override aplicado: %s", describeOverride(override))
+	return nil
+}
+
+// ClearOverride remove o override e avisa as demais instâncias
+func (pg *ProcessorGateway) ClearOverride(ctx context.Context) error {
+	if err := pg.statusStore.ClearRoutingOverride(ctx); err != nil {
+		return err
+	}
+	pg.storeOverride(nil)
+
+	if err := pg.broker.Publish(ctx, cache.CHANNEL_OVERRIDE_CHANGES, nil); err != nil {
+		log.Printf("⚠️ Erro ao publicar remoção do override (instâncias verão no próximo refresh): %v", err)
+	}
+
+	log.Printf("🎛️ Override removido")
+	return nil
+}
+
+// describeOverride formata o override para os logs
+func describeOverride(override *cache.RoutingOverride) string {
+	if override == nil {
+		return "nenhum"
+	}
+	description := override.Mode
+	if override.Processor != "" {
+		description += " " + override.Processor
+	}
+	return fmt.Sprintf("%s até %s", description, override.ExpiresAt.Format(time.RFC3339))
+}
+
+// processorByName retorna o processor configurado com o nome informado
+func (pg *ProcessorGateway) processorByName(name string) *ProcessorInfo {
+	if name == "fallback" {
+		return &ProcessorInfo{URL: pg.fallbackURL, Name: "fallback", IsDefault: false}
+	}
+	return &ProcessorInfo{URL: pg.defaultURL, Name: "default", IsDefault: true}
+}
+
+// Processors retorna os processors configurados, default primeiro
+func (pg *ProcessorGateway) Processors() []*ProcessorInfo {
+	return []*ProcessorInfo{pg.processorByName("default"), pg.processorByName("fallback")}
+}
+
+// OtherProcessor retorna o processor alternativo ao informado (default ↔ fallback)
+func (pg *ProcessorGateway) OtherProcessor(name string) *ProcessorInfo {
+	if name == "default" {
+		return pg.processorByName("fallback")
+	}
+	return pg.processorByName("default")
+}
+
+// decideFallbackWithoutCache é usado quando o cache não está disponível (fallback da Arquitetura 2).
+// O processor excluído por override (se houver) não é considerado. Os health checks
+// diretos são compartilhados entre os pagamentos da instância (ver directHealth).
+func (pg *ProcessorGateway) decideFallbackWithoutCache(ctx context.Context, excluded string) (*ProcessorInfo, error) {
 	// Verificar Default Processor primeiro
-	if pg.isProcessorUp(pg.defaultURL) {
-		log.Printf("✅ Default Processor está UP (verificação direta): %s", pg.defaultURL)
+	if excluded != "default" && pg.directHealth(ctx, pg.defaultURL) {
 		return &ProcessorInfo{
 			URL:       pg.defaultURL,
 			Name:      "default",
@@ -79,11 +330,8 @@ func (pg *ProcessorGateway) decideFallbackWithoutCache() (*ProcessorInfo, error)
 		}, nil
 	}
 	
-	log.Printf("❌ Default Processor está DOWN, verificando Fallback...")
-	
 	// Se Default falhou, verificar Fallback
-	if pg.isProcessorUp(pg.fallbackURL) {
-		log.Printf("✅ Fallback Processor está UP (verificação direta): %s", pg.fallbackURL)
+	if excluded != "fallback" && pg.directHealth(ctx, pg.fallbackURL) {
 		return &ProcessorInfo{
 			URL:       pg.fallbackURL,
 			Name:      "fallback",
@@ -92,20 +340,60 @@ func (pg *ProcessorGateway) decideFallbackWithoutCache() (*ProcessorInfo, error)
 	}
 	
 	// Se ambos falharam
-	log.Printf("❌ ERRO: Todos os processors estão DOWN (verificação direta)!")
 	return nil, fmt.Errorf("nenhum payment processor está disponível")
 }
 
-// isProcessorUp verifica se um processor está funcionando
-func (pg *ProcessorGateway) isProcessorUp(url string) bool {
+// directProbe é o último health check direto de um processor, feito sem o status store
+type directProbe struct {
+	mu        sync.Mutex
+	up        bool
+	checkedAt time.Time
+}
+
+// directHealth retorna o health check direto do processor, repetindo-o no máximo a cada
+// DIRECT_CHECK_TTL. Só um pagamento por vez faz a verificação; os demais esperam por ela
+// e usam o resultado, para não estourar o rate limit do service-health (429).
+func (pg *ProcessorGateway) directHealth(ctx context.Context, url string) bool {
+	probe := pg.directProbes[url]
+	probe.mu.Lock()
+	defer probe.mu.Unlock()
+
+	if !probe.checkedAt.IsZero() && time.Since(probe.checkedAt) < DIRECT_CHECK_TTL {
+		return probe.up
+	}
+
+	log.Printf("⚠️ Fallback: verificando %s diretamente sem cache...", url)
+	up, limited := pg.isProcessorUp(ctx, url)
+	if limited {
+		// 429: o processor respondeu, mas não informou o health; mantém o último resultado
+		// (sem um anterior, conta como disponível: a própria chamada de pagamento dirá)
+		up = probe.checkedAt.IsZero() || probe.up
+	}
+	probe.up = up
+	probe.checkedAt = time.Now()
+	return up
+}
+
+// isProcessorUp verifica se um processor está funcionando. limited indica que o
+// service-health respondeu 429 (rate limit), sem dizer nada sobre o processor.
+func (pg *ProcessorGateway) isProcessorUp(ctx context.Context, url string) (up, limited bool) {
+	ctx, cancel := context.WithTimeout(ctx, pg.healthTimeout)
+	defer cancel()
+
 	healthURL := fmt.Sprintf("%s/payments/service-health", url)
-	
-	resp, err := pg.httpClient.Get(healthURL)
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, healthURL, nil)
+	if err != nil {
+		log.Printf("❌ Erro ao criar health check de %s: %v", url, err)
+		return false, false
+	}
+
+	resp, err := pg.httpClient.Do(req)
 	if err != nil {
 		log.Printf("❌ Erro ao verificar health de %s: %v", url, err)
-		return false
+		return false, false
 	}
 	defer resp.Body.Close()
+	io.Copy(io.Discard, resp.Body)
 	
 	isUp := resp.StatusCode == http.StatusOK
 	if isUp {
@@ -114,23 +402,23 @@ func (pg *ProcessorGateway) isProcessorUp(url string) bool {
 		log.Printf("❌ Health check falhou para %s: status %d", url, resp.StatusCode)
 	}
 	
-	return isUp
+	return isUp, resp.StatusCode == http.StatusTooManyRequests
 }
 
-// GetProcessorStatus retorna o status atual dos processors do cache Redis (Arquitetura 2)
-func (pg *ProcessorGateway) GetProcessorStatus() map[string]bool {
-	log.Printf("📊 Arquitetura 2: Consultando status dos processors no Redis Cache...")
+// GetProcessorStatus retorna o status atual dos processors do status store (Arquitetura 2)
+func (pg *ProcessorGateway) GetProcessorStatus(ctx context.Context) map[string]bool {
+	log.Printf("📊 Arquitetura 2: Consultando status dos processors no status store...")
 	
-	if pg.redisCache == nil {
-		log.Printf("⚠️ Redis cache não está disponível, retornando status padrão")
+	if pg.statusStore == nil {
+		log.Printf("⚠️ Status store não está disponível, retornando status padrão")
 		return map[string]bool{
 			"default":  false,
 			"fallback": false,
 		}
 	}
 	
-	// Obter status do cache Redis
-	status := pg.redisCache.GetAllProcessorStatus()
+	// Obter status do status store
+	status := pg.statusStore.GetAllProcessorStatus(ctx)
 	
 	log.Printf("📋 Status dos processors do cache: Default=%t, Fallback=%t", 
 		status["default"], status["fallback"])
@@ -0,0 +1,147 @@
+package handler
+
+import (
+	"crypto/subtle"
+	"encoding/json"
+	"fmt"
+	"log"
+	"net/http"
+	"time"
+
+	"rinha-de-backend-2025/internal/cache"
+)
+
+// adminTokenHeader carrega o token exigido pelos endpoints /admin/*
+const adminTokenHeader = "X-Admin-Token"
+
+// maxOverrideTTL limita por quanto tempo um override manual pode ficar ativo
+const maxOverrideTTL = 24 * time.Hour
+
+// authorizeAdmin valida o método e o token dos endpoints administrativos. Sem
+// ADMIN_TOKEN eles ficam fechados: purge, pausa e webhooks não podem ficar abertos.
+func (h *Handler) authorizeAdmin(w http.ResponseWriter, r *http.Request, methods ...string) bool {
+	allowed := false
+	for _, method := range methods {
+		allowed = allowed || r.Method == method
+	}
+	if !allowed {
+		http.Error(w, "Método não permitido", http.StatusMethodNotAllowed)
+		return false
+	}
+	if h.adminToken == "" {
+		http.Error(w, "Endpoints administrativos desabilitados: configure ADMIN_TOKEN", http.StatusForbidden)
+		return false
+	}
+	if subtle.ConstantTimeCompare([]byte(r.Header.Get(adminTokenHeader)), []byte(h.adminToken)) != 1 {
+		http.Error(w, "Token de admin inválido", http.StatusUnauthorized)
+		return false
+	}
+	return true
+}
+
+// TriggerHealthCheck executa um health check dos processors na hora (POST /admin/health-check)
+func (h *Handler) TriggerHealthCheck(w http.ResponseWriter, r *http.Request) {
+	if !h.authorizeAdmin(w, r, http.MethodPost) {
+		return
+	}
+
+	probes := h.gatewayInstance.CheckNow()
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]interface{}{
+		"processor_probes": probes,
+		"processors":       h.gateway.GetProcessorStatus(r.Context()),
+		"timestamp":        time.Now(),
+	})
+}
+
+// PurgePayments apaga todos os pagamentos do repositório (POST /admin/purge). Com PEERS,
+// o ledger em memória de cada peer também é apagado.
+func (h *Handler) PurgePayments(w http.ResponseWriter, r *http.Request) {
+	if !h.authorizeAdmin(w, r, http.MethodPost) {
+		return
+	}
+
+	purged, err := h.paymentUseCase.PurgePayments(r.Context())
+	if err != nil {
+		log.Printf("Erro ao apagar pagamentos: %v", err)
+		http.Error(w, "Erro ao apagar pagamentos: "+err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	err = h.fanOut(r.Context(), r, func(body []byte) error {
+		var resp struct {
+			Purged int64 `json:"purged"`
+		}
+		if err := json.Unmarshal(body, &resp); err != nil {
+			return err
+		}
+		purged += resp.Purged
+		return nil
+	})
+	if err != nil {
+		// O purge é idempotente: repetir apaga o que ficou nos peers
+		log.Printf("Erro ao apagar pagamentos dos peers: %v", err)
+		http.Error(w, fmt.Sprintf("Pagamentos desta instância apagados, mas o purge falhou nos peers: %v", err),
+			http.StatusBadGateway)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]interface{}{
+		"purged": purged,
+	})
+}
+
+// RoutingOverride consulta (GET), aplica (POST ?mode=force|exclude|pause&processor=&ttl=&reason=)
+// ou remove (DELETE) o override manual de roteamento em /admin/override
+func (h *Handler) RoutingOverride(w http.ResponseWriter, r *http.Request) {
+	if !h.authorizeAdmin(w, r, http.MethodGet, http.MethodPost, http.MethodDelete) {
+		return
+	}
+
+	switch r.Method {
+	case http.MethodPost:
+		query := r.URL.Query()
+		ttl := 5 * time.Minute
+		if value := query.Get("ttl"); value != "" {
+			parsed, err := time.ParseDuration(value)
+			if err != nil || parsed <= 0 || parsed > maxOverrideTTL {
+				http.Error(w, "Parâmetro 'ttl' inválido (duração entre 0 e 24h, ex.: 10m)", http.StatusBadRequest)
+				return
+			}
+			ttl = parsed
+		}
+
+		now := time.Now()
+		override := &cache.RoutingOverride{
+			Mode:      query.Get("mode"),
+			Processor: query.Get("processor"),
+			Reason:    query.Get("reason"),
+			CreatedAt: now,
+			ExpiresAt: now.Add(ttl),
+		}
+		if err := override.Validate(); err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		if err := h.gateway.SetOverride(r.Context(), override); err != nil {
+			log.Printf("Erro ao aplicar override: %v", err)
+			http.Error(w, "Erro ao aplicar override: "+err.Error(), http.StatusInternalServerError)
+			return
+		}
+		h.gatewayInstance.Reevaluate()
+	case http.MethodDelete:
+		if err := h.gateway.ClearOverride(r.Context()); err != nil {
+			log.Printf("Erro ao remover override: %v", err)
+			http.Error(w, "Erro ao remover override: "+err.Error(), http.StatusInternalServerError)
+			return
+		}
+		h.gatewayInstance.Reevaluate()
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]interface{}{
+		"override": h.gateway.Override(),
+	})
+}
@@ -0,0 +1,101 @@
+package handler
+
+import (
+	"encoding/json"
+	"log"
+	"net/http"
+	"time"
+)
+
+// DashboardOverview reúne o que o painel /dashboard atualiza a cada poucos segundos:
+// gateway escolhido e override, status e latência dos processors na janela de métricas,
+// vazão, profundidade das filas e as dead letters mais antigas (GET /dashboard/overview?limit=N).
+// Expõe dead letters e overrides, então exige o token de admin como /admin/*.
+func (h *Handler) DashboardOverview(w http.ResponseWriter, r *http.Request) {
+	if !h.authorizeAdmin(w, r, http.MethodGet) {
+		return
+	}
+
+	gatewayInfo := map[string]interface{}{
+		"processor": nil,
+		"available": false,
+		"override":  h.gateway.Override(),
+		"paused":    h.gateway.Paused(),
+	}
+	if current := h.gateway.Current(r.Context()); current != nil {
+		gatewayInfo["processor"] = current.Name
+		gatewayInfo["available"] = true
+	}
+
+	report := h.paymentUseCase.MetricsReport(r.Context())
+	probes := h.gatewayInstance.ProbeStatus()
+	processors := make(map[string]interface{}, 2)
+	for _, name := range []string{"default", "fallback"} {
+		processor := map[string]interface{}{}
+		if probe, ok := probes[name]; ok {
+			processor["healthy"] = probe.Healthy
+			processor["probe"] = probe
+		}
+		if report != nil {
+			stats := report.Cluster[name]
+			processor["payments"] = stats.Payments
+			processor["health_checks"] = stats.Probes
+			processor["payments_per_second"] = perSecond(stats.Payments.Total, report.WindowSeconds)
+		}
+		processors[name] = processor
+	}
+
+	// Vazão do cluster na janela de métricas (todas as instâncias que publicaram)
+	var throughput map[string]interface{}
+	if report != nil {
+		var total, successes int64
+		for _, stats := range report.Cluster {
+			total += stats.Payments.Total
+			successes += stats.Payments.Successes
+		}
+		throughput = map[string]interface{}{
+			"window_seconds":       report.WindowSeconds,
+			"instances":            report.Instances,
+			"payments":             total,
+			"payments_per_second":  perSecond(total, report.WindowSeconds),
+			"successes_per_second": perSecond(successes, report.WindowSeconds),
+			"cluster_error":        report.ClusterError,
+		}
+	}
+
+	queues := map[string]interface{}{
+		"in_flight":          h.paymentUseCase.InFlight(),
+		"concurrency_limits": h.paymentUseCase.LimiterStats(),
+	}
+	if h.webhooks != nil {
+		queues["webhooks_pending"] = h.webhooks.Pending()
+	}
+
+	response := map[string]interface{}{
+		"gateway":    gatewayInfo,
+		"processors": processors,
+		"throughput": throughput,
+		"queues":     queues,
+		"timestamp":  time.Now(),
+	}
+
+	limit := parseLimit(r, 10, 100)
+	deadLetters, err := h.deadLetters(r.Context(), r, limit)
+	if err != nil {
+		// O painel segue útil sem o banco; o erro aparece no lugar das falhas
+		log.Printf("⚠️ Erro ao buscar dead letters para o dashboard: %v", err)
+		response["dead_letters_error"] = err.Error()
+	} else {
+		response["dead_letters"] = deadLetters
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(response)
+}
+
+func perSecond(count int64, windowSeconds float64) float64 {
+	if windowSeconds <= 0 {
+		return 0
+	}
+	return float64(count) / windowSeconds
+}
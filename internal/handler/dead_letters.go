@@ -0,0 +1,153 @@
+package handler
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"net/http"
+	"strconv"
+	"time"
+
+	"rinha-de-backend-2025/internal/repository"
+	"rinha-de-backend-2025/internal/usecase"
+)
+
+// DeadLetters lista as tentativas com falha ainda não resolvidas (GET /admin/dead-letters?limit=N).
+// Com PEERS, junta as dead letters de todas as instâncias.
+func (h *Handler) DeadLetters(w http.ResponseWriter, r *http.Request) {
+	if !h.authorizeAdmin(w, r, http.MethodGet) {
+		return
+	}
+
+	limit := parseLimit(r, 50, 1000)
+	deadLetters, err := h.deadLetters(r.Context(), r, limit)
+	if err != nil {
+		log.Printf("Erro ao buscar dead letters: %v", err)
+		http.Error(w, fmt.Sprintf("Erro ao buscar dead letters: %v", err), http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]interface{}{
+		"dead_letters": deadLetters,
+		"total":        len(deadLetters),
+		"limit":        limit,
+	})
+}
+
+// RedriveDeadLetters reenvia uma dead letter (POST /admin/dead-letters/redrive?id=...) ou,
+// sem id, as limit mais antigas (POST /admin/dead-letters/redrive?limit=N). Com PEERS, a
+// dead letter é reenviada pela instância que a tem e o lote roda em cada uma (até limit por instância).
+func (h *Handler) RedriveDeadLetters(w http.ResponseWriter, r *http.Request) {
+	if !h.authorizeAdmin(w, r, http.MethodPost) {
+		return
+	}
+
+	if paymentID := r.URL.Query().Get("id"); paymentID != "" {
+		ctx, cancel := context.WithTimeout(r.Context(), h.requestBudget)
+		defer cancel()
+
+		result, err := h.paymentUseCase.RedriveDeadLetter(ctx, paymentID)
+		if err != nil {
+			if notPending(err) && h.forwardByID(ctx, w, r) {
+				return
+			}
+			http.Error(w, err.Error(), deadLetterErrorStatus(err))
+			return
+		}
+
+		w.Header().Set("Content-Type", "application/json")
+		if !result.Resolved {
+			w.WriteHeader(http.StatusBadGateway)
+		}
+		json.NewEncoder(w).Encode(result)
+		return
+	}
+
+	limit := parseLimit(r, 100, 1000)
+	// Cada dead letter tem o orçamento de um pagamento
+	ctx, cancel := context.WithTimeout(r.Context(), time.Duration(limit)*h.requestBudget)
+	defer cancel()
+
+	report, err := h.paymentUseCase.RedriveDeadLetters(ctx, limit)
+	if err != nil {
+		log.Printf("Erro no re-drive de dead letters: %v", err)
+		http.Error(w, fmt.Sprintf("Erro no re-drive de dead letters: %v", err), http.StatusInternalServerError)
+		return
+	}
+
+	err = h.fanOut(ctx, r, func(body []byte) error {
+		var peerReport usecase.RedriveReport
+		if err := json.Unmarshal(body, &peerReport); err != nil {
+			return err
+		}
+		report.Attempted += peerReport.Attempted
+		report.Resolved += peerReport.Resolved
+		report.Failed += peerReport.Failed
+		report.Results = append(report.Results, peerReport.Results...)
+		return nil
+	})
+	if err != nil {
+		// As dead letters desta instância já foram reenviadas; repetir o lote é seguro
+		log.Printf("Erro no re-drive de dead letters dos peers: %v", err)
+		http.Error(w, fmt.Sprintf("Re-drive desta instância concluído (%d resolvidas), mas falhou nos peers: %v",
+			report.Resolved, err), http.StatusBadGateway)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(report)
+}
+
+// ResolveDeadLetter descarta uma dead letter sem reprocessá-la (POST /admin/dead-letters/resolve?id=...)
+func (h *Handler) ResolveDeadLetter(w http.ResponseWriter, r *http.Request) {
+	if !h.authorizeAdmin(w, r, http.MethodPost) {
+		return
+	}
+
+	paymentID := r.URL.Query().Get("id")
+	if paymentID == "" {
+		http.Error(w, "Parâmetro 'id' é obrigatório", http.StatusBadRequest)
+		return
+	}
+
+	if err := h.paymentUseCase.ResolveDeadLetter(r.Context(), paymentID); err != nil {
+		if notPending(err) && h.forwardByID(r.Context(), w, r) {
+			return
+		}
+		http.Error(w, err.Error(), deadLetterErrorStatus(err))
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]interface{}{
+		"payment_id": paymentID,
+		"resolved":   true,
+		"resolution": repository.RESOLUTION_DISCARDED,
+	})
+}
+
+// deadLetterErrorStatus responde 404 quando a dead letter não existe (ou já foi resolvida)
+// e 500 para falhas do repositório
+func deadLetterErrorStatus(err error) int {
+	if notPending(err) {
+		return http.StatusNotFound
+	}
+	log.Printf("❌ Erro ao acessar dead letter: %v", err)
+	return http.StatusInternalServerError
+}
+
+// notPending indica que a dead letter não existe (ou já foi resolvida) neste ledger
+func notPending(err error) bool {
+	return errors.Is(err, repository.ErrNotFound) || errors.Is(err, repository.ErrNotPending)
+}
+
+// parseLimit lê o parâmetro limit, aplicando o padrão e o máximo
+func parseLimit(r *http.Request, defaultLimit, maxLimit int) int {
+	if l, err := strconv.Atoi(r.URL.Query().Get("limit")); err == nil && l > 0 && l <= maxLimit {
+		return l
+	}
+	return defaultLimit
+}
@@ -0,0 +1,120 @@
+package handler
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strings"
+	"time"
+
+	"rinha-de-backend-2025/internal/events"
+)
+
+// StreamConfig configura o stream GET /events
+type StreamConfig struct {
+	Bus       *events.Bus   // Eventos de todas as instâncias (com fan-out) ou só desta
+	Buffer    int           // Eventos pendentes por cliente antes do descarte
+	Heartbeat time.Duration // Intervalo do comentário que mantém a conexão viva
+}
+
+// sseRetry é o tempo (ms) que o navegador espera para reconectar
+const sseRetry = 3000
+
+// Events transmite os eventos por Server-Sent Events (GET /events?types=a,b&processor=default).
+// types filtra pelo tipo do evento e processor pelo processor do pagamento ou da transição.
+// Clientes lentos perdem eventos; o stream avisa com um evento "dropped" e o total perdido.
+func (h *Handler) Events(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		http.Error(w, "Método não permitido", http.StatusMethodNotAllowed)
+		return
+	}
+
+	query := r.URL.Query()
+	var types []string
+	for _, eventType := range strings.Split(query.Get("types"), ",") {
+		if eventType = strings.TrimSpace(eventType); eventType == "" {
+			continue
+		}
+		if !events.ValidType(eventType) {
+			http.Error(w, fmt.Sprintf("Tipo de evento desconhecido: %q (use %s)", eventType, strings.Join(events.Types, ", ")), http.StatusBadRequest)
+			return
+		}
+		types = append(types, eventType)
+	}
+	processor := query.Get("processor")
+
+	flusher, ok := w.(http.Flusher)
+	if !ok {
+		http.Error(w, "Streaming não suportado pela conexão", http.StatusInternalServerError)
+		return
+	}
+
+	ctx, cancel := context.WithCancel(r.Context())
+	defer cancel()
+	sub := h.stream.Bus.Subscribe(ctx, h.stream.Buffer, types...)
+
+	w.Header().Set("Content-Type", "text/event-stream")
+	w.Header().Set("Cache-Control", "no-cache")
+	w.Header().Set("X-Accel-Buffering", "no")
+	w.WriteHeader(http.StatusOK)
+	fmt.Fprintf(w, "retry: %d\n\n", sseRetry)
+	flusher.Flush()
+
+	heartbeat := time.NewTicker(h.stream.Heartbeat)
+	defer heartbeat.Stop()
+
+	var reported int64
+	for {
+		var err error
+		select {
+		case <-ctx.Done():
+			return
+		case <-h.streamsDone:
+			return // Shutdown: encerra o stream para o servidor não esperar o cliente
+		case event, ok := <-sub.C:
+			if !ok {
+				return
+			}
+			if processor != "" && eventProcessor(event) != processor {
+				continue
+			}
+			_, err = fmt.Fprintf(w, "id: %s\nevent: %s\ndata: %s\n\n", event.ID, event.Type, mustJSON(event))
+		case <-heartbeat.C:
+			_, err = fmt.Fprint(w, ": ping\n\n")
+		}
+
+		if dropped := sub.Dropped(); err == nil && dropped > reported {
+			_, err = fmt.Fprintf(w, "event: dropped\ndata: {\"dropped\":%d}\n\n", dropped)
+			reported = dropped
+		}
+		if err != nil {
+			return // Cliente desconectou
+		}
+		flusher.Flush()
+	}
+}
+
+// CloseStreams encerra os streams abertos (registrado no shutdown do servidor)
+func (h *Handler) CloseStreams() {
+	h.closeStreams.Do(func() { close(h.streamsDone) })
+}
+
+// eventProcessor retorna o processor de um evento: o do pagamento ou o da transição
+func eventProcessor(event events.Event) string {
+	var data struct {
+		PaymentProcessor string `json:"payment_processor"`
+		Processor        string `json:"processor"`
+	}
+	json.Unmarshal(event.Data, &data)
+	if data.PaymentProcessor != "" {
+		return data.PaymentProcessor
+	}
+	return data.Processor
+}
+
+// mustJSON serializa um valor que sempre é serializável (o evento já veio em JSON)
+func mustJSON(v interface{}) []byte {
+	data, _ := json.Marshal(v)
+	return data
+}
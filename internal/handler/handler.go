@@ -1,137 +1,209 @@
 package handler
 
 import (
+	"context"
 	"encoding/json"
 	"fmt"
 	"log"
 	"net/http"
+	"sort"
 	"strconv"
+	"sync"
 	"time"
 
+	"rinha-de-backend-2025/internal/cache"
 	"rinha-de-backend-2025/internal/gateway"
+	"rinha-de-backend-2025/internal/health"
+	"rinha-de-backend-2025/internal/httpclient"
 	"rinha-de-backend-2025/internal/payment"
+	"rinha-de-backend-2025/internal/peer"
+	"rinha-de-backend-2025/internal/repository"
 	"rinha-de-backend-2025/internal/usecase"
+	"rinha-de-backend-2025/internal/webhook"
 )
 
 type Handler struct {
-	paymentUseCase *usecase.PaymentUseCase
-	gateway        *gateway.ProcessorGateway
-	defaultURL     string
-	fallbackURL    string
+	paymentUseCase  *usecase.PaymentUseCase
+	gateway         *gateway.ProcessorGateway
+	gatewayInstance *gateway.GatewayInstance
+	healthChecker   *health.Checker
+	requestBudget   time.Duration
+	adminToken      string
+	webhooks        *webhook.Dispatcher // nil com WEBHOOKS_ENABLED=false
+	peers           *peer.Forwarder     // nil fora de STORAGE=memory com PEERS
+	stream          StreamConfig
+	streamsDone     chan struct{}
+	closeStreams    sync.Once
 }
 
-func New(defaultURL, fallbackURL string, paymentUseCase *usecase.PaymentUseCase) *Handler {
+func New(
+	paymentUseCase *usecase.PaymentUseCase,
+	processorGateway *gateway.ProcessorGateway,
+	gatewayInstance *gateway.GatewayInstance,
+	healthChecker *health.Checker,
+	requestBudget time.Duration,
+	adminToken string,
+	webhooks *webhook.Dispatcher,
+	stream StreamConfig,
+) *Handler {
 	return &Handler{
-		paymentUseCase: paymentUseCase,
-		gateway:        gateway.NewProcessorGateway(defaultURL, fallbackURL),
-		defaultURL:     defaultURL,
-		fallbackURL:    fallbackURL,
+		paymentUseCase:  paymentUseCase,
+		gateway:         processorGateway,
+		healthChecker:   healthChecker,
+		gatewayInstance: gatewayInstance,
+		requestBudget:   requestBudget,
+		adminToken:      adminToken,
+		webhooks:        webhooks,
+		stream:          stream,
+		streamsDone:     make(chan struct{}),
 	}
 }
 
 func (h *Handler) Health(w http.ResponseWriter, r *http.Request) {
 	startTime := time.Now()
-	processorStatus := h.gateway.GetProcessorStatus()
-	
-	status := map[string]interface{}{
-		"status": "ok",
+	processorStatus := h.gateway.GetProcessorStatus(r.Context())
+	report := h.healthChecker.Readiness(r.Context())
+
+	components := map[string]interface{}{
+		"processor_gateway": "online",
+		"payment_usecase":   "online",
+	}
+	for name, component := range report.Components {
+		components[name] = component.Status
+	}
+
+	status := "ok"
+	if !report.Ready {
+		status = "degraded"
+	}
+
+	response := map[string]interface{}{
+		"status": status,
 		"architecture": "Arquitetura 2 - Rinha Backend 2025 (Redis Cache + Gateway Instance)",
-		"components": map[string]interface{}{
-			"processor_gateway": "online",
-			"payment_usecase":   "online",
-			"database":          "online",
-			"redis_cache":       "online", // Arquitetura 2
-			"gateway_instance":  "online", // Arquitetura 2
-		},
+		"components": components,
 		"processors": processorStatus,
+		"processor_probes": report.Processors,
+		"routing_override": h.gateway.Override(),
 		"endpoints": []string{
 			"POST /payments - Processar pagamento",
 			"GET /payments/history - Histórico de pagamentos",
 			"GET /payments/stats - Estatísticas dos processors",
 			"GET /payments-summary - Resumo de pagamentos por período",
+			"GET /admin/dead-letters - Pagamentos com falha não resolvidos",
+			"POST /admin/dead-letters/redrive - Reprocessar dead letters (?id= ou em lote)",
+			"POST /admin/dead-letters/resolve - Descartar uma dead letter (?id=)",
+			"POST /admin/health-check - Executar health check dos processors agora",
+			"POST /admin/purge - Apagar todos os pagamentos (desta instância e dos PEERS)",
+			"GET|POST|DELETE /admin/override - Override manual de roteamento (force, exclude, pause)",
+			"GET|POST|DELETE /admin/webhooks - Inscrições de webhook dos eventos de pagamento e processor",
+			"GET /admin/webhooks/deliveries - Log de entregas de webhook",
+			"GET /events - Stream (SSE) de pagamentos, transições dos processors e trocas de gateway",
+			"GET /dashboard - Painel de operações (com DASHBOARD_ENABLED=true)",
+			"GET /dashboard/overview - Gateway, processors, vazão, filas e dead letters do painel (token de admin)",
 			"GET /health - Status dos serviços",
+			"GET /livez - Liveness da instância",
+			"GET /readyz - Readiness da instância",
 		},
 		"cache_info": map[string]interface{}{
 			"enabled": true,
-			"ttl_seconds": 30,
+			"ttl_seconds": int(cache.CACHE_TTL.Seconds()),
 			"auto_invalidation": true,
 		},
 		"gateway_instance": map[string]interface{}{
 			"enabled": true,
-			"health_check_interval": "5s",
-			"running": true,
+			"health_check_interval": h.gatewayInstance.Interval().String(),
+			"running": h.gatewayInstance.IsRunning(),
 		},
 		"response_time_ms": time.Since(startTime).Milliseconds(),
 	}
 
 	w.Header().Set("Content-Type", "application/json")
-	json.NewEncoder(w).Encode(status)
+	json.NewEncoder(w).Encode(response)
+}
+
+// Livez indica apenas se o processo está vivo e atendendo requisições
+func (h *Handler) Livez(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]interface{}{
+		"status": "alive",
+		"uptime": h.healthChecker.Uptime().Round(time.Second).String(),
+	})
+}
+
+// Readyz verifica Postgres, Redis e Gateway Instance e retorna 503 se a instância não deve receber tráfego
+func (h *Handler) Readyz(w http.ResponseWriter, r *http.Request) {
+	report := h.healthChecker.Readiness(r.Context())
+
+	w.Header().Set("Content-Type", "application/json")
+	if !report.Ready {
+		log.Printf("⚠️ Readiness falhou: %+v", report.Components)
+		w.WriteHeader(http.StatusServiceUnavailable)
+	}
+	json.NewEncoder(w).Encode(report)
 }
 
+// ProcessPayment é o caminho quente da aplicação: o corpo é lido em um buffer do pool,
+// decodificado pelo codec manual e a resposta é escrita sem passar pelo encoding/json
 func (h *Handler) ProcessPayment(w http.ResponseWriter, r *http.Request) {
-	startTime := time.Now()
-	
 	if r.Method != http.MethodPost {
 		http.Error(w, "Método não permitido", http.StatusMethodNotAllowed)
 		return
 	}
 
+	buf := httpclient.GetBuffer()
+	defer httpclient.PutBuffer(buf)
+
+	if _, err := buf.ReadFrom(http.MaxBytesReader(w, r.Body, maxPaymentBody)); err != nil {
+		http.Error(w, "JSON inválido", http.StatusBadRequest)
+		return
+	}
+
 	var req payment.PaymentRequest
-	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
-		log.Printf("ERRO: JSON inválido: %v", err)
+	if err := payment.DecodeRequest(buf.Bytes(), &req); err != nil {
 		http.Error(w, "JSON inválido", http.StatusBadRequest)
 		return
 	}
 
 	// Validações do payload da Rinha de Backend 2025
 	if req.CorrelationID == "" {
-		log.Printf("ERRO: correlationId ausente")
 		http.Error(w, "correlationId é obrigatório", http.StatusBadRequest)
 		return
 	}
 	if req.Amount <= 0 {
-		log.Printf("ERRO: amount inválido: %.2f", req.Amount)
 		http.Error(w, "amount deve ser maior que zero", http.StatusBadRequest)
 		return
 	}
 
-	log.Printf("Processando pagamento: correlationId=%s, amount=%.2f", req.CorrelationID, req.Amount)
+	// O deadline do pagamento é derivado do orçamento do request; se o cliente
+	// desconectar, o contexto do request cancela as chamadas em andamento
+	ctx, cancel := context.WithTimeout(r.Context(), h.requestBudget)
+	defer cancel()
 
 	// Processar pagamento usando o Use Case da Arquitetura 1
-	result := h.paymentUseCase.ProcessPayment(req)
-	
-	processingTime := time.Since(startTime)
-	
-	// Log do resultado
-	if result.Success {
-		log.Printf("✅ Pagamento processado com sucesso: correlationId=%s, processor=%s, time=%v", 
-			req.CorrelationID, result.ProcessorUsed, processingTime)
-	} else {
-		log.Printf("❌ Falha no pagamento: correlationId=%s, processor=%s, erro=%s, time=%v", 
-			req.CorrelationID, result.ProcessorUsed, result.Error, processingTime)
-	}
+	result := h.paymentUseCase.ProcessPayment(ctx, req)
 
-	// Retornar resultado
-	if result.Success {
-		w.Header().Set("Content-Type", "application/json")
-		w.WriteHeader(http.StatusOK)
-		json.NewEncoder(w).Encode(map[string]interface{}{
-			"success":         true,
-			"payment":         result.Payment,
-			"processor_used":  result.ProcessorUsed,
-			"processing_time": result.ProcessingTime.String(),
-			"saved_to_db":     result.SavedToDB,
-		})
-	} else {
-		w.Header().Set("Content-Type", "application/json")
-		w.WriteHeader(http.StatusInternalServerError)
-		json.NewEncoder(w).Encode(map[string]interface{}{
-			"success":         false,
-			"error":           result.Error,
-			"processor_used":  result.ProcessorUsed,
-			"processing_time": result.ProcessingTime.String(),
-		})
+	status := http.StatusOK
+	if result.Paused {
+		// Pausa do admin: não é falha do pagamento, o cliente deve reenviar depois
+		status = http.StatusServiceUnavailable
+		if override := h.gateway.Override(); override != nil {
+			w.Header().Set("Retry-After", strconv.Itoa(int(time.Until(override.ExpiresAt).Seconds())+1))
+		}
+	} else if !result.Success {
+		log.Printf("❌ Falha no pagamento: correlationId=%s, processor=%s, erro=%s, time=%v",
+			req.CorrelationID, result.ProcessorUsed, result.Error, result.ProcessingTime)
+		status = http.StatusInternalServerError
 	}
+
+	// O buffer do request já foi consumido; reaproveita para a resposta
+	buf.Reset()
+	buf.Write(appendPaymentResult(buf.AvailableBuffer(), result))
+
+	header := w.Header()
+	header["Content-Type"] = jsonContentType
+	header["Content-Length"] = []string{strconv.Itoa(buf.Len())}
+	w.WriteHeader(status)
+	w.Write(buf.Bytes())
 }
 
 func (h *Handler) PaymentHistory(w http.ResponseWriter, r *http.Request) {
@@ -149,13 +221,36 @@ func (h *Handler) PaymentHistory(w http.ResponseWriter, r *http.Request) {
 		}
 	}
 
-	payments, err := h.paymentUseCase.GetPaymentHistory(limit)
+	payments, err := h.paymentUseCase.GetPaymentHistory(r.Context(), limit)
 	if err != nil {
 		fmt.Printf("Erro ao buscar histórico: %v\n", err)
 		http.Error(w, "Erro ao buscar histórico", http.StatusInternalServerError)
 		return
 	}
 
+	// Com ledgers em memória, o histórico junta os mais recentes de cada instância
+	err = h.fanOut(r.Context(), r, func(body []byte) error {
+		var resp struct {
+			Payments []*repository.Payment `json:"payments"`
+		}
+		if err := json.Unmarshal(body, &resp); err != nil {
+			return err
+		}
+		payments = append(payments, resp.Payments...)
+		return nil
+	})
+	if err != nil {
+		log.Printf("Erro ao buscar histórico dos peers: %v", err)
+		http.Error(w, fmt.Sprintf("Erro ao buscar histórico dos peers: %v", err), http.StatusBadGateway)
+		return
+	}
+	sort.SliceStable(payments, func(i, j int) bool {
+		return payments[i].CreatedAt.After(payments[j].CreatedAt)
+	})
+	if len(payments) > limit {
+		payments = payments[:limit]
+	}
+
 	w.Header().Set("Content-Type", "application/json")
 	json.NewEncoder(w).Encode(map[string]interface{}{
 		"payments": payments,
@@ -170,12 +265,73 @@ func (h *Handler) ProcessorStats(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	stats := h.paymentUseCase.GetProcessorStats()
+	from, to, ok := optionalWindow(w, r)
+	if !ok {
+		return
+	}
+
+	stats, err := h.paymentUseCase.GetProcessorStats(r.Context(), from, to)
+	if err != nil {
+		log.Printf("❌ Erro ao buscar estatísticas dos processors: %v", err)
+		http.Error(w, fmt.Sprintf("Erro ao gerar estatísticas: %v", err), http.StatusInternalServerError)
+		return
+	}
 
 	w.Header().Set("Content-Type", "application/json")
 	json.NewEncoder(w).Encode(stats)
 }
 
+// PaymentFees retorna bruto, taxas e líquido por processor e o custo do failover
+// frente ao cenário em que todos os pagamentos fossem para o default
+func (h *Handler) PaymentFees(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		http.Error(w, "Método não permitido", http.StatusMethodNotAllowed)
+		return
+	}
+
+	from, to, ok := optionalWindow(w, r)
+	if !ok {
+		return
+	}
+
+	report, err := h.paymentUseCase.GetFeeReport(r.Context(), from, to)
+	if err != nil {
+		log.Printf("❌ Erro ao gerar relatório de taxas: %v", err)
+		http.Error(w, fmt.Sprintf("Erro ao gerar relatório de taxas: %v", err), http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(report)
+}
+
+// optionalWindow lê a janela opcional sobre requestedAt: sem 'from' considera todo o
+// histórico, sem 'to' vai até agora. Responde 400 e retorna ok=false se for inválida.
+func optionalWindow(w http.ResponseWriter, r *http.Request) (from, to time.Time, ok bool) {
+	from, to = time.Time{}, time.Now()
+	if fromStr := r.URL.Query().Get("from"); fromStr != "" {
+		parsed, err := time.Parse(time.RFC3339, fromStr)
+		if err != nil {
+			http.Error(w, "Formato inválido para 'from'. Use formato RFC3339: 2020-07-10T12:34:56.000Z", http.StatusBadRequest)
+			return from, to, false
+		}
+		from = parsed
+	}
+	if toStr := r.URL.Query().Get("to"); toStr != "" {
+		parsed, err := time.Parse(time.RFC3339, toStr)
+		if err != nil {
+			http.Error(w, "Formato inválido para 'to'. Use formato RFC3339: 2020-07-10T12:34:56.000Z", http.StatusBadRequest)
+			return from, to, false
+		}
+		to = parsed
+	}
+	if to.Before(from) {
+		http.Error(w, "'to' deve ser posterior a 'from'", http.StatusBadRequest)
+		return from, to, false
+	}
+	return from, to, true
+}
+
 func (h *Handler) PaymentsSummary(w http.ResponseWriter, r *http.Request) {
 	if r.Method != http.MethodGet {
 		http.Error(w, "Método não permitido", http.StatusMethodNotAllowed)
@@ -206,7 +362,7 @@ func (h *Handler) PaymentsSummary(w http.ResponseWriter, r *http.Request) {
 	}
 
 	// Buscar resumo usando o UseCase
-	summary, err := h.paymentUseCase.GetPaymentsSummary(from, to)
+	summary, err := h.paymentUseCase.GetPaymentsSummary(r.Context(), from, to)
 	if err != nil {
 		fmt.Printf("Erro ao buscar resumo: %v\n", err)
 		http.Error(w, fmt.Sprintf("Erro ao gerar resumo: %v", err), http.StatusInternalServerError)
@@ -0,0 +1,94 @@
+package handler
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"sort"
+
+	"rinha-de-backend-2025/internal/peer"
+	"rinha-de-backend-2025/internal/repository"
+)
+
+// SetPeers repassa aos peers o histórico, as dead letters e o purge (STORAGE=memory com PEERS)
+func (h *Handler) SetPeers(peers *peer.Forwarder) {
+	h.peers = peers
+}
+
+// fanOut repassa a requisição aos peers (exceto se ela já veio de um) e decodifica a
+// resposta de cada um em decode. Um peer que não responda 200 vira erro: o resultado
+// sem ele estaria incompleto.
+func (h *Handler) fanOut(ctx context.Context, r *http.Request, decode func(body []byte) error) error {
+	if h.peers == nil || peer.Forwarded(r) {
+		return nil
+	}
+
+	responses, err := h.peers.Forward(ctx, r)
+	if err != nil {
+		return err
+	}
+	for _, resp := range responses {
+		if resp.StatusCode != http.StatusOK {
+			return fmt.Errorf("peer %s respondeu %d: %s", resp.Peer, resp.StatusCode, resp.Body)
+		}
+		if err := decode(resp.Body); err != nil {
+			return fmt.Errorf("resposta inválida do peer %s: %v", resp.Peer, err)
+		}
+	}
+	return nil
+}
+
+// forwardByID repassa aos peers uma operação sobre um pagamento que não está no ledger
+// desta instância e devolve a resposta do peer que o tem. Retorna false se nenhum o tiver.
+func (h *Handler) forwardByID(ctx context.Context, w http.ResponseWriter, r *http.Request) bool {
+	if h.peers == nil || peer.Forwarded(r) {
+		return false
+	}
+
+	responses, err := h.peers.Forward(ctx, r)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadGateway)
+		return true
+	}
+	for _, resp := range responses {
+		if resp.StatusCode == http.StatusNotFound {
+			continue
+		}
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(resp.StatusCode)
+		w.Write(resp.Body)
+		return true
+	}
+	return false
+}
+
+// deadLetters lista as dead letters desta instância e dos peers, da mais antiga para a mais nova
+func (h *Handler) deadLetters(ctx context.Context, r *http.Request, limit int) ([]*repository.Payment, error) {
+	deadLetters, err := h.paymentUseCase.GetDeadLetters(ctx, limit)
+	if err != nil {
+		return nil, err
+	}
+
+	err = h.fanOut(ctx, r, func(body []byte) error {
+		var resp struct {
+			DeadLetters []*repository.Payment `json:"dead_letters"`
+		}
+		if err := json.Unmarshal(body, &resp); err != nil {
+			return err
+		}
+		deadLetters = append(deadLetters, resp.DeadLetters...)
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	sort.SliceStable(deadLetters, func(i, j int) bool {
+		return deadLetters[i].CreatedAt.Before(deadLetters[j].CreatedAt)
+	})
+	if len(deadLetters) > limit {
+		deadLetters = deadLetters[:limit]
+	}
+	return deadLetters, nil
+}
@@ -0,0 +1,34 @@
+package handler
+
+import (
+	"strconv"
+
+	"rinha-de-backend-2025/internal/payment"
+	"rinha-de-backend-2025/internal/usecase"
+)
+
+// maxPaymentBody limita o corpo do POST /payments (o payload da Rinha tem ~70 bytes)
+const maxPaymentBody = 4 * 1024
+
+// jsonContentType é atribuído direto no mapa de headers para evitar a canonicalização
+var jsonContentType = []string{"application/json"}
+
+// appendPaymentResult escreve a resposta mínima do POST /payments:
+// {"success":true,"processor_used":"default","processing_time_ms":1.234,"saved_to_db":true}
+// Em caso de falha, "saved_to_db" dá lugar a "error".
+func appendPaymentResult(dst []byte, result *usecase.PaymentResult) []byte {
+	dst = append(dst, `{"success":`...)
+	dst = strconv.AppendBool(dst, result.Success)
+	dst = append(dst, `,"processor_used":`...)
+	dst = payment.AppendString(dst, result.ProcessorUsed)
+	dst = append(dst, `,"processing_time_ms":`...)
+	dst = strconv.AppendFloat(dst, float64(result.ProcessingTime.Microseconds())/1000, 'f', -1, 64)
+	if result.Success {
+		dst = append(dst, `,"saved_to_db":`...)
+		dst = strconv.AppendBool(dst, result.SavedToDB)
+	} else {
+		dst = append(dst, `,"error":`...)
+		dst = payment.AppendString(dst, result.Error)
+	}
+	return append(dst, "}\n"...)
+}
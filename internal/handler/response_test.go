@@ -0,0 +1,92 @@
+package handler
+
+import (
+	"bytes"
+	"encoding/json"
+	"reflect"
+	"testing"
+	"time"
+
+	"rinha-de-backend-2025/internal/usecase"
+)
+
+// paymentResultMap é a resposta como o handler a montava com encoding/json
+func paymentResultMap(result *usecase.PaymentResult) map[string]interface{} {
+	response := map[string]interface{}{
+		"success":            result.Success,
+		"processor_used":     result.ProcessorUsed,
+		"processing_time_ms": float64(result.ProcessingTime.Microseconds()) / 1000,
+	}
+	if result.Success {
+		response["saved_to_db"] = result.SavedToDB
+	} else {
+		response["error"] = result.Error
+	}
+	return response
+}
+
+func TestAppendPaymentResultMatchesEncodingJSON(t *testing.T) {
+	tests := []struct {
+		name   string
+		result usecase.PaymentResult
+	}{
+		{"sucesso", usecase.PaymentResult{Success: true, ProcessorUsed: "default", ProcessingTime: 1234567 * time.Nanosecond, SavedToDB: true}},
+		{"sucesso sem gravar", usecase.PaymentResult{Success: true, ProcessorUsed: "fallback", ProcessingTime: 3 * time.Second}},
+		{"tempo zero", usecase.PaymentResult{Success: true, ProcessorUsed: "default", SavedToDB: true}},
+		{"menos de um microssegundo", usecase.PaymentResult{Success: true, ProcessorUsed: "default", ProcessingTime: 999}},
+		{"falha", usecase.PaymentResult{ProcessorUsed: "none", ProcessingTime: 42 * time.Microsecond, Error: "Nenhum processor disponível: todos DOWN"}},
+		{"falha sem processor", usecase.PaymentResult{Error: "Entrada pausada pelo admin"}},
+		{"erro com escapes", usecase.PaymentResult{ProcessorUsed: "default", Error: "Erro no processamento: status 500: {\"message\":\"boom\"}\n\tat \\x"}},
+		{"erro com unicode e html", usecase.PaymentResult{ProcessorUsed: "default", Error: "falha <b>&</b> ☕ 😀  "}},
+		{"erro com controle", usecase.PaymentResult{ProcessorUsed: "default", Error: "nul \x00 bell \x07 esc \x1b"}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			encoded := appendPaymentResult(nil, &tt.result)
+			if !bytes.HasSuffix(encoded, []byte("}\n")) {
+				t.Fatalf("resposta sem a quebra de linha final do json.Encoder: %q", encoded)
+			}
+
+			var baseline bytes.Buffer
+			if err := json.NewEncoder(&baseline).Encode(paymentResultMap(&tt.result)); err != nil {
+				t.Fatal(err)
+			}
+
+			var got, want map[string]interface{}
+			if err := json.Unmarshal(encoded, &got); err != nil {
+				t.Fatalf("appendPaymentResult = %s, JSON inválido: %v", encoded, err)
+			}
+			json.Unmarshal(baseline.Bytes(), &want)
+			if !reflect.DeepEqual(got, want) {
+				t.Fatalf("appendPaymentResult = %s, encoding/json = %s", encoded, baseline.Bytes())
+			}
+		})
+	}
+}
+
+var benchResult = usecase.PaymentResult{
+	Success:        true,
+	ProcessorUsed:  "default",
+	ProcessingTime: 1234567 * time.Nanosecond,
+	SavedToDB:      true,
+}
+
+func BenchmarkAppendPaymentResult(b *testing.B) {
+	b.ReportAllocs()
+	buf := make([]byte, 0, 256)
+	for i := 0; i < b.N; i++ {
+		buf = appendPaymentResult(buf[:0], &benchResult)
+	}
+}
+
+func BenchmarkAppendPaymentResultEncodingJSON(b *testing.B) {
+	b.ReportAllocs()
+	var buf bytes.Buffer
+	for i := 0; i < b.N; i++ {
+		buf.Reset()
+		if err := json.NewEncoder(&buf).Encode(paymentResultMap(&benchResult)); err != nil {
+			b.Fatal(err)
+		}
+	}
+}
@@ -0,0 +1,104 @@
+package handler
+
+import (
+	"encoding/json"
+	"fmt"
+	"log"
+	"net/http"
+
+	"rinha-de-backend-2025/internal/webhook"
+)
+
+// maxWebhookBody limita o corpo do POST /admin/webhooks
+const maxWebhookBody = 16 * 1024
+
+// Webhooks lista (GET), registra (POST com {"url","events","description","secret"}) ou
+// remove (DELETE ?id=) inscrições de webhook em /admin/webhooks
+func (h *Handler) Webhooks(w http.ResponseWriter, r *http.Request) {
+	if !h.authorizeAdmin(w, r, http.MethodGet, http.MethodPost, http.MethodDelete) || !h.webhooksEnabled(w) {
+		return
+	}
+
+	switch r.Method {
+	case http.MethodPost:
+		var sub webhook.Subscription
+		if err := json.NewDecoder(http.MaxBytesReader(w, r.Body, maxWebhookBody)).Decode(&sub); err != nil {
+			http.Error(w, fmt.Sprintf("JSON inválido: %v", err), http.StatusBadRequest)
+			return
+		}
+
+		created, err := h.webhooks.Create(r.Context(), sub)
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(http.StatusCreated)
+		json.NewEncoder(w).Encode(created)
+
+	case http.MethodDelete:
+		id := r.URL.Query().Get("id")
+		if id == "" {
+			http.Error(w, "Parâmetro 'id' é obrigatório", http.StatusBadRequest)
+			return
+		}
+
+		removed, err := h.webhooks.Delete(r.Context(), id)
+		if err != nil {
+			log.Printf("Erro ao remover webhook: %v", err)
+			http.Error(w, fmt.Sprintf("Erro ao remover webhook: %v", err), http.StatusInternalServerError)
+			return
+		}
+		if !removed {
+			http.Error(w, fmt.Sprintf("webhook não encontrado: %s", id), http.StatusNotFound)
+			return
+		}
+		w.WriteHeader(http.StatusNoContent)
+
+	default:
+		subscriptions, err := h.webhooks.List(r.Context())
+		if err != nil {
+			log.Printf("Erro ao listar webhooks: %v", err)
+			http.Error(w, fmt.Sprintf("Erro ao listar webhooks: %v", err), http.StatusInternalServerError)
+			return
+		}
+
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(map[string]interface{}{
+			"webhooks": subscriptions,
+			"total":    len(subscriptions),
+		})
+	}
+}
+
+// WebhookDeliveries lista as últimas tentativas de entrega (GET /admin/webhooks/deliveries?limit=N&webhook_id=)
+func (h *Handler) WebhookDeliveries(w http.ResponseWriter, r *http.Request) {
+	if !h.authorizeAdmin(w, r, http.MethodGet) || !h.webhooksEnabled(w) {
+		return
+	}
+
+	limit := parseLimit(r, 50, 1000)
+	deliveries, err := h.webhooks.Deliveries(r.Context(), limit, r.URL.Query().Get("webhook_id"))
+	if err != nil {
+		log.Printf("Erro ao buscar entregas de webhook: %v", err)
+		http.Error(w, fmt.Sprintf("Erro ao buscar entregas de webhook: %v", err), http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]interface{}{
+		"deliveries": deliveries,
+		"total":      len(deliveries),
+		"limit":      limit,
+	})
+}
+
+// webhooksEnabled responde 404 quando a instância subiu com WEBHOOKS_ENABLED=false
+func (h *Handler) webhooksEnabled(w http.ResponseWriter) bool {
+	if h.webhooks == nil {
+		http.Error(w, "Webhooks desabilitados (WEBHOOKS_ENABLED=false)", http.StatusNotFound)
+		return false
+	}
+	return true
+}
@@ -0,0 +1,128 @@
+package health
+
+import (
+	"context"
+	"time"
+
+	"rinha-de-backend-2025/internal/gateway"
+)
+
+// Pinger é implementado pelas dependências que sabem verificar a própria conectividade
+type Pinger interface {
+	Ping(ctx context.Context) error
+}
+
+// ComponentStatus representa o estado de uma dependência da aplicação
+type ComponentStatus struct {
+	Status    string `json:"status"`
+	Error     string `json:"error,omitempty"`
+	LatencyMs int64  `json:"latency_ms"`
+}
+
+// ProcessorProbe representa o último health check de um payment processor
+type ProcessorProbe struct {
+	Healthy        bool       `json:"healthy"`
+	LastCheck      *time.Time `json:"last_check,omitempty"`
+	LastSuccess    *time.Time `json:"last_success,omitempty"`
+	LastSuccessAge string     `json:"last_success_age,omitempty"`
+}
+
+// Report é o resultado completo de uma verificação de readiness
+type Report struct {
+	Ready      bool                       `json:"ready"`
+	Components map[string]ComponentStatus `json:"components"`
+	Processors map[string]ProcessorProbe  `json:"processors"`
+	CheckedAt  time.Time                  `json:"checked_at"`
+}
+
+// pingTimeout limita cada verificação de readiness, para o probe não ficar pendurado
+const pingTimeout = 2 * time.Second
+
+// Checker verifica as dependências reais da instância (Postgres, Redis e Gateway Instance)
+type Checker struct {
+	database        Pinger
+	cache           Pinger
+	gatewayInstance *gateway.GatewayInstance
+	startedAt       time.Time
+}
+
+// NewChecker cria um novo verificador de saúde
+func NewChecker(database, cache Pinger, gatewayInstance *gateway.GatewayInstance) *Checker {
+	return &Checker{
+		database:        database,
+		cache:           cache,
+		gatewayInstance: gatewayInstance,
+		startedAt:       time.Now(),
+	}
+}
+
+// Uptime retorna há quanto tempo a instância está no ar
+func (c *Checker) Uptime() time.Duration {
+	return time.Since(c.startedAt)
+}
+
+// Readiness verifica se a instância pode receber tráfego.
+// Processors fora do ar não tornam a instância "not ready": eles são
+// compartilhados por todas as instâncias e o fail safe já trata esse caso.
+func (c *Checker) Readiness(ctx context.Context) *Report {
+	report := &Report{
+		Ready:      true,
+		Components: make(map[string]ComponentStatus),
+		Processors: make(map[string]ProcessorProbe),
+		CheckedAt:  time.Now(),
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, pingTimeout)
+	defer cancel()
+
+	report.Components["database"] = pingComponent(ctx, c.database)
+	report.Components["redis_cache"] = pingComponent(ctx, c.cache)
+
+	gatewayStatus := ComponentStatus{Status: "online"}
+	if !c.gatewayInstance.IsRunning() {
+		gatewayStatus = ComponentStatus{Status: "offline", Error: "gateway instance não está rodando"}
+	}
+	report.Components["gateway_instance"] = gatewayStatus
+
+	for _, component := range report.Components {
+		if component.Status != "online" {
+			report.Ready = false
+		}
+	}
+
+	for name, probe := range c.gatewayInstance.ProbeStatus() {
+		report.Processors[name] = newProcessorProbe(probe, report.CheckedAt)
+	}
+
+	return report
+}
+
+// pingComponent executa o ping de uma dependência medindo a latência
+func pingComponent(ctx context.Context, p Pinger) ComponentStatus {
+	start := time.Now()
+	err := p.Ping(ctx)
+	status := ComponentStatus{
+		Status:    "online",
+		LatencyMs: time.Since(start).Milliseconds(),
+	}
+	if err != nil {
+		status.Status = "offline"
+		status.Error = err.Error()
+	}
+	return status
+}
+
+// newProcessorProbe converte o resultado do Gateway Instance para o formato do relatório
+func newProcessorProbe(probe gateway.ProbeResult, now time.Time) ProcessorProbe {
+	result := ProcessorProbe{Healthy: probe.Healthy}
+	if !probe.LastCheck.IsZero() {
+		lastCheck := probe.LastCheck
+		result.LastCheck = &lastCheck
+	}
+	if !probe.LastSuccess.IsZero() {
+		lastSuccess := probe.LastSuccess
+		result.LastSuccess = &lastSuccess
+		result.LastSuccessAge = now.Sub(lastSuccess).Round(time.Millisecond).String()
+	}
+	return result
+}
@@ -0,0 +1,122 @@
+package httpclient
+
+import (
+	"bytes"
+	"log"
+	"net"
+	"net/http"
+	"os"
+	"strconv"
+	"sync"
+	"time"
+)
+
+// Config define o pool de conexões compartilhado com os payment processors
+type Config struct {
+	MaxConnsPerHost     int           // Limite de conexões simultâneas por processor (0 = sem limite)
+	MaxIdleConnsPerHost int           // Conexões keep-alive mantidas abertas por processor
+	IdleConnTimeout     time.Duration // Tempo até fechar uma conexão ociosa
+	DialTimeout         time.Duration // Timeout para abrir uma conexão TCP
+	PaymentTimeout      time.Duration // Deadline de cada POST /payments
+	HealthCheckTimeout  time.Duration // Deadline de cada GET /payments/service-health
+	H2C                 bool          // Usa HTTP/2 sem TLS (prior knowledge) com os processors
+}
+
+// DefaultConfig retorna uma configuração dimensionada para o pico da Rinha
+func DefaultConfig() Config {
+	return Config{
+		MaxConnsPerHost:     0,
+		MaxIdleConnsPerHost: 256,
+		IdleConnTimeout:     90 * time.Second,
+		DialTimeout:         2 * time.Second,
+		PaymentTimeout:      30 * time.Second,
+		HealthCheckTimeout:  5 * time.Second,
+	}
+}
+
+// ConfigFromEnv lê a configuração das variáveis de ambiente, com DefaultConfig como base
+func ConfigFromEnv() Config {
+	cfg := DefaultConfig()
+	cfg.MaxConnsPerHost = getEnvInt("PROCESSOR_MAX_CONNS_PER_HOST", cfg.MaxConnsPerHost)
+	cfg.MaxIdleConnsPerHost = getEnvInt("PROCESSOR_MAX_IDLE_CONNS_PER_HOST", cfg.MaxIdleConnsPerHost)
+	cfg.IdleConnTimeout = getEnvDuration("PROCESSOR_IDLE_CONN_TIMEOUT", cfg.IdleConnTimeout)
+	cfg.DialTimeout = getEnvDuration("PROCESSOR_DIAL_TIMEOUT", cfg.DialTimeout)
+	cfg.PaymentTimeout = getEnvDuration("PAYMENT_TIMEOUT", cfg.PaymentTimeout)
+	cfg.HealthCheckTimeout = getEnvDuration("HEALTH_CHECK_TIMEOUT", cfg.HealthCheckTimeout)
+	cfg.H2C = os.Getenv("PROCESSOR_H2C") == "true"
+	return cfg
+}
+
+// New cria o http.Client compartilhado por todos os componentes que falam com os processors.
+// O client não tem Timeout global: cada chamada define seu deadline via context.
+// O transport do Go não suporta pipelining HTTP/1.1; com H2C as requisições
+// são multiplexadas em uma única conexão HTTP/2 por processor.
+func New(cfg Config) *http.Client {
+	dialer := &net.Dialer{
+		Timeout:   cfg.DialTimeout,
+		KeepAlive: 30 * time.Second,
+	}
+
+	transport := &http.Transport{
+		Proxy:               nil,
+		DialContext:         dialer.DialContext,
+		MaxIdleConns:        cfg.MaxIdleConnsPerHost * 2,
+		MaxIdleConnsPerHost: cfg.MaxIdleConnsPerHost,
+		MaxConnsPerHost:     cfg.MaxConnsPerHost,
+		IdleConnTimeout:     cfg.IdleConnTimeout,
+		DisableCompression:  true,
+		ForceAttemptHTTP2:   false,
+	}
+
+	if cfg.H2C {
+		protocols := new(http.Protocols)
+		protocols.SetUnencryptedHTTP2(true)
+		transport.Protocols = protocols
+	}
+
+	log.Printf("🔌 HTTP client dos processors: idle/host=%d, max/host=%d, h2c=%t, timeouts: payment=%v health=%v",
+		cfg.MaxIdleConnsPerHost, cfg.MaxConnsPerHost, cfg.H2C, cfg.PaymentTimeout, cfg.HealthCheckTimeout)
+
+	return &http.Client{Transport: transport}
+}
+
+var bufferPool = sync.Pool{
+	New: func() interface{} {
+		return bytes.NewBuffer(make([]byte, 0, 512))
+	},
+}
+
+// GetBuffer retorna um buffer vazio do pool
+func GetBuffer() *bytes.Buffer {
+	return bufferPool.Get().(*bytes.Buffer)
+}
+
+// PutBuffer devolve o buffer ao pool. Buffers que cresceram demais são descartados
+// para não manter memória presa depois de uma resposta atípica.
+func PutBuffer(buf *bytes.Buffer) {
+	if buf.Cap() > 64*1024 {
+		return
+	}
+	buf.Reset()
+	bufferPool.Put(buf)
+}
+
+func getEnvInt(key string, defaultValue int) int {
+	if value := os.Getenv(key); value != "" {
+		if parsed, err := strconv.Atoi(value); err == nil {
+			return parsed
+		}
+		log.Printf("Aviso: valor inválido para %s: %s", key, value)
+	}
+	return defaultValue
+}
+
+func getEnvDuration(key string, defaultValue time.Duration) time.Duration {
+	if value := os.Getenv(key); value != "" {
+		if parsed, err := time.ParseDuration(value); err == nil {
+			return parsed
+		}
+		log.Printf("Aviso: valor inválido para %s: %s", key, value)
+	}
+	return defaultValue
+}
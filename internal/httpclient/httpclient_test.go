@@ -0,0 +1,96 @@
+package httpclient
+
+import (
+	"bytes"
+	"context"
+	"io"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+)
+
+var benchPayload = []byte(`{"correlationId":"4a7901b8-7d26-4d9d-aa19-4dc1c7cf60b3","amount":19.9,"requestedAt":"2025-07-15T12:34:56.789Z"}`)
+
+// newProcessorServer sobe um processor de mentira para o POST /payments e conta as
+// conexões abertas pelos clients
+func newProcessorServer(b *testing.B, h2c bool) (*httptest.Server, *atomic.Int64) {
+	b.Helper()
+	var dials atomic.Int64
+	srv := httptest.NewUnstartedServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		io.Copy(io.Discard, r.Body)
+		w.Header().Set("Content-Type", "application/json")
+		w.Write([]byte(`{"message":"payment processed successfully"}`))
+	}))
+	srv.Config.ConnState = func(conn net.Conn, state http.ConnState) {
+		if state == http.StateNew {
+			dials.Add(1)
+		}
+	}
+	if h2c {
+		protocols := new(http.Protocols)
+		protocols.SetHTTP1(true)
+		protocols.SetUnencryptedHTTP2(true)
+		srv.Config.Protocols = protocols
+	}
+	srv.Start()
+	b.Cleanup(srv.Close)
+	return srv, &dials
+}
+
+func postPayment(ctx context.Context, client *http.Client, url string) error {
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, url+"/payments", bytes.NewReader(benchPayload))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/json")
+
+	resp, err := client.Do(req)
+	if err != nil {
+		return err
+	}
+	io.Copy(io.Discard, resp.Body)
+	return resp.Body.Close()
+}
+
+// BenchmarkProcessorClient compara o http.DefaultTransport (só 2 conexões ociosas por
+// host) com o client do httpclient.New, com e sem H2C, contra um processor local.
+// "dials/op" são as conexões TCP abertas no servidor por pagamento (reconexões).
+func BenchmarkProcessorClient(b *testing.B) {
+	cfg := DefaultConfig()
+	h2cCfg := cfg
+	h2cCfg.H2C = true
+
+	clients := []struct {
+		name   string
+		client *http.Client
+		h2c    bool
+	}{
+		{"DefaultTransport", &http.Client{Transport: http.DefaultTransport.(*http.Transport).Clone()}, false},
+		{"httpclient", New(cfg), false},
+		{"httpclient-h2c", New(h2cCfg), true},
+	}
+
+	for _, c := range clients {
+		b.Run(c.name, func(b *testing.B) {
+			srv, dials := newProcessorServer(b, c.h2c)
+			defer c.client.CloseIdleConnections()
+			ctx := context.Background()
+
+			b.ReportAllocs()
+			b.SetParallelism(64) // 64 × GOMAXPROCS pagamentos simultâneos
+			b.ResetTimer()
+			b.RunParallel(func(pb *testing.PB) {
+				for pb.Next() {
+					if err := postPayment(ctx, c.client, srv.URL); err != nil {
+						b.Error(err)
+						return
+					}
+				}
+			})
+			b.StopTimer()
+			b.ReportMetric(float64(dials.Load())/float64(b.N), "dials/op")
+		})
+	}
+}
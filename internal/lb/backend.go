@@ -0,0 +1,136 @@
+package lb
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"net/http/httputil"
+	"net/url"
+	"strings"
+	"sync/atomic"
+	"time"
+)
+
+// unixPrefix identifica backends acessados por Unix domain socket ("unix:/var/run/rinha/api01.sock")
+const unixPrefix = "unix:"
+
+// Backend é uma instância da API atrás do load balancer
+type Backend struct {
+	Address string
+
+	target    *url.URL
+	transport *http.Transport
+	proxy     *httputil.ReverseProxy
+
+	active  atomic.Int64
+	healthy atomic.Bool
+}
+
+// BackendStatus é o estado de um backend exposto em /lb/status
+type BackendStatus struct {
+	Address string `json:"address"`
+	Healthy bool   `json:"healthy"`
+	Active  int64  `json:"active"`
+}
+
+// ParseBackends interpreta a lista separada por vírgula de LB_BACKENDS.
+// Aceita "unix:/caminho.sock", "http://host:porta" ou "host:porta".
+func ParseBackends(value string, cfg Config) ([]*Backend, error) {
+	var backends []*Backend
+	for _, address := range strings.Split(value, ",") {
+		address = strings.TrimSpace(address)
+		if address == "" {
+			continue
+		}
+		backend, err := newBackend(address, cfg)
+		if err != nil {
+			return nil, err
+		}
+		backends = append(backends, backend)
+	}
+	if len(backends) == 0 {
+		return nil, fmt.Errorf("nenhum backend configurado")
+	}
+	return backends, nil
+}
+
+func newBackend(address string, cfg Config) (*Backend, error) {
+	dialer := &net.Dialer{Timeout: cfg.DialTimeout, KeepAlive: 30 * time.Second}
+	transport := &http.Transport{
+		MaxIdleConns:        cfg.MaxIdleConnsPerBackend,
+		MaxIdleConnsPerHost: cfg.MaxIdleConnsPerBackend,
+		IdleConnTimeout:     90 * time.Second,
+		DisableCompression:  true,
+		DialContext:         dialer.DialContext,
+	}
+
+	var target *url.URL
+	if socketPath, ok := strings.CutPrefix(address, unixPrefix); ok {
+		// O host é ignorado pelo dial: todas as conexões vão para o socket
+		target = &url.URL{Scheme: "http", Host: "unix"}
+		transport.DialContext = func(ctx context.Context, _, _ string) (net.Conn, error) {
+			return dialer.DialContext(ctx, "unix", socketPath)
+		}
+	} else {
+		if !strings.Contains(address, "://") {
+			address = "http://" + address
+		}
+		parsed, err := url.Parse(address)
+		if err != nil || parsed.Host == "" {
+			return nil, fmt.Errorf("backend inválido: %s", address)
+		}
+		target = parsed
+	}
+
+	b := &Backend{Address: address, target: target, transport: transport}
+	b.proxy = &httputil.ReverseProxy{
+		Rewrite: func(r *httputil.ProxyRequest) {
+			r.SetURL(target)
+			r.Out.Host = r.In.Host
+		},
+		Transport:     transport,
+		FlushInterval: -1, // respostas em streaming (SSE) não ficam presas no buffer
+		ErrorHandler:  b.proxyError,
+	}
+	// Até o primeiro health check, o backend é considerado saudável
+	b.healthy.Store(true)
+	return b, nil
+}
+
+// proxyError marca o backend como fora do ar até o próximo health check bem-sucedido
+func (b *Backend) proxyError(w http.ResponseWriter, r *http.Request, err error) {
+	if r.Context().Err() == nil {
+		b.healthy.Store(false)
+	}
+	http.Error(w, fmt.Sprintf("backend indisponível: %v", err), http.StatusBadGateway)
+}
+
+// check consulta o /readyz do backend
+func (b *Backend) check(ctx context.Context, path string, timeout time.Duration) bool {
+	ctx, cancel := context.WithTimeout(ctx, timeout)
+	defer cancel()
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, b.target.String()+path, nil)
+	if err != nil {
+		return false
+	}
+	resp, err := b.transport.RoundTrip(req)
+	if err != nil {
+		return false
+	}
+	defer resp.Body.Close()
+	io.Copy(io.Discard, resp.Body)
+
+	return resp.StatusCode == http.StatusOK
+}
+
+// Status retorna o estado atual do backend
+func (b *Backend) Status() BackendStatus {
+	return BackendStatus{
+		Address: b.Address,
+		Healthy: b.healthy.Load(),
+		Active:  b.active.Load(),
+	}
+}
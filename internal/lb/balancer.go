@@ -0,0 +1,146 @@
+package lb
+
+import (
+	"context"
+	"encoding/json"
+	"log"
+	"net/http"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// StatusPath é respondido pelo próprio load balancer, sem ir aos backends
+const StatusPath = "/lb/status"
+
+// Config define o comportamento do load balancer
+type Config struct {
+	HealthPath             string        // Endpoint de readiness das instâncias
+	HealthInterval         time.Duration // Intervalo entre health checks
+	HealthTimeout          time.Duration // Timeout de cada health check
+	DialTimeout            time.Duration // Timeout de conexão com o backend
+	MaxIdleConnsPerBackend int           // Conexões keep-alive mantidas por backend
+}
+
+// DefaultConfig retorna a configuração padrão do load balancer
+func DefaultConfig() Config {
+	return Config{
+		HealthPath:             "/readyz",
+		HealthInterval:         2 * time.Second,
+		HealthTimeout:          time.Second,
+		DialTimeout:            time.Second,
+		MaxIdleConnsPerBackend: 64,
+	}
+}
+
+// Balancer distribui requisições entre as instâncias pelo menor número de
+// conexões ativas, ignorando instâncias que falharam no /readyz
+type Balancer struct {
+	cfg      Config
+	backends []*Backend
+	next     atomic.Uint64
+}
+
+// New cria um novo load balancer
+func New(backends []*Backend, cfg Config) *Balancer {
+	return &Balancer{cfg: cfg, backends: backends}
+}
+
+// ServeHTTP encaminha a requisição para o backend escolhido
+func (b *Balancer) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	if r.URL.Path == StatusPath {
+		b.serveStatus(w)
+		return
+	}
+
+	backend := b.pick()
+	backend.active.Add(1)
+	defer backend.active.Add(-1)
+
+	backend.proxy.ServeHTTP(w, r)
+}
+
+// pick escolhe o backend saudável com menos conexões ativas. O ponto de partida
+// gira a cada chamada para que empates sejam distribuídos em round-robin.
+// Se nenhum estiver saudável, tenta todos (melhor do que recusar o tráfego).
+func (b *Balancer) pick() *Backend {
+	start := int(b.next.Add(1) % uint64(len(b.backends)))
+
+	var chosen *Backend
+	for _, healthyOnly := range []bool{true, false} {
+		for i := range b.backends {
+			backend := b.backends[(start+i)%len(b.backends)]
+			if healthyOnly && !backend.healthy.Load() {
+				continue
+			}
+			if chosen == nil || backend.active.Load() < chosen.active.Load() {
+				chosen = backend
+			}
+		}
+		if chosen != nil {
+			return chosen
+		}
+	}
+	return chosen
+}
+
+// Start inicia os health checks periódicos até o contexto ser cancelado
+func (b *Balancer) Start(ctx context.Context) {
+	b.checkAll(ctx)
+
+	go func() {
+		ticker := time.NewTicker(b.cfg.HealthInterval)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case <-ticker.C:
+				b.checkAll(ctx)
+			}
+		}
+	}()
+}
+
+func (b *Balancer) checkAll(ctx context.Context) {
+	var wg sync.WaitGroup
+	for _, backend := range b.backends {
+		wg.Add(1)
+		go func(backend *Backend) {
+			defer wg.Done()
+			healthy := backend.check(ctx, b.cfg.HealthPath, b.cfg.HealthTimeout)
+			if previous := backend.healthy.Swap(healthy); previous != healthy {
+				if healthy {
+					log.Printf("✅ Backend %s voltou a ficar pronto", backend.Address)
+				} else {
+					log.Printf("❌ Backend %s não está pronto, removido do balanceamento", backend.Address)
+				}
+			}
+		}(backend)
+	}
+	wg.Wait()
+}
+
+// Status retorna o estado de todos os backends
+func (b *Balancer) Status() []BackendStatus {
+	status := make([]BackendStatus, len(b.backends))
+	for i, backend := range b.backends {
+		status[i] = backend.Status()
+	}
+	return status
+}
+
+func (b *Balancer) serveStatus(w http.ResponseWriter) {
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]interface{}{
+		"algorithm": "least-connections",
+		"backends":  b.Status(),
+	})
+}
+
+// CloseIdleConnections fecha as conexões keep-alive com os backends
+func (b *Balancer) CloseIdleConnections() {
+	for _, backend := range b.backends {
+		backend.transport.CloseIdleConnections()
+	}
+}
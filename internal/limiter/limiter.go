@@ -0,0 +1,239 @@
+package limiter
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"time"
+)
+
+// ErrLimitExceeded indica que não houve vaga dentro do tempo de fila
+var ErrLimitExceeded = fmt.Errorf("limite de concorrência atingido")
+
+// decreaseCooldown evita que uma rajada de falhas simultâneas derrube o limite várias vezes
+const decreaseCooldown = 100 * time.Millisecond
+
+// Config define os limites do controle AIMD
+type Config struct {
+	InitialLimit     int
+	MinLimit         int
+	MaxLimit         int
+	LatencyThreshold time.Duration // Latência acima disso conta como sinal de sobrecarga
+	BackoffRatio     float64       // Fator multiplicativo aplicado em sobrecarga (ex: 0.9)
+	QueueTimeout     time.Duration // Tempo máximo esperando vaga antes de desistir
+}
+
+// DefaultConfig retorna limites conservadores para um payment processor
+func DefaultConfig() Config {
+	return Config{
+		InitialLimit:     50,
+		MinLimit:         5,
+		MaxLimit:         500,
+		LatencyThreshold: time.Second,
+		BackoffRatio:     0.9,
+		QueueTimeout:     100 * time.Millisecond,
+	}
+}
+
+// Stats é o estado atual de um limitador
+type Stats struct {
+	Name      string `json:"name"`
+	Limit     int    `json:"limit"`
+	InFlight  int    `json:"in_flight"`
+	Queued    int    `json:"queued"`
+	Rejected  uint64 `json:"rejected"`
+	Throttled bool   `json:"throttled"`
+}
+
+// State é a transição de um limitador entre normal e estrangulado, o equivalente a abrir e
+// fechar um circuit breaker: estrangulado quando sucessivas sobrecargas levam o limite ao
+// mínimo, normal de novo quando ele volta ao limite inicial
+type State struct {
+	Processor string `json:"processor"`
+	Throttled bool   `json:"throttled"`
+	Limit     int    `json:"limit"`
+}
+
+// Limiter é um limitador de concorrência AIMD: aumenta o limite em ~1 a cada
+// janela de chamadas rápidas e bem-sucedidas e o reduz multiplicativamente
+// quando a latência passa do limiar ou a chamada falha
+type Limiter struct {
+	name string
+	cfg  Config
+
+	mu           sync.Mutex
+	limit        float64
+	inFlight     int
+	waiters      []chan struct{}
+	rejected     uint64
+	lastDecrease time.Time
+	throttled    bool
+	onChange     func(State)
+}
+
+// New cria um limitador para um processor
+func New(name string, cfg Config) *Limiter {
+	if cfg.MinLimit < 1 {
+		cfg.MinLimit = 1
+	}
+	if cfg.MaxLimit < cfg.MinLimit {
+		cfg.MaxLimit = cfg.MinLimit
+	}
+	if cfg.InitialLimit < cfg.MinLimit || cfg.InitialLimit > cfg.MaxLimit {
+		cfg.InitialLimit = cfg.MinLimit
+	}
+	return &Limiter{name: name, cfg: cfg, limit: float64(cfg.InitialLimit)}
+}
+
+// OnStateChange registra fn para ser chamada (fora do lock) a cada transição de estado.
+// Deve ser chamada antes de o limitador entrar em uso.
+func (l *Limiter) OnStateChange(fn func(State)) {
+	l.onChange = fn
+}
+
+// Token representa uma vaga adquirida; Release deve ser chamado exatamente uma vez
+type Token struct {
+	limiter *Limiter
+}
+
+// TryAcquire tenta uma vaga sem esperar
+func (l *Limiter) TryAcquire() (*Token, bool) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	if l.inFlight < int(l.limit) {
+		l.inFlight++
+		return &Token{limiter: l}, true
+	}
+	return nil, false
+}
+
+// Acquire espera por uma vaga por até QueueTimeout (ou até o ctx ser cancelado)
+func (l *Limiter) Acquire(ctx context.Context) (*Token, error) {
+	l.mu.Lock()
+	if l.inFlight < int(l.limit) && len(l.waiters) == 0 {
+		l.inFlight++
+		l.mu.Unlock()
+		return &Token{limiter: l}, nil
+	}
+	ready := make(chan struct{})
+	l.waiters = append(l.waiters, ready)
+	l.mu.Unlock()
+
+	timer := time.NewTimer(l.cfg.QueueTimeout)
+	defer timer.Stop()
+
+	var reason error
+	select {
+	case <-ready:
+		return &Token{limiter: l}, nil
+	case <-timer.C:
+		reason = ErrLimitExceeded
+	case <-ctx.Done():
+		reason = ctx.Err()
+	}
+
+	if l.abandon(ready) {
+		return nil, reason
+	}
+	// A vaga chegou junto com o timeout: aproveitá-la
+	return &Token{limiter: l}, nil
+}
+
+// abandon retira o waiter da fila. Retorna false se a vaga já tinha sido entregue.
+func (l *Limiter) abandon(ready chan struct{}) bool {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	for i, waiter := range l.waiters {
+		if waiter == ready {
+			l.waiters = append(l.waiters[:i], l.waiters[i+1:]...)
+			l.rejected++
+			return true
+		}
+	}
+	return false
+}
+
+// Release devolve a vaga e ajusta o limite conforme o resultado da chamada.
+// overloaded deve ser true para falhas do processor ou timeouts, e false para
+// sucessos e cancelamentos feitos por nós (ex: perdedor de um hedge).
+func (t *Token) Release(latency time.Duration, overloaded bool) {
+	l := t.limiter
+	l.mu.Lock()
+
+	l.inFlight--
+
+	decreased := false
+	if overloaded || latency > l.cfg.LatencyThreshold {
+		if time.Since(l.lastDecrease) >= decreaseCooldown {
+			decreased = true
+			l.limit *= l.cfg.BackoffRatio
+			if l.limit < float64(l.cfg.MinLimit) {
+				l.limit = float64(l.cfg.MinLimit)
+			}
+			l.lastDecrease = time.Now()
+		}
+	} else {
+		l.limit += 1 / l.limit
+		if l.limit > float64(l.cfg.MaxLimit) {
+			l.limit = float64(l.cfg.MaxLimit)
+		}
+	}
+
+	changed := l.updateStateLocked(decreased)
+	state := State{Processor: l.name, Throttled: l.throttled, Limit: int(l.limit)}
+	l.wakeLocked()
+	l.mu.Unlock()
+
+	if changed && l.onChange != nil {
+		l.onChange(state)
+	}
+}
+
+// updateStateLocked aplica a histerese entre normal e estrangulado e indica se mudou.
+// Só uma redução por sobrecarga estrangula (um limite inicial igual ao mínimo não conta).
+func (l *Limiter) updateStateLocked(decreased bool) bool {
+	if l.cfg.MinLimit == l.cfg.MaxLimit {
+		return false // Limite fixo: não há o que estrangular
+	}
+	recoverAt := l.cfg.InitialLimit
+	if recoverAt <= l.cfg.MinLimit {
+		recoverAt = l.cfg.MinLimit + 1
+	}
+
+	switch {
+	case !l.throttled && decreased && int(l.limit) <= l.cfg.MinLimit:
+		l.throttled = true
+	case l.throttled && int(l.limit) >= recoverAt:
+		l.throttled = false
+	default:
+		return false
+	}
+	return true
+}
+
+// wakeLocked entrega vagas livres aos waiters em ordem de chegada
+func (l *Limiter) wakeLocked() {
+	for len(l.waiters) > 0 && l.inFlight < int(l.limit) {
+		waiter := l.waiters[0]
+		l.waiters = l.waiters[1:]
+		l.inFlight++
+		close(waiter)
+	}
+}
+
+// Stats retorna o estado atual do limitador
+func (l *Limiter) Stats() Stats {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	return Stats{
+		Name:      l.name,
+		Limit:     int(l.limit),
+		InFlight:  l.inFlight,
+		Queued:    len(l.waiters),
+		Rejected:  l.rejected,
+		Throttled: l.throttled,
+	}
+}
@@ -0,0 +1,88 @@
+package listener
+
+import (
+	"errors"
+	"fmt"
+	"log"
+	"net"
+	"os"
+	"path/filepath"
+	"strconv"
+	"syscall"
+	"time"
+)
+
+// staleProbeTimeout limita a tentativa de conexão usada para detectar sockets abandonados
+const staleProbeTimeout = 500 * time.Millisecond
+
+// ParseMode converte uma permissão em octal ("660", "0666") para os.FileMode
+func ParseMode(value string) (os.FileMode, error) {
+	mode, err := strconv.ParseUint(value, 8, 32)
+	if err != nil || mode > 0o777 {
+		return 0, fmt.Errorf("permissão de socket inválida: %s", value)
+	}
+	return os.FileMode(mode), nil
+}
+
+// TCP abre o listener TCP na porta informada
+func TCP(port string) (net.Listener, error) {
+	ln, err := net.Listen("tcp", ":"+port)
+	if err != nil {
+		return nil, fmt.Errorf("erro ao escutar na porta %s: %v", port, err)
+	}
+	return ln, nil
+}
+
+// Unix abre um listener em um Unix domain socket. Um socket abandonado por uma
+// execução anterior (ninguém aceitando conexões) é removido; um socket ainda em
+// uso ou um arquivo comum no caminho é erro. O arquivo é removido no Close.
+func Unix(path string, mode os.FileMode) (net.Listener, error) {
+	if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+		return nil, fmt.Errorf("erro ao criar diretório do socket: %v", err)
+	}
+
+	if err := removeStale(path); err != nil {
+		return nil, err
+	}
+
+	ln, err := net.Listen("unix", path)
+	if err != nil {
+		return nil, fmt.Errorf("erro ao escutar no socket %s: %v", path, err)
+	}
+
+	if err := os.Chmod(path, mode); err != nil {
+		ln.Close()
+		return nil, fmt.Errorf("erro ao ajustar permissões do socket %s: %v", path, err)
+	}
+
+	return ln, nil
+}
+
+// removeStale remove o socket deixado por um processo que não encerrou corretamente
+func removeStale(path string) error {
+	info, err := os.Lstat(path)
+	if errors.Is(err, os.ErrNotExist) {
+		return nil
+	}
+	if err != nil {
+		return fmt.Errorf("erro ao verificar socket %s: %v", path, err)
+	}
+	if info.Mode()&os.ModeSocket == 0 {
+		return fmt.Errorf("%s existe e não é um socket", path)
+	}
+
+	conn, err := net.DialTimeout("unix", path, staleProbeTimeout)
+	if err == nil {
+		conn.Close()
+		return fmt.Errorf("socket %s já está em uso por outro processo", path)
+	}
+	if !errors.Is(err, syscall.ECONNREFUSED) && !errors.Is(err, os.ErrNotExist) {
+		return fmt.Errorf("erro ao verificar socket %s: %v", path, err)
+	}
+
+	log.Printf("🧹 Removendo socket abandonado: %s", path)
+	if err := os.Remove(path); err != nil && !errors.Is(err, os.ErrNotExist) {
+		return fmt.Errorf("erro ao remover socket abandonado %s: %v", path, err)
+	}
+	return nil
+}
@@ -0,0 +1,446 @@
+package loadgen
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"crypto/rand"
+	"encoding/json"
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+	"os"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"rinha-de-backend-2025/internal/mockprocessor"
+	"rinha-de-backend-2025/internal/repository"
+)
+
+// Pontuação da Rinha 2025: bônus de 2% por ms abaixo de 11ms no p99 e
+// multa de 35% no lucro quando há inconsistências entre os resumos
+const (
+	p99BonusThreshold      = 11 * time.Millisecond
+	p99BonusPerMs          = 0.02
+	inconsistencyPenalty   = 0.35
+	defaultPaymentAmount   = 19.90
+	settleTimeAfterTraffic = 2 * time.Second
+)
+
+// FailureWindow é uma janela em que um processor é colocado em modo de falha
+type FailureWindow struct {
+	Processor string
+	Start     time.Duration
+	End       time.Duration
+}
+
+// ParseFailureWindow interpreta janelas no formato "default:10s-25s"
+func ParseFailureWindow(value string) (FailureWindow, error) {
+	parts := strings.SplitN(value, ":", 2)
+	if len(parts) != 2 {
+		return FailureWindow{}, fmt.Errorf("janela inválida %q: use processor:inicio-fim", value)
+	}
+	bounds := strings.SplitN(parts[1], "-", 2)
+	if len(bounds) != 2 {
+		return FailureWindow{}, fmt.Errorf("janela inválida %q: use processor:inicio-fim", value)
+	}
+
+	start, err := time.ParseDuration(bounds[0])
+	if err != nil {
+		return FailureWindow{}, fmt.Errorf("início inválido em %q: %v", value, err)
+	}
+	end, err := time.ParseDuration(bounds[1])
+	if err != nil {
+		return FailureWindow{}, fmt.Errorf("fim inválido em %q: %v", value, err)
+	}
+	if end <= start {
+		return FailureWindow{}, fmt.Errorf("janela %q termina antes de começar", value)
+	}
+
+	return FailureWindow{Processor: parts[0], Start: start, End: end}, nil
+}
+
+// Config define o perfil de carga
+type Config struct {
+	TargetURL      string        // URL da API (ou load balancer)
+	Duration       time.Duration // Duração total do tráfego
+	StartRate      float64       // Requisições por segundo no início da rampa
+	EndRate        float64       // Requisições por segundo no fim da rampa
+	MaxInFlight    int           // Máximo de requisições simultâneas
+	Amount         float64       // Valor de cada pagamento sintetizado
+	ReplayFile     string        // Arquivo JSONL com payloads {"correlationId","amount"} a reproduzir
+	Processors     []*ProcessorAdmin
+	FailureWindows []FailureWindow
+	Purge          bool // Limpa os processors antes de começar
+}
+
+// Result é o resultado de uma única requisição
+type Result struct {
+	StatusCode int
+	Latency    time.Duration
+	Err        error
+}
+
+// Report é o relatório final de uma execução
+type Report struct {
+	Requests           int                               `json:"requests"`
+	Succeeded          int                               `json:"succeeded"`
+	Failed             int                               `json:"failed"`
+	TransportErrors    int                               `json:"transport_errors"`
+	Dropped            int                               `json:"dropped"`
+	P50                time.Duration                     `json:"p50"`
+	P99                time.Duration                     `json:"p99"`
+	Max                time.Duration                     `json:"max"`
+	From               time.Time                         `json:"from"`
+	To                 time.Time                         `json:"to"`
+	BackendSummary     *repository.PaymentSummary        `json:"backend_summary,omitempty"`
+	ProcessorSummaries map[string]*mockprocessor.Summary `json:"processor_summaries,omitempty"`
+	Inconsistencies    []Inconsistency                   `json:"inconsistencies"`
+	GrossProfit        float64                           `json:"gross_profit"`
+	SimulatedProfit    float64                           `json:"simulated_profit"`
+}
+
+// paymentPayload é o corpo enviado para POST /payments
+type paymentPayload struct {
+	CorrelationID string  `json:"correlationId"`
+	Amount        float64 `json:"amount"`
+}
+
+// Runner executa um perfil de carga contra a API
+type Runner struct {
+	cfg     Config
+	client  *http.Client
+	mu      sync.Mutex
+	results []Result
+	dropped int
+}
+
+// NewRunner cria um novo executor de carga
+func NewRunner(cfg Config, client *http.Client) *Runner {
+	if cfg.Amount <= 0 {
+		cfg.Amount = defaultPaymentAmount
+	}
+	if cfg.MaxInFlight <= 0 {
+		cfg.MaxInFlight = 500
+	}
+	if cfg.EndRate <= 0 {
+		cfg.EndRate = cfg.StartRate
+	}
+	return &Runner{cfg: cfg, client: client}
+}
+
+// Run executa o perfil de carga e compara os resumos ao final
+func (r *Runner) Run(ctx context.Context) (*Report, error) {
+	payloads, err := r.payloadSource()
+	if err != nil {
+		return nil, err
+	}
+
+	if r.cfg.Purge {
+		for _, processor := range r.cfg.Processors {
+			if err := processor.Purge(ctx); err != nil {
+				return nil, err
+			}
+		}
+	}
+
+	report := &Report{From: time.Now().UTC()}
+	log.Printf("🚀 Iniciando carga: %.0f → %.0f req/s por %v contra %s",
+		r.cfg.StartRate, r.cfg.EndRate, r.cfg.Duration, r.cfg.TargetURL)
+
+	trafficCtx, cancel := context.WithTimeout(ctx, r.cfg.Duration)
+	defer cancel()
+
+	var windows sync.WaitGroup
+	r.scheduleFailureWindows(trafficCtx, &windows)
+
+	var inFlight sync.WaitGroup
+	slots := make(chan struct{}, r.cfg.MaxInFlight)
+	start := time.Now()
+
+	for trafficCtx.Err() == nil {
+		payload, ok := payloads()
+		if !ok {
+			break
+		}
+
+		select {
+		case slots <- struct{}{}:
+			inFlight.Add(1)
+			go func() {
+				defer inFlight.Done()
+				defer func() { <-slots }()
+				r.record(r.sendPayment(ctx, payload))
+			}()
+		default:
+			r.mu.Lock()
+			r.dropped++
+			r.mu.Unlock()
+		}
+
+		rate := r.rateAt(time.Since(start))
+		select {
+		case <-trafficCtx.Done():
+		case <-time.After(time.Duration(float64(time.Second) / rate)):
+		}
+	}
+
+	inFlight.Wait()
+	windows.Wait()
+	time.Sleep(settleTimeAfterTraffic)
+	report.To = time.Now().UTC()
+
+	r.fillLatencies(report)
+	if err := r.fillSummaries(ctx, report); err != nil {
+		return report, err
+	}
+
+	return report, nil
+}
+
+// rateAt calcula a taxa da rampa linear no instante informado
+func (r *Runner) rateAt(elapsed time.Duration) float64 {
+	progress := float64(elapsed) / float64(r.cfg.Duration)
+	if progress > 1 {
+		progress = 1
+	}
+	rate := r.cfg.StartRate + (r.cfg.EndRate-r.cfg.StartRate)*progress
+	if rate < 1 {
+		rate = 1
+	}
+	return rate
+}
+
+// payloadSource retorna um gerador de payloads (replay de arquivo ou sintético)
+func (r *Runner) payloadSource() (func() (paymentPayload, bool), error) {
+	if r.cfg.ReplayFile == "" {
+		return func() (paymentPayload, bool) {
+			return paymentPayload{CorrelationID: newUUID(), Amount: r.cfg.Amount}, true
+		}, nil
+	}
+
+	file, err := os.Open(r.cfg.ReplayFile)
+	if err != nil {
+		return nil, fmt.Errorf("erro ao abrir arquivo de replay: %v", err)
+	}
+	defer file.Close()
+
+	var payloads []paymentPayload
+	scanner := bufio.NewScanner(file)
+	for scanner.Scan() {
+		line := bytes.TrimSpace(scanner.Bytes())
+		if len(line) == 0 {
+			continue
+		}
+		var payload paymentPayload
+		if err := json.Unmarshal(line, &payload); err != nil {
+			return nil, fmt.Errorf("linha inválida no arquivo de replay: %v", err)
+		}
+		payloads = append(payloads, payload)
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, fmt.Errorf("erro ao ler arquivo de replay: %v", err)
+	}
+
+	next := 0
+	return func() (paymentPayload, bool) {
+		if next >= len(payloads) {
+			return paymentPayload{}, false
+		}
+		payload := payloads[next]
+		next++
+		return payload, true
+	}, nil
+}
+
+// scheduleFailureWindows liga e desliga o modo de falha dos processors nos horários configurados
+func (r *Runner) scheduleFailureWindows(ctx context.Context, wg *sync.WaitGroup) {
+	admins := make(map[string]*ProcessorAdmin)
+	for _, processor := range r.cfg.Processors {
+		admins[processor.Name] = processor
+	}
+
+	for _, window := range r.cfg.FailureWindows {
+		admin, ok := admins[window.Processor]
+		if !ok {
+			log.Printf("⚠️ Janela de falha ignorada: processor %s desconhecido", window.Processor)
+			continue
+		}
+
+		wg.Add(1)
+		go func(window FailureWindow) {
+			defer wg.Done()
+
+			select {
+			case <-ctx.Done():
+				return
+			case <-time.After(window.Start):
+			}
+			log.Printf("💥 Processor %s em falha (%v-%v)", window.Processor, window.Start, window.End)
+			if err := admin.SetFailure(context.Background(), true); err != nil {
+				log.Printf("❌ Erro ao ativar falha: %v", err)
+			}
+
+			select {
+			case <-ctx.Done():
+			case <-time.After(window.End - window.Start):
+			}
+			log.Printf("✅ Processor %s recuperado", window.Processor)
+			if err := admin.SetFailure(context.Background(), false); err != nil {
+				log.Printf("❌ Erro ao desativar falha: %v", err)
+			}
+		}(window)
+	}
+}
+
+func (r *Runner) sendPayment(ctx context.Context, payload paymentPayload) Result {
+	body, err := json.Marshal(payload)
+	if err != nil {
+		return Result{Err: err}
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, r.cfg.TargetURL+"/payments", bytes.NewReader(body))
+	if err != nil {
+		return Result{Err: err}
+	}
+	req.Header.Set("Content-Type", "application/json")
+
+	start := time.Now()
+	resp, err := r.client.Do(req)
+	if err != nil {
+		return Result{Err: err, Latency: time.Since(start)}
+	}
+	io.Copy(io.Discard, resp.Body)
+	resp.Body.Close()
+
+	return Result{StatusCode: resp.StatusCode, Latency: time.Since(start)}
+}
+
+func (r *Runner) record(result Result) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.results = append(r.results, result)
+}
+
+// fillLatencies calcula contadores e percentis de latência
+func (r *Runner) fillLatencies(report *Report) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	latencies := make([]time.Duration, 0, len(r.results))
+	for _, result := range r.results {
+		report.Requests++
+		switch {
+		case result.Err != nil:
+			report.TransportErrors++
+		case result.StatusCode >= 200 && result.StatusCode < 300:
+			report.Succeeded++
+		default:
+			report.Failed++
+		}
+		latencies = append(latencies, result.Latency)
+	}
+	report.Dropped = r.dropped
+
+	if len(latencies) == 0 {
+		return
+	}
+	sort.Slice(latencies, func(i, j int) bool { return latencies[i] < latencies[j] })
+	report.P50 = Percentile(latencies, 0.50)
+	report.P99 = Percentile(latencies, 0.99)
+	report.Max = latencies[len(latencies)-1]
+}
+
+// fillSummaries compara os resumos da API e dos processors e calcula o lucro simulado
+func (r *Runner) fillSummaries(ctx context.Context, report *Report) error {
+	backend, err := FetchBackendSummary(ctx, r.client, r.cfg.TargetURL, report.From, report.To)
+	if err != nil {
+		return err
+	}
+	report.BackendSummary = backend
+
+	if len(r.cfg.Processors) == 0 {
+		return nil
+	}
+
+	report.ProcessorSummaries = make(map[string]*mockprocessor.Summary)
+	for _, processor := range r.cfg.Processors {
+		summary, err := processor.Summary(ctx, report.From, report.To)
+		if err != nil {
+			return err
+		}
+		report.ProcessorSummaries[processor.Name] = summary
+		report.GrossProfit += summary.TotalAmount - summary.TotalFee
+	}
+
+	report.Inconsistencies = CompareSummaries(backend, report.ProcessorSummaries)
+	report.SimulatedProfit = SimulatedProfit(report.GrossProfit, report.P99, len(report.Inconsistencies) > 0)
+	return nil
+}
+
+// SimulatedProfit aplica o bônus de p99 e a multa por inconsistência da Rinha ao lucro líquido
+func SimulatedProfit(grossProfit float64, p99 time.Duration, inconsistent bool) float64 {
+	profit := grossProfit
+	if p99 < p99BonusThreshold {
+		bonusMs := float64(p99BonusThreshold-p99) / float64(time.Millisecond)
+		profit *= 1 + bonusMs*p99BonusPerMs
+	}
+	if inconsistent {
+		profit *= 1 - inconsistencyPenalty
+	}
+	return profit
+}
+
+// Percentile retorna o percentil p (0..1) de latências já ordenadas
+func Percentile(sorted []time.Duration, p float64) time.Duration {
+	if len(sorted) == 0 {
+		return 0
+	}
+	index := int(float64(len(sorted)-1) * p)
+	return sorted[index]
+}
+
+// Print escreve o relatório em formato legível
+func (report *Report) Print(w io.Writer) {
+	fmt.Fprintf(w, "=== Relatório de Carga ===\n")
+	fmt.Fprintf(w, "Janela: %s → %s\n", report.From.Format(time.RFC3339), report.To.Format(time.RFC3339))
+	fmt.Fprintf(w, "Requisições: %d (2xx: %d, erro HTTP: %d, erro de transporte: %d, descartadas: %d)\n",
+		report.Requests, report.Succeeded, report.Failed, report.TransportErrors, report.Dropped)
+	fmt.Fprintf(w, "Latência: p50=%v p99=%v max=%v\n", report.P50, report.P99, report.Max)
+
+	if report.BackendSummary != nil {
+		fmt.Fprintf(w, "Resumo da API: default=%d/%.2f fallback=%d/%.2f\n",
+			report.BackendSummary.Default.TotalRequests, report.BackendSummary.Default.TotalAmount,
+			report.BackendSummary.Fallback.TotalRequests, report.BackendSummary.Fallback.TotalAmount)
+	}
+	for _, name := range []string{"default", "fallback"} {
+		if summary, ok := report.ProcessorSummaries[name]; ok {
+			fmt.Fprintf(w, "Processor %s: %d/%.2f (taxas %.2f)\n",
+				name, summary.TotalRequests, summary.TotalAmount, summary.TotalFee)
+		}
+	}
+
+	if len(report.Inconsistencies) == 0 {
+		fmt.Fprintf(w, "Inconsistências: nenhuma ✅\n")
+	} else {
+		fmt.Fprintf(w, "Inconsistências: %d ❌\n", len(report.Inconsistencies))
+		for _, inconsistency := range report.Inconsistencies {
+			fmt.Fprintf(w, "  - %s.%s: API=%.2f processor=%.2f\n", inconsistency.Processor,
+				inconsistency.Field, inconsistency.BackendValue, inconsistency.ProcessorValue)
+		}
+	}
+	fmt.Fprintf(w, "Lucro líquido: %.2f | Lucro simulado (bônus p99/multa): %.2f\n",
+		report.GrossProfit, report.SimulatedProfit)
+}
+
+// newUUID gera um UUID v4 para correlationId
+func newUUID() string {
+	var b [16]byte
+	rand.Read(b[:])
+	b[6] = (b[6] & 0x0f) | 0x40
+	b[8] = (b[8] & 0x3f) | 0x80
+	return fmt.Sprintf("%x-%x-%x-%x-%x", b[0:4], b[4:6], b[6:8], b[8:10], b[10:16])
+}
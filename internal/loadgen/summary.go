@@ -0,0 +1,174 @@
+package loadgen
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/url"
+	"time"
+
+	"rinha-de-backend-2025/internal/mockprocessor"
+	"rinha-de-backend-2025/internal/repository"
+)
+
+// summaryTimeFormat é o formato aceito pelos endpoints de resumo (RFC3339 com milissegundos)
+const summaryTimeFormat = "2006-01-02T15:04:05.000Z07:00"
+
+// Inconsistency representa uma divergência entre o resumo da API e o do processor
+type Inconsistency struct {
+	Processor      string  `json:"processor"`
+	Field          string  `json:"field"`
+	BackendValue   float64 `json:"backend_value"`
+	ProcessorValue float64 `json:"processor_value"`
+}
+
+// FetchBackendSummary consulta GET /payments-summary da API
+func FetchBackendSummary(ctx context.Context, client *http.Client, baseURL string, from, to time.Time) (*repository.PaymentSummary, error) {
+	var summary repository.PaymentSummary
+	if err := getJSON(ctx, client, summaryURL(baseURL+"/payments-summary", from, to), nil, &summary); err != nil {
+		return nil, fmt.Errorf("erro ao buscar resumo da API: %v", err)
+	}
+	return &summary, nil
+}
+
+// ProcessorAdmin fala com os endpoints administrativos de um payment processor
+type ProcessorAdmin struct {
+	Name   string
+	URL    string
+	Token  string
+	client *http.Client
+}
+
+// NewProcessorAdmin cria um cliente administrativo para um processor
+func NewProcessorAdmin(name, processorURL, token string, client *http.Client) *ProcessorAdmin {
+	return &ProcessorAdmin{Name: name, URL: processorURL, Token: token, client: client}
+}
+
+// Summary consulta GET /admin/payments-summary do processor
+func (a *ProcessorAdmin) Summary(ctx context.Context, from, to time.Time) (*mockprocessor.Summary, error) {
+	var summary mockprocessor.Summary
+	headers := map[string]string{"X-Rinha-Token": a.Token}
+	if err := getJSON(ctx, a.client, summaryURL(a.URL+"/admin/payments-summary", from, to), headers, &summary); err != nil {
+		return nil, fmt.Errorf("erro ao buscar resumo do processor %s: %v", a.Name, err)
+	}
+	return &summary, nil
+}
+
+// SetFailure liga ou desliga o modo de falha do processor
+func (a *ProcessorAdmin) SetFailure(ctx context.Context, failure bool) error {
+	return a.send(ctx, http.MethodPut, "/admin/configurations/failure", map[string]bool{"failure": failure})
+}
+
+// SetDelay define a latência do processor
+func (a *ProcessorAdmin) SetDelay(ctx context.Context, delay time.Duration) error {
+	return a.send(ctx, http.MethodPut, "/admin/configurations/delay", map[string]int64{"delay": delay.Milliseconds()})
+}
+
+// Purge remove os pagamentos registrados no processor
+func (a *ProcessorAdmin) Purge(ctx context.Context) error {
+	return a.send(ctx, http.MethodPost, "/admin/purge-payments", nil)
+}
+
+func (a *ProcessorAdmin) send(ctx context.Context, method, path string, body interface{}) error {
+	var payload []byte
+	if body != nil {
+		var err error
+		if payload, err = json.Marshal(body); err != nil {
+			return fmt.Errorf("erro ao serializar request: %v", err)
+		}
+	}
+
+	req, err := http.NewRequestWithContext(ctx, method, a.URL+path, bytes.NewReader(payload))
+	if err != nil {
+		return fmt.Errorf("erro ao criar request: %v", err)
+	}
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("X-Rinha-Token", a.Token)
+
+	resp, err := a.client.Do(req)
+	if err != nil {
+		return fmt.Errorf("erro na requisição para %s%s: %v", a.URL, path, err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode >= 300 {
+		return fmt.Errorf("processor %s retornou status %d em %s", a.Name, resp.StatusCode, path)
+	}
+	return nil
+}
+
+// CompareSummaries compara o resumo da API com o resumo de cada processor
+func CompareSummaries(backend *repository.PaymentSummary, processors map[string]*mockprocessor.Summary) []Inconsistency {
+	var inconsistencies []Inconsistency
+
+	backendByName := map[string]repository.ProcessorSummary{
+		"default":  backend.Default,
+		"fallback": backend.Fallback,
+	}
+
+	for _, name := range []string{"default", "fallback"} {
+		processorSummary, ok := processors[name]
+		if !ok {
+			continue
+		}
+		backendSummary := backendByName[name]
+
+		if backendSummary.TotalRequests != processorSummary.TotalRequests {
+			inconsistencies = append(inconsistencies, Inconsistency{
+				Processor:      name,
+				Field:          "totalRequests",
+				BackendValue:   float64(backendSummary.TotalRequests),
+				ProcessorValue: float64(processorSummary.TotalRequests),
+			})
+		}
+		if !amountsEqual(backendSummary.TotalAmount, processorSummary.TotalAmount) {
+			inconsistencies = append(inconsistencies, Inconsistency{
+				Processor:      name,
+				Field:          "totalAmount",
+				BackendValue:   backendSummary.TotalAmount,
+				ProcessorValue: processorSummary.TotalAmount,
+			})
+		}
+	}
+
+	return inconsistencies
+}
+
+// amountsEqual compara valores monetários com tolerância de meio centavo
+func amountsEqual(a, b float64) bool {
+	diff := a - b
+	return diff < 0.005 && diff > -0.005
+}
+
+func summaryURL(endpoint string, from, to time.Time) string {
+	query := url.Values{}
+	query.Set("from", from.UTC().Format(summaryTimeFormat))
+	query.Set("to", to.UTC().Format(summaryTimeFormat))
+	return endpoint + "?" + query.Encode()
+}
+
+func getJSON(ctx context.Context, client *http.Client, endpoint string, headers map[string]string, out interface{}) error {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, endpoint, nil)
+	if err != nil {
+		return fmt.Errorf("erro ao criar request: %v", err)
+	}
+	for key, value := range headers {
+		req.Header.Set(key, value)
+	}
+
+	resp, err := client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return fmt.Errorf("status %d em %s", resp.StatusCode, endpoint)
+	}
+	if err := json.NewDecoder(resp.Body).Decode(out); err != nil {
+		return fmt.Errorf("erro ao deserializar resposta: %v", err)
+	}
+	return nil
+}
@@ -0,0 +1,68 @@
+package metrics
+
+import (
+	"context"
+	"errors"
+	"net"
+	"net/http"
+
+	"rinha-de-backend-2025/internal/payment"
+)
+
+// Classes de erro contadas por processor
+const (
+	CLASS_TIMEOUT      = "timeout"      // Deadline estourado antes da resposta
+	CLASS_CONNECTION   = "connection"   // Conexão recusada, interrompida ou DNS
+	CLASS_RATE_LIMITED = "rate_limited" // 429 Too Many Requests
+	CLASS_CLIENT       = "client_error" // Demais 4xx (pagamento rejeitado)
+	CLASS_SERVER       = "server_error" // 5xx
+	CLASS_OTHER        = "other"        // Resposta inválida e erros não classificados
+)
+
+// classes define a ordem dos contadores de erro
+var classes = []string{CLASS_TIMEOUT, CLASS_CONNECTION, CLASS_RATE_LIMITED, CLASS_CLIENT, CLASS_SERVER, CLASS_OTHER}
+
+const numClasses = 6
+
+// Classify retorna a classe de um erro de chamada ao processor
+func Classify(err error) string {
+	var statusErr *payment.StatusError
+	if errors.As(err, &statusErr) {
+		return ClassifyStatus(statusErr.StatusCode)
+	}
+
+	var netErr net.Error
+	if errors.Is(err, context.DeadlineExceeded) || (errors.As(err, &netErr) && netErr.Timeout()) {
+		return CLASS_TIMEOUT
+	}
+
+	var transportErr *payment.TransportError
+	var opErr *net.OpError
+	if errors.As(err, &transportErr) || errors.As(err, &opErr) || netErr != nil {
+		return CLASS_CONNECTION
+	}
+	return CLASS_OTHER
+}
+
+// ClassifyStatus retorna a classe de uma resposta HTTP diferente de 200
+func ClassifyStatus(statusCode int) string {
+	switch {
+	case statusCode == http.StatusTooManyRequests:
+		return CLASS_RATE_LIMITED
+	case statusCode >= 500:
+		return CLASS_SERVER
+	case statusCode >= 400:
+		return CLASS_CLIENT
+	default:
+		return CLASS_OTHER
+	}
+}
+
+func classIndex(class string) int {
+	for i, candidate := range classes {
+		if candidate == class {
+			return i
+		}
+	}
+	return numClasses - 1
+}
@@ -0,0 +1,228 @@
+package metrics
+
+import (
+	"net/http"
+	"time"
+)
+
+// windowSlices é a quantidade de fatias da janela deslizante (resolução = janela / 20)
+const windowSlices = 20
+
+// processorNames são os processors acompanhados
+var processorNames = []string{"default", "fallback"}
+
+// Registry mantém as janelas deslizantes de cada processor desta instância,
+// alimentadas pelas chamadas de pagamento e pelos health checks
+type Registry struct {
+	instanceID string
+	size       time.Duration
+	windows    map[string]*window
+	store      Store
+}
+
+// New cria o registro de métricas com uma janela do tamanho informado
+func New(instanceID string, size time.Duration) *Registry {
+	r := &Registry{
+		instanceID: instanceID,
+		size:       size,
+		windows:    make(map[string]*window, len(processorNames)),
+	}
+	for _, name := range processorNames {
+		r.windows[name] = newWindow(size, windowSlices)
+	}
+	return r
+}
+
+// ObservePayment registra uma chamada de pagamento ao processor (err nil = sucesso)
+func (r *Registry) ObservePayment(processor string, latency time.Duration, err error) {
+	if r == nil {
+		return
+	}
+	if w, ok := r.windows[processor]; ok {
+		class := ""
+		if err != nil {
+			class = Classify(err)
+		}
+		w.observePayment(time.Now(), latency, class)
+	}
+}
+
+// ObserveProbe registra um health check do processor: err de transporte ou o status da resposta
+func (r *Registry) ObserveProbe(processor string, latency time.Duration, statusCode int, err error) {
+	if r == nil {
+		return
+	}
+	if w, ok := r.windows[processor]; ok {
+		class := ""
+		if err != nil {
+			class = Classify(err)
+		} else if statusCode != http.StatusOK {
+			class = ClassifyStatus(statusCode)
+		}
+		w.observeProbe(time.Now(), latency, class)
+	}
+}
+
+// Collect retorna as contagens atuais da janela desta instância
+func (r *Registry) Collect() Data {
+	now := time.Now()
+	data := Data{
+		Instance:      r.instanceID,
+		WindowSeconds: r.size.Seconds(),
+		CollectedAt:   now,
+		Processors:    make(map[string]ProcessorData, len(r.windows)),
+	}
+	for name, w := range r.windows {
+		payments, probes := w.collect(now)
+		data.Processors[name] = ProcessorData{Payments: payments.export(), Probes: probes.export()}
+	}
+	return data
+}
+
+// Stats retorna as estatísticas locais de um processor
+func (r *Registry) Stats(processor string) ProcessorStats {
+	return statsOf(r.Collect())[processor]
+}
+
+// ProcessorData são as contagens somáveis de um processor, trocadas entre instâncias
+type ProcessorData struct {
+	Payments Counts `json:"payments"`
+	Probes   Counts `json:"probes"`
+}
+
+// Data é a janela de uma instância (ou a soma de várias)
+type Data struct {
+	Instance      string                   `json:"instance"`
+	WindowSeconds float64                  `json:"window_seconds"`
+	CollectedAt   time.Time                `json:"collected_at"`
+	Processors    map[string]ProcessorData `json:"processors"`
+}
+
+// Counts é a forma serializada de counts
+type Counts struct {
+	Total        int64            `json:"total"`
+	Successes    int64            `json:"successes"`
+	Errors       map[string]int64 `json:"errors,omitempty"`
+	LatencySumUs int64            `json:"latency_sum_us"`
+	LatencyMaxUs int64            `json:"latency_max_us"`
+	Histogram    []int64          `json:"histogram"`
+}
+
+func (c *counts) export() Counts {
+	exported := Counts{
+		Total:        c.total,
+		Successes:    c.successes,
+		LatencySumUs: c.latencySum,
+		LatencyMaxUs: c.latencyMax,
+		Histogram:    c.histogram,
+	}
+	for i, n := range c.errors {
+		if n > 0 {
+			if exported.Errors == nil {
+				exported.Errors = make(map[string]int64)
+			}
+			exported.Errors[classes[i]] = n
+		}
+	}
+	return exported
+}
+
+func (c Counts) internal() counts {
+	internal := newCounts()
+	internal.total = c.Total
+	internal.successes = c.Successes
+	internal.latencySum = c.LatencySumUs
+	internal.latencyMax = c.LatencyMaxUs
+	for class, n := range c.Errors {
+		internal.errors[classIndex(class)] += n
+	}
+	copy(internal.histogram, c.Histogram)
+	return internal
+}
+
+// Merge soma as janelas de várias instâncias
+func Merge(datas ...Data) Data {
+	merged := Data{Processors: make(map[string]ProcessorData)}
+	sums := make(map[string][2]counts)
+	for _, data := range datas {
+		if data.WindowSeconds > merged.WindowSeconds {
+			merged.WindowSeconds = data.WindowSeconds
+		}
+		if data.CollectedAt.After(merged.CollectedAt) {
+			merged.CollectedAt = data.CollectedAt
+		}
+		for name, processor := range data.Processors {
+			sum, ok := sums[name]
+			if !ok {
+				sum = [2]counts{newCounts(), newCounts()}
+			}
+			payments, probes := processor.Payments.internal(), processor.Probes.internal()
+			sum[0].add(&payments)
+			sum[1].add(&probes)
+			sums[name] = sum
+		}
+	}
+	for name, sum := range sums {
+		merged.Processors[name] = ProcessorData{Payments: sum[0].export(), Probes: sum[1].export()}
+	}
+	return merged
+}
+
+// Stats resume as contagens para leitura: taxa de erro, 429 e percentis de latência
+type Stats struct {
+	Total       int64            `json:"total"`
+	Successes   int64            `json:"successes"`
+	Failures    int64            `json:"failures"`
+	ErrorRate   float64          `json:"error_rate"`
+	RateLimited int64            `json:"rate_limited"`
+	Errors      map[string]int64 `json:"errors"`
+	Latency     Latency          `json:"latency_ms"`
+}
+
+// Latency são os percentis aproximados (limite do bucket do histograma), em ms
+type Latency struct {
+	Mean float64 `json:"mean"`
+	P50  float64 `json:"p50"`
+	P90  float64 `json:"p90"`
+	P99  float64 `json:"p99"`
+	Max  float64 `json:"max"`
+}
+
+// ProcessorStats são as estatísticas de pagamentos e health checks de um processor
+type ProcessorStats struct {
+	Payments Stats `json:"payments"`
+	Probes   Stats `json:"probes"`
+}
+
+func statsOf(data Data) map[string]ProcessorStats {
+	stats := make(map[string]ProcessorStats, len(data.Processors))
+	for name, processor := range data.Processors {
+		payments, probes := processor.Payments.internal(), processor.Probes.internal()
+		stats[name] = ProcessorStats{Payments: payments.stats(), Probes: probes.stats()}
+	}
+	return stats
+}
+
+func (c *counts) stats() Stats {
+	stats := Stats{
+		Total:       c.total,
+		Successes:   c.successes,
+		Failures:    c.total - c.successes,
+		RateLimited: c.errors[classIndex(CLASS_RATE_LIMITED)],
+		Errors:      make(map[string]int64, numClasses),
+	}
+	for i, n := range c.errors {
+		stats.Errors[classes[i]] = n
+	}
+	if c.total > 0 {
+		stats.ErrorRate = float64(stats.Failures) / float64(c.total)
+		stats.Latency = Latency{
+			Mean: float64(c.latencySum) / float64(c.total) / 1000,
+			P50:  float64(c.percentile(0.50)) / 1000,
+			P90:  float64(c.percentile(0.90)) / 1000,
+			P99:  float64(c.percentile(0.99)) / 1000,
+			Max:  float64(c.latencyMax) / 1000,
+		}
+	}
+	return stats
+}
@@ -0,0 +1,92 @@
+package metrics
+
+import (
+	"context"
+	"encoding/json"
+	"log"
+	"sort"
+	"time"
+)
+
+// Store guarda a janela publicada por cada instância (cache.RedisMetricsStore ou cache.MemoryMetricsStore)
+type Store interface {
+	SetInstanceMetrics(ctx context.Context, instanceID string, data []byte, ttl time.Duration) error
+	GetInstanceMetrics(ctx context.Context) (map[string][]byte, error)
+}
+
+// Report é a visão exposta em /payments/stats: a janela desta instância e a soma de todas
+type Report struct {
+	Instance      string                    `json:"instance"`
+	WindowSeconds float64                   `json:"window_seconds"`
+	Local         map[string]ProcessorStats `json:"local"`
+	Cluster       map[string]ProcessorStats `json:"cluster"`
+	Instances     []string                  `json:"instances"`
+	ClusterError  string                    `json:"cluster_error,omitempty"`
+}
+
+// Share publica a janela desta instância no store a cada interval, até o ctx ser
+// cancelado. Cada publicação expira em 3 intervalos: instâncias paradas saem da soma.
+func (r *Registry) Share(ctx context.Context, store Store, interval time.Duration) {
+	r.store = store
+	ttl := 3 * interval
+
+	go func() {
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+
+		failing := false
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case <-ticker.C:
+			}
+
+			data, err := json.Marshal(r.Collect())
+			if err == nil {
+				err = store.SetInstanceMetrics(ctx, r.instanceID, data, ttl)
+			}
+			if err != nil && !failing {
+				log.Printf("⚠️ Erro ao publicar métricas da instância %s: %v", r.instanceID, err)
+			} else if err == nil && failing {
+				log.Printf("✅ Publicação de métricas da instância %s restabelecida", r.instanceID)
+			}
+			failing = err != nil
+		}
+	}()
+}
+
+// Report combina a janela local (atual) com as publicadas pelas demais instâncias
+func (r *Registry) Report(ctx context.Context) Report {
+	local := r.Collect()
+	report := Report{
+		Instance:      r.instanceID,
+		WindowSeconds: local.WindowSeconds,
+		Local:         statsOf(local),
+		Instances:     []string{r.instanceID},
+	}
+
+	all := []Data{local}
+	if r.store != nil {
+		published, err := r.store.GetInstanceMetrics(ctx)
+		if err != nil {
+			report.ClusterError = err.Error()
+		}
+		for instanceID, raw := range published {
+			if instanceID == r.instanceID {
+				continue // A janela local é mais recente que a publicada
+			}
+			var data Data
+			if err := json.Unmarshal(raw, &data); err != nil {
+				log.Printf("⚠️ Métricas inválidas da instância %s: %v", instanceID, err)
+				continue
+			}
+			all = append(all, data)
+			report.Instances = append(report.Instances, instanceID)
+		}
+	}
+
+	sort.Strings(report.Instances)
+	report.Cluster = statsOf(Merge(all...))
+	return report
+}
@@ -0,0 +1,158 @@
+package metrics
+
+import (
+	"math"
+	"sort"
+	"sync"
+	"time"
+)
+
+// latencyBounds são os limites superiores (em microssegundos) dos buckets do histograma:
+// progressão geométrica de 100µs a ~60s, com erro de até 25% nos percentis. Os limites
+// são fixos para que histogramas de instâncias diferentes possam ser somados.
+var latencyBounds = func() []int64 {
+	var bounds []int64
+	for bound := 100.0; bound < float64(time.Minute/time.Microsecond); bound *= 1.25 {
+		bounds = append(bounds, int64(math.Round(bound)))
+	}
+	return bounds
+}()
+
+// counts acumula as observações de um intervalo; é somável entre intervalos e instâncias
+type counts struct {
+	total      int64
+	successes  int64
+	errors     [numClasses]int64
+	latencySum int64 // µs
+	latencyMax int64 // µs
+	histogram  []int64
+}
+
+func newCounts() counts {
+	return counts{histogram: make([]int64, len(latencyBounds)+1)}
+}
+
+func (c *counts) observe(latency time.Duration, class string) {
+	us := latency.Microseconds()
+	c.total++
+	if class == "" {
+		c.successes++
+	} else {
+		c.errors[classIndex(class)]++
+	}
+	c.latencySum += us
+	if us > c.latencyMax {
+		c.latencyMax = us
+	}
+	c.histogram[sort.Search(len(latencyBounds), func(i int) bool { return latencyBounds[i] >= us })]++
+}
+
+func (c *counts) add(other *counts) {
+	c.total += other.total
+	c.successes += other.successes
+	for i := range c.errors {
+		c.errors[i] += other.errors[i]
+	}
+	c.latencySum += other.latencySum
+	if other.latencyMax > c.latencyMax {
+		c.latencyMax = other.latencyMax
+	}
+	for i := range c.histogram {
+		if i < len(other.histogram) {
+			c.histogram[i] += other.histogram[i]
+		}
+	}
+}
+
+func (c *counts) reset() {
+	histogram := c.histogram
+	for i := range histogram {
+		histogram[i] = 0
+	}
+	*c = counts{histogram: histogram}
+}
+
+// percentile retorna o limite superior do bucket que contém o percentil p, em µs
+func (c *counts) percentile(p float64) int64 {
+	if c.total == 0 {
+		return 0
+	}
+	rank := int64(math.Ceil(p * float64(c.total)))
+	var cumulative int64
+	for i, n := range c.histogram {
+		cumulative += n
+		if cumulative >= rank {
+			if i < len(latencyBounds) && latencyBounds[i] < c.latencyMax {
+				return latencyBounds[i]
+			}
+			return c.latencyMax
+		}
+	}
+	return c.latencyMax
+}
+
+// bucket guarda as observações de uma fatia da janela
+type bucket struct {
+	epoch    int64 // Índice da fatia (tempo / largura); identifica buckets antigos
+	payments counts
+	probes   counts
+}
+
+// window é uma janela deslizante em fatias: a observação vai para a fatia do instante
+// atual, e fatias mais antigas que a janela são ignoradas na coleta e reutilizadas
+type window struct {
+	mu      sync.Mutex
+	width   time.Duration
+	buckets []bucket
+}
+
+func newWindow(size time.Duration, slices int) *window {
+	w := &window{width: size / time.Duration(slices), buckets: make([]bucket, slices)}
+	for i := range w.buckets {
+		w.buckets[i].epoch = -1
+		w.buckets[i].payments = newCounts()
+		w.buckets[i].probes = newCounts()
+	}
+	return w
+}
+
+// current retorna a fatia do instante, zerando-a se ainda guardar uma volta anterior.
+// Chamado com o lock.
+func (w *window) current(now time.Time) *bucket {
+	epoch := now.UnixNano() / int64(w.width)
+	b := &w.buckets[epoch%int64(len(w.buckets))]
+	if b.epoch != epoch {
+		b.epoch = epoch
+		b.payments.reset()
+		b.probes.reset()
+	}
+	return b
+}
+
+func (w *window) observePayment(now time.Time, latency time.Duration, class string) {
+	w.mu.Lock()
+	w.current(now).payments.observe(latency, class)
+	w.mu.Unlock()
+}
+
+func (w *window) observeProbe(now time.Time, latency time.Duration, class string) {
+	w.mu.Lock()
+	w.current(now).probes.observe(latency, class)
+	w.mu.Unlock()
+}
+
+// collect soma as fatias que ainda estão dentro da janela
+func (w *window) collect(now time.Time) (payments, probes counts) {
+	payments, probes = newCounts(), newCounts()
+	oldest := now.UnixNano()/int64(w.width) - int64(len(w.buckets)) + 1
+
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	for i := range w.buckets {
+		if w.buckets[i].epoch >= oldest {
+			payments.add(&w.buckets[i].payments)
+			probes.add(&w.buckets[i].probes)
+		}
+	}
+	return payments, probes
+}
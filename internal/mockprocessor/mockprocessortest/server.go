@@ -0,0 +1,35 @@
+// Package mockprocessortest sobe payment processors simulados em
+// httptest.Server para testes da lógica de failover e reconciliação.
+package mockprocessortest
+
+import (
+	"net/http/httptest"
+
+	"rinha-de-backend-2025/internal/mockprocessor"
+)
+
+// Server é um processor simulado rodando em um httptest.Server
+type Server struct {
+	*httptest.Server
+	Processor *mockprocessor.Processor
+}
+
+// NewServer sobe um processor simulado com a configuração informada
+func NewServer(cfg mockprocessor.Config) *Server {
+	processor := mockprocessor.New(cfg)
+	return &Server{
+		Server:    httptest.NewServer(processor),
+		Processor: processor,
+	}
+}
+
+// NewPair sobe um par default/fallback com as taxas do processor oficial
+// e sem rate limit no service-health
+func NewPair() (defaultServer, fallbackServer *Server) {
+	defaultCfg := mockprocessor.DefaultConfig("default", 0.05)
+	defaultCfg.HealthRateLimit = 0
+	fallbackCfg := mockprocessor.DefaultConfig("fallback", 0.15)
+	fallbackCfg.HealthRateLimit = 0
+
+	return NewServer(defaultCfg), NewServer(fallbackCfg)
+}
@@ -0,0 +1,400 @@
+package mockprocessor
+
+import (
+	"encoding/json"
+	"log"
+	mathrand "math/rand"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Config define o comportamento de um payment processor simulado
+type Config struct {
+	Name            string        // Nome do processor (default/fallback), usado nos logs
+	Fee             float64       // Taxa por transação (ex: 0.05 = 5%)
+	Token           string        // Valor esperado no header X-Rinha-Token dos endpoints /admin
+	Delay           time.Duration // Latência injetada em cada POST /payments
+	Failing         bool          // Quando true, todo POST /payments retorna 500
+	FailureRate     float64       // Probabilidade (0..1) de falha aleatória quando não está failing
+	HealthRateLimit time.Duration // Intervalo mínimo entre chamadas ao service-health (429 se violado)
+}
+
+// DefaultConfig retorna a configuração equivalente ao processor oficial da Rinha
+func DefaultConfig(name string, fee float64) Config {
+	return Config{
+		Name:            name,
+		Fee:             fee,
+		Token:           "123",
+		HealthRateLimit: 5 * time.Second,
+	}
+}
+
+// PaymentRequest é o payload aceito pelo processor simulado
+type PaymentRequest struct {
+	CorrelationID string    `json:"correlationId"`
+	Amount        float64   `json:"amount"`
+	RequestedAt   time.Time `json:"requestedAt"`
+}
+
+// Summary é a resposta do endpoint GET /admin/payments-summary
+type Summary struct {
+	TotalRequests     int64   `json:"totalRequests"`
+	TotalAmount       float64 `json:"totalAmount"`
+	TotalFee          float64 `json:"totalFee"`
+	FeePerTransaction float64 `json:"feePerTransaction"`
+}
+
+// HealthStatus é a resposta do endpoint GET /payments/service-health
+type HealthStatus struct {
+	Failing         bool `json:"failing"`
+	MinResponseTime int  `json:"minResponseTime"`
+}
+
+type storedPayment struct {
+	amount      float64
+	fee         float64
+	requestedAt time.Time
+}
+
+// Processor implementa a API do payment processor da Rinha em memória
+type Processor struct {
+	mu           sync.Mutex
+	cfg          Config
+	payments     map[string]storedPayment
+	lastHealthAt time.Time
+	rng          *mathrand.Rand
+	mux          *http.ServeMux
+}
+
+// New cria um novo processor simulado
+func New(cfg Config) *Processor {
+	if cfg.Token == "" {
+		cfg.Token = "123"
+	}
+
+	p := &Processor{
+		cfg:      cfg,
+		payments: make(map[string]storedPayment),
+		rng:      mathrand.New(mathrand.NewSource(time.Now().UnixNano())),
+		mux:      http.NewServeMux(),
+	}
+
+	p.mux.HandleFunc("/payments", p.handlePayments)
+	p.mux.HandleFunc("/payments/service-health", p.handleServiceHealth)
+	p.mux.HandleFunc("/payments/", p.handlePaymentDetails)
+	p.mux.HandleFunc("/admin/payments-summary", p.requireToken(p.handleSummary))
+	p.mux.HandleFunc("/admin/purge-payments", p.requireToken(p.handlePurge))
+	p.mux.HandleFunc("/admin/configurations/token", p.requireToken(p.handleSetToken))
+	p.mux.HandleFunc("/admin/configurations/delay", p.requireToken(p.handleSetDelay))
+	p.mux.HandleFunc("/admin/configurations/failure", p.requireToken(p.handleSetFailure))
+	p.mux.HandleFunc("/admin/configurations/failure-rate", p.requireToken(p.handleSetFailureRate))
+	p.mux.HandleFunc("/admin/configurations/fee", p.requireToken(p.handleSetFee))
+
+	return p
+}
+
+// ServeHTTP implementa http.Handler
+func (p *Processor) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	p.mux.ServeHTTP(w, r)
+}
+
+// SetFailing liga ou desliga o modo de falha total
+func (p *Processor) SetFailing(failing bool) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.cfg.Failing = failing
+}
+
+// SetFailureRate define a probabilidade de falha aleatória
+func (p *Processor) SetFailureRate(rate float64) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.cfg.FailureRate = rate
+}
+
+// SetDelay define a latência injetada em cada pagamento
+func (p *Processor) SetDelay(delay time.Duration) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.cfg.Delay = delay
+}
+
+// SetFee define a taxa por transação
+func (p *Processor) SetFee(fee float64) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.cfg.Fee = fee
+}
+
+// Summary retorna o resumo de pagamentos aceitos com requestedAt dentro do período.
+// Períodos com from ou to zerados são considerados abertos.
+func (p *Processor) Summary(from, to time.Time) Summary {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	summary := Summary{FeePerTransaction: p.cfg.Fee}
+	for _, payment := range p.payments {
+		if !from.IsZero() && payment.requestedAt.Before(from) {
+			continue
+		}
+		if !to.IsZero() && payment.requestedAt.After(to) {
+			continue
+		}
+		summary.TotalRequests++
+		summary.TotalAmount += payment.amount
+		summary.TotalFee += payment.fee
+	}
+	return summary
+}
+
+// Purge remove todos os pagamentos registrados
+func (p *Processor) Purge() {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.payments = make(map[string]storedPayment)
+}
+
+func (p *Processor) handlePayments(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		http.Error(w, "Método não permitido", http.StatusMethodNotAllowed)
+		return
+	}
+
+	var req PaymentRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil || req.CorrelationID == "" || req.Amount <= 0 {
+		writeJSON(w, http.StatusUnprocessableEntity, map[string]string{"message": "invalid payment"})
+		return
+	}
+
+	p.mu.Lock()
+	delay := p.cfg.Delay
+	fail := p.cfg.Failing || (p.cfg.FailureRate > 0 && p.rng.Float64() < p.cfg.FailureRate)
+	p.mu.Unlock()
+
+	if delay > 0 {
+		select {
+		case <-time.After(delay):
+		case <-r.Context().Done():
+			return
+		}
+	}
+
+	if fail {
+		writeJSON(w, http.StatusInternalServerError, map[string]string{"message": "payment processor failure"})
+		return
+	}
+
+	if req.RequestedAt.IsZero() {
+		req.RequestedAt = time.Now().UTC()
+	}
+
+	p.mu.Lock()
+	if _, exists := p.payments[req.CorrelationID]; exists {
+		p.mu.Unlock()
+		writeJSON(w, http.StatusUnprocessableEntity, map[string]string{"message": "correlationId already exists"})
+		return
+	}
+	fee := req.Amount * p.cfg.Fee
+	p.payments[req.CorrelationID] = storedPayment{
+		amount:      req.Amount,
+		fee:         fee,
+		requestedAt: req.RequestedAt,
+	}
+	p.mu.Unlock()
+
+	// Mesma resposta do processor oficial: só "message", sem id, status ou fee
+	writeJSON(w, http.StatusOK, map[string]string{"message": "payment processed successfully"})
+}
+
+// handlePaymentDetails responde GET /payments/{id} (id = correlationId), como o processor oficial
+func (p *Processor) handlePaymentDetails(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		http.Error(w, "Método não permitido", http.StatusMethodNotAllowed)
+		return
+	}
+
+	correlationID := strings.TrimPrefix(r.URL.Path, "/payments/")
+	p.mu.Lock()
+	payment, exists := p.payments[correlationID]
+	p.mu.Unlock()
+
+	if !exists {
+		writeJSON(w, http.StatusNotFound, map[string]string{"message": "payment not found"})
+		return
+	}
+	writeJSON(w, http.StatusOK, map[string]interface{}{
+		"correlationId": correlationID,
+		"amount":        payment.amount,
+		"requestedAt":   payment.requestedAt.UTC().Format(time.RFC3339Nano),
+	})
+}
+
+func (p *Processor) handleServiceHealth(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		http.Error(w, "Método não permitido", http.StatusMethodNotAllowed)
+		return
+	}
+
+	p.mu.Lock()
+	now := time.Now()
+	if p.cfg.HealthRateLimit > 0 && !p.lastHealthAt.IsZero() && now.Sub(p.lastHealthAt) < p.cfg.HealthRateLimit {
+		p.mu.Unlock()
+		writeJSON(w, http.StatusTooManyRequests, map[string]string{"message": "too many requests"})
+		return
+	}
+	p.lastHealthAt = now
+	status := HealthStatus{
+		Failing:         p.cfg.Failing,
+		MinResponseTime: int(p.cfg.Delay.Milliseconds()),
+	}
+	p.mu.Unlock()
+
+	writeJSON(w, http.StatusOK, status)
+}
+
+func (p *Processor) handleSummary(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		http.Error(w, "Método não permitido", http.StatusMethodNotAllowed)
+		return
+	}
+
+	var from, to time.Time
+	var err error
+	if fromStr := r.URL.Query().Get("from"); fromStr != "" {
+		if from, err = time.Parse(time.RFC3339, fromStr); err != nil {
+			http.Error(w, "Formato inválido para 'from'", http.StatusBadRequest)
+			return
+		}
+	}
+	if toStr := r.URL.Query().Get("to"); toStr != "" {
+		if to, err = time.Parse(time.RFC3339, toStr); err != nil {
+			http.Error(w, "Formato inválido para 'to'", http.StatusBadRequest)
+			return
+		}
+	}
+
+	writeJSON(w, http.StatusOK, p.Summary(from, to))
+}
+
+func (p *Processor) handlePurge(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		http.Error(w, "Método não permitido", http.StatusMethodNotAllowed)
+		return
+	}
+
+	p.Purge()
+	log.Printf("🗑️ [%s] Pagamentos removidos", p.cfg.Name)
+	writeJSON(w, http.StatusOK, map[string]string{"message": "All payments purged."})
+}
+
+func (p *Processor) handleSetToken(w http.ResponseWriter, r *http.Request) {
+	var body struct {
+		Token string `json:"token"`
+	}
+	if !decodeConfiguration(w, r, &body) {
+		return
+	}
+	if body.Token == "" {
+		http.Error(w, "token é obrigatório", http.StatusBadRequest)
+		return
+	}
+
+	p.mu.Lock()
+	p.cfg.Token = body.Token
+	p.mu.Unlock()
+	w.WriteHeader(http.StatusNoContent)
+}
+
+func (p *Processor) handleSetDelay(w http.ResponseWriter, r *http.Request) {
+	var body struct {
+		Delay int `json:"delay"`
+	}
+	if !decodeConfiguration(w, r, &body) {
+		return
+	}
+
+	p.SetDelay(time.Duration(body.Delay) * time.Millisecond)
+	log.Printf("⏱️ [%s] Delay configurado: %dms", p.cfg.Name, body.Delay)
+	w.WriteHeader(http.StatusNoContent)
+}
+
+func (p *Processor) handleSetFailure(w http.ResponseWriter, r *http.Request) {
+	var body struct {
+		Failure bool `json:"failure"`
+	}
+	if !decodeConfiguration(w, r, &body) {
+		return
+	}
+
+	p.SetFailing(body.Failure)
+	log.Printf("💥 [%s] Modo de falha: %t", p.cfg.Name, body.Failure)
+	w.WriteHeader(http.StatusNoContent)
+}
+
+func (p *Processor) handleSetFailureRate(w http.ResponseWriter, r *http.Request) {
+	var body struct {
+		FailureRate float64 `json:"failureRate"`
+	}
+	if !decodeConfiguration(w, r, &body) {
+		return
+	}
+	if body.FailureRate < 0 || body.FailureRate > 1 {
+		http.Error(w, "failureRate deve estar entre 0 e 1", http.StatusBadRequest)
+		return
+	}
+
+	p.SetFailureRate(body.FailureRate)
+	w.WriteHeader(http.StatusNoContent)
+}
+
+func (p *Processor) handleSetFee(w http.ResponseWriter, r *http.Request) {
+	var body struct {
+		Fee float64 `json:"fee"`
+	}
+	if !decodeConfiguration(w, r, &body) {
+		return
+	}
+	if body.Fee < 0 {
+		http.Error(w, "fee não pode ser negativa", http.StatusBadRequest)
+		return
+	}
+
+	p.SetFee(body.Fee)
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// requireToken protege os endpoints administrativos com o header X-Rinha-Token
+func (p *Processor) requireToken(next http.HandlerFunc) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		p.mu.Lock()
+		token := p.cfg.Token
+		p.mu.Unlock()
+
+		if r.Header.Get("X-Rinha-Token") != token {
+			http.Error(w, "token inválido", http.StatusUnauthorized)
+			return
+		}
+		next(w, r)
+	}
+}
+
+// decodeConfiguration valida o método PUT e decodifica o corpo dos endpoints de configuração
+func decodeConfiguration(w http.ResponseWriter, r *http.Request, body interface{}) bool {
+	if r.Method != http.MethodPut {
+		http.Error(w, "Método não permitido", http.StatusMethodNotAllowed)
+		return false
+	}
+	if err := json.NewDecoder(r.Body).Decode(body); err != nil {
+		http.Error(w, "JSON inválido", http.StatusBadRequest)
+		return false
+	}
+	return true
+}
+
+func writeJSON(w http.ResponseWriter, status int, body interface{}) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	json.NewEncoder(w).Encode(body)
+}
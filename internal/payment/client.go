@@ -2,70 +2,133 @@ package payment
 
 import (
 	"bytes"
+	"context"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"io"
 	"net/http"
+	neturl "net/url"
+	"strings"
 	"time"
+
+	"rinha-de-backend-2025/internal/httpclient"
 )
 
 type Client struct {
-	httpClient  *http.Client
-	defaultURL  string
-	fallbackURL string
+	httpClient     *http.Client
+	defaultURL     string
+	fallbackURL    string
+	paymentTimeout time.Duration
+	healthTimeout  time.Duration
 }
 
 // PaymentRequest representa o payload da Rinha de Backend 2025
 type PaymentRequest struct {
-	CorrelationID string  `json:"correlationId"`
-	Amount        float64 `json:"amount"`
+	CorrelationID string    `json:"correlationId"`
+	Amount        float64   `json:"amount"`
+	RequestedAt   time.Time `json:"requestedAt"` // Gerado uma vez por pagamento (NewRequestedAt)
+}
+
+// NewRequestedAt gera o requestedAt com a precisão que o processor grava (milissegundos)
+func NewRequestedAt() time.Time {
+	return time.Now().UTC().Truncate(time.Millisecond)
 }
 
-// PaymentResponse representa a resposta do Payment Processor
+// PaymentResponse representa a resposta do Payment Processor: o processor oficial
+// devolve só "message" (sem id, status ou fee)
 type PaymentResponse struct {
-	ID            string  `json:"id"`
-	CorrelationID string  `json:"correlationId"`
-	Status        string  `json:"status"`
-	Amount        float64 `json:"amount"`
-	Fee           float64 `json:"fee,omitempty"`
-	ProcessedAt   string  `json:"processedAt"`
+	Message string `json:"message"`
+}
+
+// StatusError é retornado quando o processor responde com status diferente de 200
+type StatusError struct {
+	StatusCode int
+	Body       string
+}
+
+func (e *StatusError) Error() string {
+	if e.StatusCode >= 500 {
+		return fmt.Sprintf("erro do servidor: status %d", e.StatusCode)
+	}
+	return fmt.Sprintf("erro na resposta: status %d, body: %s", e.StatusCode, e.Body)
+}
+
+// TransportError é retornado quando o request não chegou a ter resposta do processor
+// (timeout, conexão recusada ou interrompida, cancelamento)
+type TransportError struct {
+	Err error
+}
+
+func (e *TransportError) Error() string {
+	return fmt.Sprintf("erro na requisição HTTP: %v", e.Err)
 }
 
-func NewClient(defaultURL, fallbackURL string) *Client {
+func (e *TransportError) Unwrap() error {
+	return e.Err
+}
+
+// IsOverload indica se o erro sinaliza sobrecarga ou falha do processor (5xx, 429,
+// timeout ou erro de transporte), em oposição a uma rejeição do pagamento (4xx)
+func IsOverload(err error) bool {
+	if err == nil {
+		return false
+	}
+	var statusErr *StatusError
+	if errors.As(err, &statusErr) {
+		return statusErr.StatusCode >= 500 || statusErr.StatusCode == http.StatusTooManyRequests
+	}
+	return true
+}
+
+// IsDuplicate indica que o processor recusou o pagamento porque já tem o correlationId
+// (422 "correlationId already exists"): ele aceitou esse pagamento em uma chamada anterior
+func IsDuplicate(err error) bool {
+	var statusErr *StatusError
+	return errors.As(err, &statusErr) &&
+		statusErr.StatusCode == http.StatusUnprocessableEntity &&
+		strings.Contains(strings.ToLower(statusErr.Body), "already exists")
+}
+
+// NewClient cria o cliente de pagamentos sobre o http.Client compartilhado (httpclient.New)
+func NewClient(defaultURL, fallbackURL string, httpClient *http.Client, cfg httpclient.Config) *Client {
 	return &Client{
-		httpClient: &http.Client{
-			Timeout: 30 * time.Second,
-		},
-		defaultURL:  defaultURL,
-		fallbackURL: fallbackURL,
+		httpClient:     httpClient,
+		defaultURL:     defaultURL,
+		fallbackURL:    fallbackURL,
+		paymentTimeout: cfg.PaymentTimeout,
+		healthTimeout:  cfg.HealthCheckTimeout,
 	}
 }
 
 // ProcessPayment usa a lógica original de failover automático
-func (c *Client) ProcessPayment(req PaymentRequest) (*PaymentResponse, error) {
+func (c *Client) ProcessPayment(ctx context.Context, req PaymentRequest) (*PaymentResponse, error) {
 	// Tentar primeiro com o serviço default
-	resp, err := c.sendPaymentRequest(c.defaultURL, req)
+	resp, err := c.sendPaymentRequest(ctx, c.defaultURL, req)
 	if err == nil {
 		return resp, nil
 	}
 
 	// Se falhar, tentar com o fallback
 	fmt.Printf("Erro no serviço default: %v. Tentando fallback...\n", err)
-	return c.sendPaymentRequest(c.fallbackURL, req)
+	return c.sendPaymentRequest(ctx, c.fallbackURL, req)
 }
 
 // ProcessPaymentWithURL permite especificar o URL do processor - usado pelo UseCase
-func (c *Client) ProcessPaymentWithURL(url string, req PaymentRequest) (*PaymentResponse, error) {
-	return c.sendPaymentRequest(url, req)
+func (c *Client) ProcessPaymentWithURL(ctx context.Context, url string, req PaymentRequest) (*PaymentResponse, error) {
+	return c.sendPaymentRequest(ctx, url, req)
 }
 
-func (c *Client) sendPaymentRequest(url string, req PaymentRequest) (*PaymentResponse, error) {
-	jsonData, err := json.Marshal(req)
-	if err != nil {
-		return nil, fmt.Errorf("erro ao serializar request: %v", err)
-	}
+// sendPaymentRequest respeita o deadline do chamador, limitado a PAYMENT_TIMEOUT
+func (c *Client) sendPaymentRequest(ctx context.Context, url string, req PaymentRequest) (*PaymentResponse, error) {
+	ctx, cancel := context.WithTimeout(ctx, c.paymentTimeout)
+	defer cancel()
 
-	httpReq, err := http.NewRequest("POST", url+"/payments", bytes.NewBuffer(jsonData))
+	// O corpo do request não sai do pool: o transport pode lê-lo ou fechá-lo em outra
+	// goroutine mesmo depois que Do retorna, e o buffer não teria um momento seguro de volta
+	body := AppendRequest(make([]byte, 0, 128), req)
+
+	httpReq, err := http.NewRequestWithContext(ctx, "POST", url+"/payments", bytes.NewReader(body))
 	if err != nil {
 		return nil, fmt.Errorf("erro ao criar request: %v", err)
 	}
@@ -74,41 +137,80 @@ func (c *Client) sendPaymentRequest(url string, req PaymentRequest) (*PaymentRes
 
 	resp, err := c.httpClient.Do(httpReq)
 	if err != nil {
-		return nil, fmt.Errorf("erro na requisição HTTP: %v", err)
+		return nil, &TransportError{Err: err}
 	}
 	defer resp.Body.Close()
 
+	respBuf := httpclient.GetBuffer()
+	defer httpclient.PutBuffer(respBuf)
+
 	if resp.StatusCode >= 500 {
-		return nil, fmt.Errorf("erro do servidor: status %d", resp.StatusCode)
+		io.Copy(io.Discard, resp.Body)
+		return nil, &StatusError{StatusCode: resp.StatusCode}
 	}
 
-	body, err := io.ReadAll(resp.Body)
-	if err != nil {
+	if _, err := respBuf.ReadFrom(resp.Body); err != nil {
 		return nil, fmt.Errorf("erro ao ler resposta: %v", err)
 	}
 
 	if resp.StatusCode != http.StatusOK {
-		return nil, fmt.Errorf("erro na resposta: status %d, body: %s", resp.StatusCode, string(body))
+		return nil, &StatusError{StatusCode: resp.StatusCode, Body: respBuf.String()}
 	}
 
 	var paymentResp PaymentResponse
-	if err := json.Unmarshal(body, &paymentResp); err != nil {
+	if err := json.Unmarshal(respBuf.Bytes(), &paymentResp); err != nil {
 		return nil, fmt.Errorf("erro ao deserializar resposta: %v", err)
 	}
 
 	return &paymentResp, nil
 }
 
-func (c *Client) HealthCheck(url string) error {
-	resp, err := c.httpClient.Get(url + "/payments/service-health")
+// FindPayment consulta GET /payments/{id} do processor. Retorna false quando o
+// processor não conhece o correlationId (404).
+func (c *Client) FindPayment(ctx context.Context, url, correlationID string) (bool, error) {
+	ctx, cancel := context.WithTimeout(ctx, c.paymentTimeout)
+	defer cancel()
+
+	httpReq, err := http.NewRequestWithContext(ctx, http.MethodGet, url+"/payments/"+neturl.PathEscape(correlationID), nil)
+	if err != nil {
+		return false, fmt.Errorf("erro ao criar request: %v", err)
+	}
+
+	resp, err := c.httpClient.Do(httpReq)
+	if err != nil {
+		return false, &TransportError{Err: err}
+	}
+	defer resp.Body.Close()
+	io.Copy(io.Discard, resp.Body)
+
+	switch resp.StatusCode {
+	case http.StatusOK:
+		return true, nil
+	case http.StatusNotFound:
+		return false, nil
+	}
+	return false, &StatusError{StatusCode: resp.StatusCode}
+}
+
+func (c *Client) HealthCheck(ctx context.Context, url string) error {
+	ctx, cancel := context.WithTimeout(ctx, c.healthTimeout)
+	defer cancel()
+
+	httpReq, err := http.NewRequestWithContext(ctx, http.MethodGet, url+"/payments/service-health", nil)
+	if err != nil {
+		return err
+	}
+
+	resp, err := c.httpClient.Do(httpReq)
 	if err != nil {
 		return err
 	}
 	defer resp.Body.Close()
+	io.Copy(io.Discard, resp.Body)
 
 	if resp.StatusCode != http.StatusOK {
 		return fmt.Errorf("health check falhou: status %d", resp.StatusCode)
 	}
 
 	return nil
-} 
\ No newline at end of file
+}
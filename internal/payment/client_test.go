@@ -0,0 +1,50 @@
+package payment
+
+import (
+	"context"
+	"testing"
+
+	"rinha-de-backend-2025/internal/httpclient"
+	"rinha-de-backend-2025/internal/mockprocessor/mockprocessortest"
+)
+
+// TestRetryAfterLostAck cobre a retomada de um pagamento que o processor aceitou
+// antes do crash: a consulta o encontra e o reenvio é recusado como repetido
+func TestRetryAfterLostAck(t *testing.T) {
+	defaultServer, fallbackServer := mockprocessortest.NewPair()
+	defer defaultServer.Close()
+	defer fallbackServer.Close()
+
+	cfg := httpclient.DefaultConfig()
+	client := NewClient(defaultServer.URL, fallbackServer.URL, httpclient.New(cfg), cfg)
+	ctx := context.Background()
+	req := PaymentRequest{CorrelationID: "4a7901b8-7d26-4d9d-aa19-4dc1c7cf60b3", Amount: 19.9, RequestedAt: NewRequestedAt()}
+
+	if found, err := client.FindPayment(ctx, defaultServer.URL, req.CorrelationID); err != nil || found {
+		t.Fatalf("FindPayment antes do envio = %t, %v; want false, nil", found, err)
+	}
+	if _, err := client.ProcessPaymentWithURL(ctx, defaultServer.URL, req); err != nil {
+		t.Fatalf("ProcessPaymentWithURL: %v", err)
+	}
+
+	if found, err := client.FindPayment(ctx, defaultServer.URL, req.CorrelationID); err != nil || !found {
+		t.Fatalf("FindPayment no default = %t, %v; want true, nil", found, err)
+	}
+	if found, err := client.FindPayment(ctx, fallbackServer.URL, req.CorrelationID); err != nil || found {
+		t.Fatalf("FindPayment no fallback = %t, %v; want false, nil", found, err)
+	}
+
+	_, err := client.ProcessPaymentWithURL(ctx, defaultServer.URL, req)
+	if !IsDuplicate(err) {
+		t.Fatalf("reenvio = %v, want correlationId repetido", err)
+	}
+	if IsOverload(err) {
+		t.Fatalf("correlationId repetido tratado como sobrecarga: %v", err)
+	}
+
+	// Pagamento inválido também é 422, mas não é um aceite anterior
+	_, err = client.ProcessPaymentWithURL(ctx, defaultServer.URL, PaymentRequest{CorrelationID: "invalido"})
+	if err == nil || IsDuplicate(err) {
+		t.Fatalf("pagamento inválido = %v, want 422 que não é repetido", err)
+	}
+}
@@ -0,0 +1,251 @@
+package payment
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"strconv"
+	"time"
+	"unicode/utf8"
+)
+
+// Codec manual para o payload da Rinha ({"correlationId": "...", "amount": 19.90}).
+// O requestedAt só é escrito na chamada ao processor; no POST /payments ele é ignorado,
+// pois é gerado pela API.
+// Evita a reflexão do encoding/json no caminho quente do POST /payments; payloads
+// com formatos inesperados (escapes em strings, null, chaves com outra caixa) caem no
+// encoding/json, então o resultado é sempre o mesmo do json.Unmarshal.
+
+var errInvalidJSON = errors.New("JSON inválido")
+
+// requestedAtFormat é o formato ISO-8601 com milissegundos usado pelo processor
+const requestedAtFormat = "2006-01-02T15:04:05.000Z07:00"
+
+// AppendRequest serializa o request no fim de dst, sem alocar além do crescimento de dst
+func AppendRequest(dst []byte, req PaymentRequest) []byte {
+	dst = append(dst, `{"correlationId":`...)
+	dst = AppendString(dst, req.CorrelationID)
+	dst = append(dst, `,"amount":`...)
+	dst = strconv.AppendFloat(dst, req.Amount, 'f', -1, 64)
+	if !req.RequestedAt.IsZero() {
+		dst = append(dst, `,"requestedAt":"`...)
+		dst = req.RequestedAt.UTC().AppendFormat(dst, requestedAtFormat)
+		dst = append(dst, '"')
+	}
+	return append(dst, '}')
+}
+
+// DecodeRequest preenche req a partir do corpo JSON. Campos desconhecidos são ignorados.
+func DecodeRequest(data []byte, req *PaymentRequest) error {
+	d := decoder{data: data}
+	if !d.consume('{') {
+		return d.fallback(req)
+	}
+	if d.consume('}') {
+		return d.end()
+	}
+
+	for {
+		key, ok := d.rawString()
+		if !ok {
+			return d.fallback(req)
+		}
+		if !d.consume(':') {
+			return errInvalidJSON
+		}
+
+		switch string(key) { // a conversão na comparação não aloca
+		case "correlationId":
+			value, ok := d.rawString()
+			if !ok {
+				return d.fallback(req)
+			}
+			req.CorrelationID = string(value)
+		case "amount":
+			number := d.number()
+			amount, err := strconv.ParseFloat(string(number), 64)
+			if err != nil {
+				return d.fallback(req) // null, string ou fora do float64
+			}
+			req.Amount = amount
+		default:
+			// O encoding/json casa as chaves sem diferenciar maiúsculas
+			if bytes.EqualFold(key, []byte("amount")) || bytes.EqualFold(key, []byte("correlationId")) {
+				return d.fallback(req)
+			}
+			if !d.skipValue() {
+				return d.fallback(req)
+			}
+		}
+
+		if d.consume(',') {
+			continue
+		}
+		if d.consume('}') {
+			return d.end()
+		}
+		return errInvalidJSON
+	}
+}
+
+// AppendString escreve s como string JSON, escapando aspas, barras e caracteres de controle
+func AppendString(dst []byte, s string) []byte {
+	const hex = "0123456789abcdef"
+	dst = append(dst, '"')
+	for i := 0; i < len(s); i++ {
+		c := s[i]
+		switch {
+		case c == '"' || c == '\\':
+			dst = append(dst, '\\', c)
+		case c < 0x20:
+			dst = append(dst, '\\', 'u', '0', '0', hex[c>>4], hex[c&0xf])
+		default:
+			dst = append(dst, c)
+		}
+	}
+	return append(dst, '"')
+}
+
+type decoder struct {
+	data []byte
+	pos  int
+}
+
+func (d *decoder) skipSpaces() {
+	for d.pos < len(d.data) {
+		switch d.data[d.pos] {
+		case ' ', '\t', '\n', '\r':
+			d.pos++
+		default:
+			return
+		}
+	}
+}
+
+func (d *decoder) consume(c byte) bool {
+	d.skipSpaces()
+	if d.pos < len(d.data) && d.data[d.pos] == c {
+		d.pos++
+		return true
+	}
+	return false
+}
+
+func (d *decoder) end() error {
+	d.skipSpaces()
+	if d.pos != len(d.data) {
+		return errInvalidJSON
+	}
+	return nil
+}
+
+// rawString lê uma string sem escapes; retorna false se houver escapes ou UTF-8 inválido
+func (d *decoder) rawString() ([]byte, bool) {
+	if !d.consume('"') {
+		return nil, false
+	}
+	start := d.pos
+	for d.pos < len(d.data) {
+		switch c := d.data[d.pos]; {
+		case c == '"':
+			value := d.data[start:d.pos]
+			d.pos++
+			return value, utf8.Valid(value)
+		case c == '\\' || c < 0x20:
+			return nil, false
+		}
+		d.pos++
+	}
+	return nil, false
+}
+
+// number lê um número JSON; retorna nil se ele não seguir a gramática do JSON
+// (ex.: "01", "+1", ".5", "1."), que o strconv aceitaria
+func (d *decoder) number() []byte {
+	d.skipSpaces()
+	start := d.pos
+	for d.pos < len(d.data) {
+		c := d.data[d.pos]
+		if !(c >= '0' && c <= '9' || c == '-' || c == '+' || c == '.' || c == 'e' || c == 'E') {
+			break
+		}
+		d.pos++
+	}
+	if !validNumber(d.data[start:d.pos]) {
+		return nil
+	}
+	return d.data[start:d.pos]
+}
+
+// validNumber confere a gramática do RFC 8259: -?(0|[1-9][0-9]*)(\.[0-9]+)?([eE][+-]?[0-9]+)?
+func validNumber(s []byte) bool {
+	i := 0
+	digits := func() int {
+		start := i
+		for i < len(s) && s[i] >= '0' && s[i] <= '9' {
+			i++
+		}
+		return i - start
+	}
+
+	if i < len(s) && s[i] == '-' {
+		i++
+	}
+	if i < len(s) && s[i] == '0' {
+		i++
+	} else if i >= len(s) || s[i] < '1' || s[i] > '9' || digits() == 0 {
+		return false
+	}
+	if i < len(s) && s[i] == '.' {
+		i++
+		if digits() == 0 {
+			return false
+		}
+	}
+	if i < len(s) && (s[i] == 'e' || s[i] == 'E') {
+		i++
+		if i < len(s) && (s[i] == '+' || s[i] == '-') {
+			i++
+		}
+		if digits() == 0 {
+			return false
+		}
+	}
+	return i == len(s)
+}
+
+// skipValue pula valores escalares; objetos e arrays ficam para o encoding/json
+func (d *decoder) skipValue() bool {
+	d.skipSpaces()
+	if d.pos >= len(d.data) {
+		return false
+	}
+	switch d.data[d.pos] {
+	case '"':
+		_, ok := d.rawString()
+		return ok
+	case 't', 'f', 'n':
+		for _, literal := range [...]string{"true", "false", "null"} {
+			if len(d.data)-d.pos >= len(literal) && string(d.data[d.pos:d.pos+len(literal)]) == literal {
+				d.pos += len(literal)
+				return true
+			}
+		}
+		return false
+	default:
+		return len(d.number()) > 0
+	}
+}
+
+// fallback decodifica com o encoding/json quando o formato foge do caminho rápido
+func (d *decoder) fallback(req *PaymentRequest) error {
+	// Decodifica em uma variável local para que req não escape para o heap no caminho rápido
+	var decoded PaymentRequest
+	if err := json.Unmarshal(d.data, &decoded); err != nil {
+		*req = PaymentRequest{}
+		return errInvalidJSON
+	}
+	decoded.RequestedAt = time.Time{}
+	*req = decoded
+	return nil
+}
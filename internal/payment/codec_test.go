@@ -0,0 +1,219 @@
+package payment
+
+import (
+	"encoding/json"
+	"testing"
+	"time"
+)
+
+// decodeTests cobrem o caminho rápido e os desvios para o encoding/json
+var decodeTests = []struct {
+	name string
+	body string
+}{
+	// Válidos
+	{"payload da rinha", `{"correlationId":"4a7901b8-7d26-4d9d-aa19-4dc1c7cf60b3","amount":19.90}`},
+	{"espaços", " {\n\t\"correlationId\" : \"abc\" ,\r\n \"amount\" : 1 } \n"},
+	{"ordem invertida", `{"amount":19.9,"correlationId":"abc"}`},
+	{"objeto vazio", `{}`},
+	{"sem amount", `{"correlationId":"abc"}`},
+	{"sem correlationId", `{"amount":10}`},
+	{"requestedAt ignorado", `{"correlationId":"abc","amount":1,"requestedAt":"2025-07-15T12:34:56.000Z"}`},
+	{"campos extras escalares", `{"x":"y","n":-1.5e3,"t":true,"f":false,"z":null,"correlationId":"abc","amount":2}`},
+	{"campos extras aninhados", `{"meta":{"a":[1,2,{"b":null}]},"correlationId":"abc","amount":3}`},
+	{"chave repetida", `{"amount":1,"amount":2,"correlationId":"a","correlationId":"b"}`},
+	{"escape de aspas", `{"correlationId":"a\"b","amount":1}`},
+	{"escape unicode", `{"correlationId":"caf\u00e9 \ud83d\ude00","amount":1}`},
+	{"escapes de controle", `{"correlationId":"a\n\t\\\/b","amount":1}`},
+	{"unicode sem escape", `{"correlationId":"café ☕ 😀","amount":1}`},
+	{"chave com escape", `{"amo\u0075nt":7,"correlationId":"abc"}`},
+	{"chave com outra caixa", `{"Amount":7,"CORRELATIONID":"abc"}`},
+	{"amount inteiro", `{"amount":100}`},
+	{"amount zero", `{"amount":0}`},
+	{"amount negativo", `{"amount":-0.01}`},
+	{"amount com expoente", `{"amount":1.5E+2}`},
+	{"amount expoente negativo", `{"amount":25e-1}`},
+	{"amount muitas casas", `{"amount":0.1000000000000000055511151231257827}`},
+	{"amount null", `{"correlationId":"abc","amount":null}`},
+	{"correlationId null", `{"correlationId":null,"amount":1}`},
+	{"corpo null", `null`},
+
+	// Inválidos
+	{"vazio", ``},
+	{"só espaços", `   `},
+	{"objeto aberto", `{`},
+	{"array", `[]`},
+	{"string solta", `"abc"`},
+	{"lixo no fim", `{"amount":1}x`},
+	{"dois objetos", `{"amount":1}{"amount":2}`},
+	{"vírgula sobrando", `{"amount":1,}`},
+	{"sem dois pontos", `{"amount" 1}`},
+	{"sem valor", `{"amount":}`},
+	{"chave sem aspas", `{amount:1}`},
+	{"aspas simples", `{'amount':1}`},
+	{"string sem fechar", `{"correlationId":"abc,"amount":1}`},
+	{"surrogate sozinho", `{"correlationId":"\ud83d","amount":1}`},
+	{"escape inválido", `{"correlationId":"a\qb","amount":1}`},
+	{"controle sem escape", "{\"correlationId\":\"a\x01b\",\"amount\":1}"},
+	{"amount string", `{"amount":"19.90"}`},
+	{"amount com zero à esquerda", `{"amount":01}`},
+	{"amount com mais", `{"amount":+1}`},
+	{"amount começando com ponto", `{"amount":.5}`},
+	{"amount terminando com ponto", `{"amount":1.}`},
+	{"amount expoente vazio", `{"amount":1e}`},
+	{"amount só sinal", `{"amount":-}`},
+	{"amount hexadecimal", `{"amount":0x10}`},
+	{"amount NaN", `{"amount":NaN}`},
+	{"amount fora do float64", `{"amount":1e400}`},
+	{"correlationId número", `{"correlationId":123,"amount":1}`},
+	{"extra com número inválido", `{"x":01,"amount":1}`},
+	{"extra literal errado", `{"x":tru,"amount":1}`},
+}
+
+func TestDecodeRequestMatchesEncodingJSON(t *testing.T) {
+	for _, tt := range decodeTests {
+		t.Run(tt.name, func(t *testing.T) {
+			var want PaymentRequest
+			wantErr := json.Unmarshal([]byte(tt.body), &want)
+			want.RequestedAt = time.Time{} // Gerado pela API, nunca lido do corpo
+
+			var got PaymentRequest
+			gotErr := DecodeRequest([]byte(tt.body), &got)
+
+			if (gotErr != nil) != (wantErr != nil) {
+				t.Fatalf("DecodeRequest erro = %v, encoding/json erro = %v", gotErr, wantErr)
+			}
+			if wantErr == nil && got != want {
+				t.Fatalf("DecodeRequest = %+v, encoding/json = %+v", got, want)
+			}
+		})
+	}
+}
+
+func TestAppendStringMatchesEncodingJSON(t *testing.T) {
+	tests := []string{
+		"",
+		"4a7901b8-7d26-4d9d-aa19-4dc1c7cf60b3",
+		`aspas " e barra \ e barra normal /`,
+		"controle \x00 \x01 \b \f \n \r \t \x1f fim",
+		"café ☕ 😀 中文",
+		"html <script>&</script>",
+		"separadores \u2028 \u2029",
+		"del \x7f",
+	}
+
+	for _, s := range tests {
+		encoded := AppendString(nil, s)
+		if !json.Valid(encoded) {
+			t.Fatalf("AppendString(%q) = %s, JSON inválido", s, encoded)
+		}
+
+		var decoded string
+		if err := json.Unmarshal(encoded, &decoded); err != nil {
+			t.Fatalf("json.Unmarshal(%s): %v", encoded, err)
+		}
+		if decoded != s {
+			t.Fatalf("AppendString(%q) decodifica para %q", s, decoded)
+		}
+	}
+}
+
+func TestAppendRequestMatchesEncodingJSON(t *testing.T) {
+	requestedAt := time.Date(2025, 7, 15, 12, 34, 56, 789000000, time.FixedZone("BRT", -3*3600))
+	tests := []PaymentRequest{
+		{CorrelationID: "4a7901b8-7d26-4d9d-aa19-4dc1c7cf60b3", Amount: 19.9, RequestedAt: requestedAt},
+		{CorrelationID: "sem requestedAt", Amount: 19.9},
+		{CorrelationID: `escapes "\` + "\n", Amount: 0.1, RequestedAt: requestedAt},
+		{CorrelationID: "unicode ☕ 😀", Amount: 1e21, RequestedAt: requestedAt},
+		{CorrelationID: "", Amount: 0},
+		{CorrelationID: "negativo", Amount: -123.456},
+		{CorrelationID: "pequeno", Amount: 5e-324},
+	}
+
+	for _, req := range tests {
+		encoded := AppendRequest(nil, req)
+
+		var got, want map[string]interface{}
+		if err := json.Unmarshal(encoded, &got); err != nil {
+			t.Fatalf("AppendRequest(%+v) = %s, JSON inválido: %v", req, encoded, err)
+		}
+
+		// O processor recebe o requestedAt em UTC com milissegundos
+		expected := map[string]interface{}{"correlationId": req.CorrelationID, "amount": req.Amount}
+		if !req.RequestedAt.IsZero() {
+			expected["requestedAt"] = req.RequestedAt.UTC().Format(requestedAtFormat)
+		}
+		baseline, _ := json.Marshal(expected)
+		json.Unmarshal(baseline, &want)
+
+		if len(got) != len(want) {
+			t.Fatalf("AppendRequest = %s, encoding/json = %s", encoded, baseline)
+		}
+		for key, value := range want {
+			if got[key] != value {
+				t.Fatalf("AppendRequest %s = %v, encoding/json = %v (%s)", key, got[key], value, encoded)
+			}
+		}
+	}
+}
+
+var (
+	benchBody = []byte(`{"correlationId":"4a7901b8-7d26-4d9d-aa19-4dc1c7cf60b3","amount":19.90}`)
+	benchReq  = PaymentRequest{
+		CorrelationID: "4a7901b8-7d26-4d9d-aa19-4dc1c7cf60b3",
+		Amount:        19.9,
+		RequestedAt:   time.Date(2025, 7, 15, 12, 34, 56, 789000000, time.UTC),
+	}
+)
+
+func BenchmarkDecodeRequest(b *testing.B) {
+	b.ReportAllocs()
+	b.SetBytes(int64(len(benchBody)))
+	var req PaymentRequest
+	for i := 0; i < b.N; i++ {
+		if err := DecodeRequest(benchBody, &req); err != nil {
+			b.Fatal(err)
+		}
+	}
+}
+
+// Escapes na string desviam para o encoding/json
+func BenchmarkDecodeRequestFallback(b *testing.B) {
+	body := []byte(`{"correlationId":"\u0034a7901b8-7d26-4d9d-aa19-4dc1c7cf60b3","amount":19.90}`)
+	b.ReportAllocs()
+	b.SetBytes(int64(len(body)))
+	var req PaymentRequest
+	for i := 0; i < b.N; i++ {
+		if err := DecodeRequest(body, &req); err != nil {
+			b.Fatal(err)
+		}
+	}
+}
+
+func BenchmarkDecodeRequestEncodingJSON(b *testing.B) {
+	b.ReportAllocs()
+	b.SetBytes(int64(len(benchBody)))
+	var req PaymentRequest
+	for i := 0; i < b.N; i++ {
+		if err := json.Unmarshal(benchBody, &req); err != nil {
+			b.Fatal(err)
+		}
+	}
+}
+
+func BenchmarkAppendRequest(b *testing.B) {
+	b.ReportAllocs()
+	buf := make([]byte, 0, 256)
+	for i := 0; i < b.N; i++ {
+		buf = AppendRequest(buf[:0], benchReq)
+	}
+}
+
+func BenchmarkAppendRequestEncodingJSON(b *testing.B) {
+	b.ReportAllocs()
+	for i := 0; i < b.N; i++ {
+		if _, err := json.Marshal(benchReq); err != nil {
+			b.Fatal(err)
+		}
+	}
+}
@@ -0,0 +1,94 @@
+package peer
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"net/http"
+	"sync"
+	"time"
+)
+
+// LocalParam marca uma requisição repassada por um peer: a instância responde só
+// com o próprio ledger, sem repassá-la de novo
+const LocalParam = "local"
+
+// Forwarded indica se a requisição foi repassada por um peer
+func Forwarded(r *http.Request) bool {
+	return r.URL.Query().Get(LocalParam) == "true"
+}
+
+// Response é a resposta de um peer a uma requisição repassada
+type Response struct {
+	Peer       string
+	StatusCode int
+	Body       []byte
+}
+
+// Forwarder repete nos peers as operações que leem ou alteram o ledger em memória
+// (histórico, dead letters, purge), já que cada instância só enxerga o próprio
+type Forwarder struct {
+	peers   []string
+	client  *http.Client
+	timeout time.Duration
+}
+
+// NewForwarder cria o repasse de requisições aos peers
+func NewForwarder(peers []string, client *http.Client, timeout time.Duration) *Forwarder {
+	return &Forwarder{peers: peers, client: client, timeout: timeout}
+}
+
+// Forward repete a requisição (método, caminho, query e headers, com local=true) em todos
+// os peers em paralelo. Sem prazo no ctx, cada peer tem PEER_TIMEOUT para responder.
+// Qualquer status é devolvido ao chamador; só falhas de conexão viram erro.
+func (f *Forwarder) Forward(ctx context.Context, r *http.Request) ([]*Response, error) {
+	if _, ok := ctx.Deadline(); !ok {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, f.timeout)
+		defer cancel()
+	}
+
+	query := r.URL.Query()
+	query.Set(LocalParam, "true")
+	target := r.URL.Path + "?" + query.Encode()
+
+	responses := make([]*Response, len(f.peers))
+	errs := make([]error, len(f.peers))
+	var wg sync.WaitGroup
+	for i, peer := range f.peers {
+		wg.Add(1)
+		go func(i int, peer string) {
+			defer wg.Done()
+			responses[i], errs[i] = f.send(ctx, r, peer, target)
+		}(i, peer)
+	}
+	wg.Wait()
+
+	for i, err := range errs {
+		if err != nil {
+			return nil, fmt.Errorf("erro ao repassar %s ao peer %s: %v", r.URL.Path, f.peers[i], err)
+		}
+	}
+	return responses, nil
+}
+
+// send executa a requisição repassada em um peer
+func (f *Forwarder) send(ctx context.Context, r *http.Request, peer, target string) (*Response, error) {
+	req, err := http.NewRequestWithContext(ctx, r.Method, peer+target, nil)
+	if err != nil {
+		return nil, err
+	}
+	req.Header = r.Header.Clone()
+
+	resp, err := f.client.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	body, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return nil, fmt.Errorf("resposta incompleta: %v", err)
+	}
+	return &Response{Peer: peer, StatusCode: resp.StatusCode, Body: body}, nil
+}
@@ -0,0 +1,161 @@
+package peer
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strings"
+	"sync"
+	"time"
+
+	"rinha-de-backend-2025/internal/repository"
+)
+
+// SummaryPath é o endpoint interno que expõe apenas o ledger local da instância
+const SummaryPath = "/internal/payments-summary"
+
+// windowFormat preserva a precisão da janela ao repassá-la aos peers
+const windowFormat = time.RFC3339Nano
+
+// ParsePeers interpreta a lista separada por vírgula de PEERS ("http://api02:8080,...")
+func ParsePeers(value string) []string {
+	var peers []string
+	for _, peer := range strings.Split(value, ",") {
+		peer = strings.TrimRight(strings.TrimSpace(peer), "/")
+		if peer != "" {
+			peers = append(peers, peer)
+		}
+	}
+	return peers
+}
+
+// Repository envolve o ledger local e responde GetPaymentsSummary somando os
+// ledgers de todos os peers. As demais operações continuam locais.
+type Repository struct {
+	repository.PaymentRepository
+
+	peers   []string
+	client  *http.Client
+	timeout time.Duration
+}
+
+// NewRepository cria o repositório que agrega o resumo dos peers
+func NewRepository(local repository.PaymentRepository, peers []string, client *http.Client, timeout time.Duration) *Repository {
+	return &Repository{
+		PaymentRepository: local,
+		peers:             peers,
+		client:            client,
+		timeout:           timeout,
+	}
+}
+
+// GetPaymentsSummary consulta o ledger local e todos os peers em paralelo.
+// Se qualquer peer falhar, o resumo é recusado: um total parcial seria incorreto.
+func (r *Repository) GetPaymentsSummary(ctx context.Context, from, to time.Time) (*repository.PaymentSummary, error) {
+	ctx, cancel := context.WithTimeout(ctx, r.timeout)
+	defer cancel()
+
+	summaries := make([]*repository.PaymentSummary, len(r.peers)+1)
+	errs := make([]error, len(r.peers)+1)
+
+	var wg sync.WaitGroup
+	for i, peer := range r.peers {
+		wg.Add(1)
+		go func(i int, peer string) {
+			defer wg.Done()
+			summaries[i], errs[i] = r.fetch(ctx, peer, from, to)
+		}(i, peer)
+	}
+	local := len(r.peers)
+	summaries[local], errs[local] = r.PaymentRepository.GetPaymentsSummary(ctx, from, to)
+	wg.Wait()
+
+	for i, err := range errs {
+		if err == nil {
+			continue
+		}
+		if i == local {
+			return nil, err
+		}
+		return nil, fmt.Errorf("erro ao buscar resumo do peer %s: %v", r.peers[i], err)
+	}
+
+	return Merge(summaries...), nil
+}
+
+// fetch busca o resumo do ledger local de um peer
+func (r *Repository) fetch(ctx context.Context, peer string, from, to time.Time) (*repository.PaymentSummary, error) {
+	query := url.Values{}
+	query.Set("from", from.Format(windowFormat))
+	query.Set("to", to.Format(windowFormat))
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, peer+SummaryPath+"?"+query.Encode(), nil)
+	if err != nil {
+		return nil, err
+	}
+
+	resp, err := r.client.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		io.Copy(io.Discard, resp.Body)
+		return nil, fmt.Errorf("status %d", resp.StatusCode)
+	}
+
+	var summary repository.PaymentSummary
+	if err := json.NewDecoder(resp.Body).Decode(&summary); err != nil {
+		return nil, fmt.Errorf("resposta inválida: %v", err)
+	}
+	return &summary, nil
+}
+
+// Merge soma os totais por processor (em centavos, para não acumular erro de float)
+func Merge(summaries ...*repository.PaymentSummary) *repository.PaymentSummary {
+	var defaultCents, fallbackCents int64
+	merged := &repository.PaymentSummary{}
+	for _, summary := range summaries {
+		merged.Default.TotalRequests += summary.Default.TotalRequests
+		merged.Fallback.TotalRequests += summary.Fallback.TotalRequests
+		defaultCents += repository.ToCents(summary.Default.TotalAmount)
+		fallbackCents += repository.ToCents(summary.Fallback.TotalAmount)
+	}
+	merged.Default.TotalAmount = repository.FromCents(defaultCents)
+	merged.Fallback.TotalAmount = repository.FromCents(fallbackCents)
+	return merged
+}
+
+// SummaryHandler expõe o resumo do ledger local para os peers
+func SummaryHandler(local repository.PaymentRepository) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodGet {
+			http.Error(w, "Método não permitido", http.StatusMethodNotAllowed)
+			return
+		}
+
+		from, err := time.Parse(windowFormat, r.URL.Query().Get("from"))
+		if err != nil {
+			http.Error(w, "Parâmetro 'from' inválido", http.StatusBadRequest)
+			return
+		}
+		to, err := time.Parse(windowFormat, r.URL.Query().Get("to"))
+		if err != nil {
+			http.Error(w, "Parâmetro 'to' inválido", http.StatusBadRequest)
+			return
+		}
+
+		summary, err := local.GetPaymentsSummary(r.Context(), from, to)
+		if err != nil {
+			http.Error(w, fmt.Sprintf("Erro ao gerar resumo: %v", err), http.StatusInternalServerError)
+			return
+		}
+
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(summary)
+	}
+}
@@ -0,0 +1,61 @@
+package replay
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"time"
+)
+
+// Entry é uma requisição capturada, uma por linha do arquivo JSONL:
+//
+//	{"timestamp":"2025-07-10T12:34:56.123Z","method":"POST","path":"/payments",
+//	 "body":{"correlationId":"...","amount":19.9},"status":200}
+//
+// Para GET /payments-summary, "response" guarda o corpo recebido na captura.
+type Entry struct {
+	Line      int               `json:"-"`
+	Timestamp time.Time         `json:"timestamp"`
+	Method    string            `json:"method"`
+	Path      string            `json:"path"`
+	Headers   map[string]string `json:"headers,omitempty"`
+	Body      json.RawMessage   `json:"body,omitempty"`
+	Status    int               `json:"status"`
+	Response  json.RawMessage   `json:"response,omitempty"`
+}
+
+// ReadCaptures lê um arquivo JSONL de capturas
+func ReadCaptures(r io.Reader) ([]Entry, error) {
+	var entries []Entry
+
+	scanner := bufio.NewScanner(r)
+	scanner.Buffer(make([]byte, 64*1024), 1024*1024)
+	line := 0
+	for scanner.Scan() {
+		line++
+		data := bytes.TrimSpace(scanner.Bytes())
+		if len(data) == 0 {
+			continue
+		}
+
+		var entry Entry
+		if err := json.Unmarshal(data, &entry); err != nil {
+			return nil, fmt.Errorf("linha %d inválida: %v", line, err)
+		}
+		if entry.Method == "" || entry.Path == "" {
+			return nil, fmt.Errorf("linha %d sem method ou path", line)
+		}
+		if entry.Timestamp.IsZero() {
+			return nil, fmt.Errorf("linha %d sem timestamp", line)
+		}
+		entry.Line = line
+		entries = append(entries, entry)
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, fmt.Errorf("erro ao ler capturas: %v", err)
+	}
+
+	return entries, nil
+}
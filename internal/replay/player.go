@@ -0,0 +1,295 @@
+package replay
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+	"net/url"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"rinha-de-backend-2025/internal/repository"
+)
+
+// summaryTimeFormat é o formato usado ao reescrever a janela dos resumos
+const summaryTimeFormat = "2006-01-02T15:04:05.000Z07:00"
+
+// Config define como as capturas são reproduzidas
+type Config struct {
+	TargetURL   string  // URL da instância (ou load balancer)
+	Speed       float64 // 1 = tempo original, 2 = duas vezes mais rápido, 0 = sem espera
+	MaxInFlight int     // Máximo de requisições simultâneas
+}
+
+// Mismatch descreve uma requisição cuja resposta divergiu da captura
+type Mismatch struct {
+	Line           int      `json:"line"`
+	Method         string   `json:"method"`
+	Path           string   `json:"path"`
+	ExpectedStatus int      `json:"expected_status"`
+	ActualStatus   int      `json:"actual_status"`
+	Error          string   `json:"error,omitempty"`
+	Diffs          []string `json:"diffs,omitempty"`
+}
+
+// Report é o relatório de diferenças de uma reprodução
+type Report struct {
+	Started           time.Time  `json:"started"`
+	Finished          time.Time  `json:"finished"`
+	Total             int        `json:"total"`
+	Matched           int        `json:"matched"`
+	StatusMismatches  int        `json:"status_mismatches"`
+	SummaryMismatches int        `json:"summary_mismatches"`
+	Errors            int        `json:"errors"`
+	Mismatches        []Mismatch `json:"mismatches"`
+}
+
+// OK indica se a reprodução não encontrou nenhuma divergência
+func (r *Report) OK() bool {
+	return len(r.Mismatches) == 0
+}
+
+// Player reproduz capturas contra uma instância em execução
+type Player struct {
+	cfg    Config
+	client *http.Client
+
+	mu     sync.Mutex
+	report *Report
+}
+
+// NewPlayer cria um novo reprodutor de capturas
+func NewPlayer(cfg Config, client *http.Client) *Player {
+	if cfg.MaxInFlight <= 0 {
+		cfg.MaxInFlight = 500
+	}
+	return &Player{cfg: cfg, client: client}
+}
+
+// Play reproduz as capturas respeitando o tempo original escalado por Speed.
+// Consultas ao /payments-summary aguardam todas as requisições anteriores
+// terminarem e têm a janela from/to deslocada para o tempo da reprodução.
+func (p *Player) Play(ctx context.Context, entries []Entry) (*Report, error) {
+	if len(entries) == 0 {
+		return nil, fmt.Errorf("nenhuma captura para reproduzir")
+	}
+
+	sorted := make([]Entry, len(entries))
+	copy(sorted, entries)
+	sort.SliceStable(sorted, func(i, j int) bool {
+		return sorted[i].Timestamp.Before(sorted[j].Timestamp)
+	})
+
+	captureStart := sorted[0].Timestamp
+	replayStart := time.Now()
+	p.report = &Report{Started: replayStart.UTC()}
+
+	log.Printf("▶️ Reproduzindo %d requisições contra %s (velocidade %.2fx)", len(sorted), p.cfg.TargetURL, p.cfg.Speed)
+
+	var inFlight sync.WaitGroup
+	slots := make(chan struct{}, p.cfg.MaxInFlight)
+
+	for _, entry := range sorted {
+		if err := p.waitUntil(ctx, p.mapTime(entry.Timestamp, captureStart, replayStart)); err != nil {
+			break
+		}
+
+		if isSummary(entry) {
+			inFlight.Wait()
+			entry.Path = p.rewriteSummaryWindow(entry.Path, captureStart, replayStart)
+			p.execute(ctx, entry)
+			continue
+		}
+
+		select {
+		case slots <- struct{}{}:
+		case <-ctx.Done():
+		}
+		if ctx.Err() != nil {
+			break
+		}
+
+		inFlight.Add(1)
+		go func(entry Entry) {
+			defer inFlight.Done()
+			defer func() { <-slots }()
+			p.execute(ctx, entry)
+		}(entry)
+	}
+
+	inFlight.Wait()
+	p.report.Finished = time.Now().UTC()
+
+	sort.Slice(p.report.Mismatches, func(i, j int) bool {
+		return p.report.Mismatches[i].Line < p.report.Mismatches[j].Line
+	})
+
+	return p.report, ctx.Err()
+}
+
+// mapTime converte um instante da captura para o instante correspondente na reprodução
+func (p *Player) mapTime(t, captureStart, replayStart time.Time) time.Time {
+	offset := t.Sub(captureStart)
+	if p.cfg.Speed > 0 {
+		offset = time.Duration(float64(offset) / p.cfg.Speed)
+	} else if offset > 0 {
+		offset = 0
+	}
+	return replayStart.Add(offset)
+}
+
+func (p *Player) waitUntil(ctx context.Context, target time.Time) error {
+	wait := time.Until(target)
+	if wait <= 0 {
+		return ctx.Err()
+	}
+
+	timer := time.NewTimer(wait)
+	defer timer.Stop()
+	select {
+	case <-ctx.Done():
+		return ctx.Err()
+	case <-timer.C:
+		return nil
+	}
+}
+
+// rewriteSummaryWindow desloca os parâmetros from/to para o tempo da reprodução
+func (p *Player) rewriteSummaryWindow(path string, captureStart, replayStart time.Time) string {
+	parsed, err := url.Parse(path)
+	if err != nil {
+		return path
+	}
+
+	query := parsed.Query()
+	for _, param := range []string{"from", "to"} {
+		value := query.Get(param)
+		if value == "" {
+			continue
+		}
+		t, err := time.Parse(time.RFC3339, value)
+		if err != nil {
+			continue
+		}
+		mapped := p.mapTime(t, captureStart, replayStart)
+		if p.cfg.Speed <= 0 && param == "to" {
+			// Sem espera entre requisições, todo o tráfego cai antes do "agora"
+			mapped = time.Now()
+		}
+		query.Set(param, mapped.UTC().Format(summaryTimeFormat))
+	}
+	parsed.RawQuery = query.Encode()
+	return parsed.String()
+}
+
+// execute envia uma requisição capturada e registra as divergências
+func (p *Player) execute(ctx context.Context, entry Entry) {
+	var body io.Reader
+	if len(entry.Body) > 0 {
+		body = bytes.NewReader(entry.Body)
+	}
+
+	req, err := http.NewRequestWithContext(ctx, entry.Method, p.cfg.TargetURL+entry.Path, body)
+	if err != nil {
+		p.recordError(entry, err)
+		return
+	}
+	if body != nil {
+		req.Header.Set("Content-Type", "application/json")
+	}
+	for key, value := range entry.Headers {
+		req.Header.Set(key, value)
+	}
+
+	resp, err := p.client.Do(req)
+	if err != nil {
+		p.recordError(entry, err)
+		return
+	}
+	defer resp.Body.Close()
+
+	respBody, err := io.ReadAll(resp.Body)
+	if err != nil {
+		p.recordError(entry, err)
+		return
+	}
+
+	mismatch := Mismatch{
+		Line:           entry.Line,
+		Method:         entry.Method,
+		Path:           entry.Path,
+		ExpectedStatus: entry.Status,
+		ActualStatus:   resp.StatusCode,
+	}
+
+	statusMismatch := entry.Status != 0 && entry.Status != resp.StatusCode
+	if isSummary(entry) && len(entry.Response) > 0 && resp.StatusCode == http.StatusOK {
+		mismatch.Diffs = diffSummaries(entry.Response, respBody)
+	}
+
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.report.Total++
+	if statusMismatch {
+		p.report.StatusMismatches++
+	}
+	if len(mismatch.Diffs) > 0 {
+		p.report.SummaryMismatches++
+	}
+	if statusMismatch || len(mismatch.Diffs) > 0 {
+		p.report.Mismatches = append(p.report.Mismatches, mismatch)
+		return
+	}
+	p.report.Matched++
+}
+
+func (p *Player) recordError(entry Entry, err error) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.report.Total++
+	p.report.Errors++
+	p.report.Mismatches = append(p.report.Mismatches, Mismatch{
+		Line:           entry.Line,
+		Method:         entry.Method,
+		Path:           entry.Path,
+		ExpectedStatus: entry.Status,
+		Error:          err.Error(),
+	})
+}
+
+// diffSummaries compara o resumo capturado com o resumo obtido na reprodução
+func diffSummaries(expectedBody, actualBody []byte) []string {
+	var expected, actual repository.PaymentSummary
+	if err := json.Unmarshal(expectedBody, &expected); err != nil {
+		return []string{fmt.Sprintf("resumo capturado inválido: %v", err)}
+	}
+	if err := json.Unmarshal(actualBody, &actual); err != nil {
+		return []string{fmt.Sprintf("resumo obtido inválido: %v", err)}
+	}
+
+	var diffs []string
+	compare := func(name string, expected, actual repository.ProcessorSummary) {
+		if expected.TotalRequests != actual.TotalRequests {
+			diffs = append(diffs, fmt.Sprintf("%s.totalRequests: esperado %d, obtido %d",
+				name, expected.TotalRequests, actual.TotalRequests))
+		}
+		if diff := expected.TotalAmount - actual.TotalAmount; diff > 0.005 || diff < -0.005 {
+			diffs = append(diffs, fmt.Sprintf("%s.totalAmount: esperado %.2f, obtido %.2f",
+				name, expected.TotalAmount, actual.TotalAmount))
+		}
+	}
+	compare("default", expected.Default, actual.Default)
+	compare("fallback", expected.Fallback, actual.Fallback)
+
+	return diffs
+}
+
+func isSummary(entry Entry) bool {
+	return entry.Method == http.MethodGet && strings.HasPrefix(entry.Path, "/payments-summary")
+}
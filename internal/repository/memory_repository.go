@@ -0,0 +1,260 @@
+package repository
+
+import (
+	"context"
+	"fmt"
+	"math"
+	"sync"
+	"time"
+)
+
+// MemoryPaymentRepository guarda os pagamentos apenas na memória da instância.
+// Usado com STORAGE=memory: cada instância mantém o próprio ledger e o resumo
+// global é obtido somando os ledgers dos peers (ver internal/peer).
+type MemoryPaymentRepository struct {
+	mu              sync.RWMutex
+	payments        []*Payment
+	byPaymentID     map[string]*Payment
+	byCorrelationID map[string]*Payment
+	succeeded       map[string]*Payment // Primeiro pagamento com sucesso de cada correlation_id
+	nextID          int
+}
+
+// NewMemoryPaymentRepository cria um ledger em memória vazio
+func NewMemoryPaymentRepository() *MemoryPaymentRepository {
+	return &MemoryPaymentRepository{
+		byPaymentID:     make(map[string]*Payment),
+		byCorrelationID: make(map[string]*Payment),
+		succeeded:       make(map[string]*Payment),
+	}
+}
+
+// Save registra o pagamento no ledger (payment_id é único, como na tabela payments)
+func (r *MemoryPaymentRepository) Save(ctx context.Context, payment *Payment) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if _, exists := r.byPaymentID[payment.PaymentID]; exists {
+		return fmt.Errorf("%w: %s", ErrDuplicate, payment.PaymentID)
+	}
+
+	r.nextID++
+	payment.ID = r.nextID
+
+	stored := *payment
+	r.payments = append(r.payments, &stored)
+	r.byPaymentID[stored.PaymentID] = &stored
+	r.byCorrelationID[stored.CorrelationID] = &stored
+	if _, ok := r.succeeded[stored.CorrelationID]; !ok && stored.Status != "failed" {
+		r.succeeded[stored.CorrelationID] = &stored
+	}
+	return nil
+}
+
+// FindByID busca um pagamento pelo payment_id
+func (r *MemoryPaymentRepository) FindByID(ctx context.Context, paymentID string) (*Payment, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	payment, ok := r.byPaymentID[paymentID]
+	if !ok {
+		return nil, fmt.Errorf("%w: %s", ErrNotFound, paymentID)
+	}
+	found := *payment
+	return &found, nil
+}
+
+// FindByCorrelationID busca o último pagamento registrado para o correlation_id
+func (r *MemoryPaymentRepository) FindByCorrelationID(ctx context.Context, correlationID string) (*Payment, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	payment, ok := r.byCorrelationID[correlationID]
+	if !ok {
+		return nil, fmt.Errorf("%w para correlation_id: %s", ErrNotFound, correlationID)
+	}
+	found := *payment
+	return &found, nil
+}
+
+// FindSucceeded busca o pagamento com sucesso do correlation_id, ou nil se não houver
+func (r *MemoryPaymentRepository) FindSucceeded(ctx context.Context, correlationID string) (*Payment, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	payment, ok := r.succeeded[correlationID]
+	if !ok {
+		return nil, nil
+	}
+	found := *payment
+	return &found, nil
+}
+
+// FindAll retorna os pagamentos mais recentes primeiro
+func (r *MemoryPaymentRepository) FindAll(ctx context.Context, limit int) ([]*Payment, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	payments := make([]*Payment, 0, limit)
+	for i := len(r.payments) - 1; i >= 0 && len(payments) < limit; i-- {
+		found := *r.payments[i]
+		payments = append(payments, &found)
+	}
+	return payments, nil
+}
+
+// GetProcessorStats retorna as estatísticas por processor com requestedAt no período
+func (r *MemoryPaymentRepository) GetProcessorStats(ctx context.Context, from, to time.Time) (map[string]*ProcessorStats, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	// Valores somados em centavos, como no resumo
+	type totals struct {
+		amountCents, failedCents, feeCents, noFeeCents int64
+		latencySum                         float64
+		latencyCount                       int64
+	}
+	stats := make(map[string]*ProcessorStats)
+	sums := make(map[string]*totals)
+	for _, payment := range r.payments {
+		requestedAt := payment.RequestedAt
+		if requestedAt.IsZero() {
+			requestedAt = payment.CreatedAt
+		}
+		if requestedAt.Before(from) || requestedAt.After(to) {
+			continue
+		}
+
+		processorStats, ok := stats[payment.PaymentProcessor]
+		if !ok {
+			processorStats = &ProcessorStats{}
+			stats[payment.PaymentProcessor] = processorStats
+			sums[payment.PaymentProcessor] = &totals{}
+		}
+		sum := sums[payment.PaymentProcessor]
+
+		processorStats.Total++
+		if payment.Status == "failed" {
+			processorStats.Failed++
+			sum.failedCents += ToCents(payment.Amount)
+		} else {
+			processorStats.Succeeded++
+			sum.amountCents += ToCents(payment.Amount)
+			sum.feeCents += ToCents(payment.Fee)
+			if ToCents(payment.Fee) == 0 {
+				sum.noFeeCents += ToCents(payment.Amount)
+			}
+		}
+		if payment.LatencyMs > 0 {
+			sum.latencySum += payment.LatencyMs
+			sum.latencyCount++
+		}
+		if payment.Failover {
+			processorStats.Failovers++
+		}
+	}
+
+	for processor, processorStats := range stats {
+		sum := sums[processor]
+		processorStats.TotalAmount = FromCents(sum.amountCents)
+		processorStats.FailedAmount = FromCents(sum.failedCents)
+		processorStats.TotalFees = FromCents(sum.feeCents)
+		processorStats.AmountWithoutFee = FromCents(sum.noFeeCents)
+		if sum.latencyCount > 0 {
+			processorStats.AvgLatencyMs = sum.latencySum / float64(sum.latencyCount)
+		}
+	}
+	return stats, nil
+}
+
+// GetPaymentsSummary soma os pagamentos bem-sucedidos do ledger local com requestedAt no período
+func (r *MemoryPaymentRepository) GetPaymentsSummary(ctx context.Context, from, to time.Time) (*PaymentSummary, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	// Soma em centavos, como o DECIMAL(10,2) do Postgres, para não acumular erro de float
+	var defaultCents, fallbackCents int64
+	summary := &PaymentSummary{}
+	for _, payment := range r.payments {
+		// Registros sem requestedAt (anteriores à coluna) usam created_at, como no Postgres
+		requestedAt := payment.RequestedAt
+		if requestedAt.IsZero() {
+			requestedAt = payment.CreatedAt
+		}
+		if payment.Status == "failed" || requestedAt.Before(from) || requestedAt.After(to) {
+			continue
+		}
+		switch payment.PaymentProcessor {
+		case "default":
+			summary.Default.TotalRequests++
+			defaultCents += ToCents(payment.Amount)
+		case "fallback":
+			summary.Fallback.TotalRequests++
+			fallbackCents += ToCents(payment.Amount)
+		}
+	}
+	summary.Default.TotalAmount = FromCents(defaultCents)
+	summary.Fallback.TotalAmount = FromCents(fallbackCents)
+	return summary, nil
+}
+
+// ToCents converte um valor monetário para centavos, arredondando
+func ToCents(amount float64) int64 {
+	return int64(math.Round(amount * 100))
+}
+
+// FromCents converte centavos de volta para o valor monetário
+func FromCents(cents int64) float64 {
+	return float64(cents) / 100
+}
+
+// FindFailed retorna as dead letters: tentativas com falha ainda não resolvidas, mais antigas primeiro
+func (r *MemoryPaymentRepository) FindFailed(ctx context.Context, limit int) ([]*Payment, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	payments := make([]*Payment, 0)
+	for _, payment := range r.payments {
+		if len(payments) >= limit {
+			break
+		}
+		if payment.Status == "failed" && payment.ResolvedAt == nil {
+			found := *payment
+			payments = append(payments, &found)
+		}
+	}
+	return payments, nil
+}
+
+// MarkResolved marca uma dead letter como resolvida
+func (r *MemoryPaymentRepository) MarkResolved(ctx context.Context, paymentID, resolution string) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	payment, ok := r.byPaymentID[paymentID]
+	if !ok || payment.Status != "failed" || payment.ResolvedAt != nil {
+		return fmt.Errorf("%w: %s", ErrNotPending, paymentID)
+	}
+	resolvedAt := time.Now()
+	payment.ResolvedAt = &resolvedAt
+	payment.Resolution = resolution
+	return nil
+}
+
+// Purge esvazia o ledger e retorna quantos pagamentos foram apagados
+func (r *MemoryPaymentRepository) Purge(ctx context.Context) (int64, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	purged := int64(len(r.payments))
+	r.payments = nil
+	r.byPaymentID = make(map[string]*Payment)
+	r.byCorrelationID = make(map[string]*Payment)
+	r.succeeded = make(map[string]*Payment)
+	return purged, nil
+}
+
+// Ping sempre responde: o ledger vive no próprio processo
+func (r *MemoryPaymentRepository) Ping(ctx context.Context) error {
+	return nil
+}
@@ -1,48 +1,95 @@
 package repository
 
 import (
+	"context"
 	"database/sql"
+	"errors"
 	"fmt"
 	"log"
 	"time"
 
-	_ "github.com/lib/pq"
+	"github.com/lib/pq"
 )
 
 // Payment representa um registro na tabela payments
 type Payment struct {
-	ID               int       `json:"id" db:"id"`
-	PaymentID        string    `json:"payment_id" db:"payment_id"`
-	CorrelationID    string    `json:"correlation_id" db:"correlation_id"`
-	PaymentProcessor string    `json:"payment_processor" db:"payment_processor"`
-	Amount           float64   `json:"amount" db:"amount"`
-	Status           string    `json:"status" db:"status"`
-	Fee              float64   `json:"fee" db:"fee"`
-	ErrorMessage     string    `json:"error_message,omitempty" db:"error_message"`
-	ProcessedAt      time.Time `json:"processed_at" db:"processed_at"`
-	CreatedAt        time.Time `json:"created_at" db:"created_at"`
+	ID               int        `json:"id" db:"id"`
+	PaymentID        string     `json:"payment_id" db:"payment_id"`
+	CorrelationID    string     `json:"correlation_id" db:"correlation_id"`
+	PaymentProcessor string     `json:"payment_processor" db:"payment_processor"`
+	Amount           float64    `json:"amount" db:"amount"`
+	Status           string     `json:"status" db:"status"`
+	Fee              float64    `json:"fee" db:"fee"`
+	ErrorMessage     string     `json:"error_message,omitempty" db:"error_message"`
+	ProcessedAt      time.Time  `json:"processed_at" db:"processed_at"`
+	CreatedAt        time.Time  `json:"created_at" db:"created_at"`
+	RequestedAt      time.Time  `json:"requested_at" db:"requested_at"`         // Mesmo requestedAt enviado ao processor
+	ResolvedAt       *time.Time `json:"resolved_at,omitempty" db:"resolved_at"` // Dead letters: quando foi resolvido
+	Resolution       string     `json:"resolution,omitempty" db:"resolution"`   // Dead letters: como foi resolvido
+	LatencyMs        float64    `json:"latency_ms" db:"latency_ms"`             // Duração da chamada ao processor
+	Failover         bool       `json:"failover" db:"failover"`                 // Concluído em outro processor que não o escolhido
 }
 
+// Resoluções de dead letters
+const (
+	RESOLUTION_REDRIVEN  = "redriven"  // Reprocessado com sucesso
+	RESOLUTION_DISCARDED = "discarded" // Descartado manualmente
+	RESOLUTION_DUPLICATE = "duplicate" // Outro registro do mesmo correlationId já foi reprocessado
+	RESOLUTION_SUCCEEDED = "succeeded" // O correlationId já tinha um pagamento com sucesso gravado
+)
+
 // ProcessorSummary representa estatísticas de um processor específico
 type ProcessorSummary struct {
 	TotalRequests int64   `json:"totalRequests"`
 	TotalAmount   float64 `json:"totalAmount"`
 }
 
+// ProcessorStats são as estatísticas de um processor em uma janela de requested_at.
+// Registros com falha entram em failed; valores e taxas contam só os processados.
+type ProcessorStats struct {
+	Total            int64   `json:"total"`
+	Succeeded        int64   `json:"succeeded"`
+	Failed           int64   `json:"failed"`
+	TotalAmount      float64 `json:"total_amount"`
+	FailedAmount     float64 `json:"failed_amount"`
+	TotalFees        float64 `json:"total_fees"`
+	// Valor processado sem taxa registrada (o processor não informou fee na resposta)
+	AmountWithoutFee float64 `json:"amount_without_fee"`
+	AvgLatencyMs     float64 `json:"avg_latency_ms"` // Registros anteriores à coluna latency_ms não entram na média
+	Failovers        int64   `json:"failovers"`
+}
+
 // PaymentSummary representa o resumo completo de pagamentos
 type PaymentSummary struct {
 	Default  ProcessorSummary `json:"default"`
 	Fallback ProcessorSummary `json:"fallback"`
 }
 
+// ErrNotFound indica que o pagamento procurado não existe no repositório
+var ErrNotFound = errors.New("pagamento não encontrado")
+
+// ErrNotPending indica que o pagamento não é uma dead letter pendente de resolução
+var ErrNotPending = errors.New("pagamento com falha não encontrado ou já resolvido")
+
+// ErrDuplicate indica que já existe um pagamento com o mesmo payment_id
+var ErrDuplicate = errors.New("payment_id duplicado")
+
+// pqUniqueViolation é o código do PostgreSQL para violação de UNIQUE
+const pqUniqueViolation = "23505"
+
 // PaymentRepository interface para operações de pagamento
 type PaymentRepository interface {
-	Save(payment *Payment) error
-	FindByID(paymentID string) (*Payment, error)
-	FindByCorrelationID(correlationID string) (*Payment, error)
-	FindAll(limit int) ([]*Payment, error)
-	GetProcessorStats() map[string]int
-	GetPaymentsSummary(from, to time.Time) (*PaymentSummary, error)
+	Save(ctx context.Context, payment *Payment) error
+	FindByID(ctx context.Context, paymentID string) (*Payment, error)
+	FindByCorrelationID(ctx context.Context, correlationID string) (*Payment, error)
+	FindSucceeded(ctx context.Context, correlationID string) (*Payment, error)
+	FindAll(ctx context.Context, limit int) ([]*Payment, error)
+	GetProcessorStats(ctx context.Context, from, to time.Time) (map[string]*ProcessorStats, error)
+	GetPaymentsSummary(ctx context.Context, from, to time.Time) (*PaymentSummary, error)
+	FindFailed(ctx context.Context, limit int) ([]*Payment, error)
+	MarkResolved(ctx context.Context, paymentID, resolution string) error
+	Purge(ctx context.Context) (int64, error)
+	Ping(ctx context.Context) error
 }
 
 // PostgreSQLPaymentRepository implementação PostgreSQL
@@ -56,15 +103,17 @@ func NewPostgreSQLPaymentRepository(db *sql.DB) PaymentRepository {
 }
 
 // Save salva um pagamento no banco de dados
-func (r *PostgreSQLPaymentRepository) Save(payment *Payment) error {
+func (r *PostgreSQLPaymentRepository) Save(ctx context.Context, payment *Payment) error {
 	query := `
 		INSERT INTO payments (
 			payment_id, correlation_id, payment_processor, amount, 
-			status, fee, error_message, processed_at, created_at
-		) VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9)
+			status, fee, error_message, processed_at, created_at, requested_at,
+			latency_ms, failover
+		) VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11, $12)
 		RETURNING id`
 	
-	err := r.db.QueryRow(
+	err := r.db.QueryRowContext(
+		ctx,
 		query,
 		payment.PaymentID,
 		payment.CorrelationID,
@@ -75,8 +124,15 @@ func (r *PostgreSQLPaymentRepository) Save(payment *Payment) error {
 		payment.ErrorMessage,
 		payment.ProcessedAt,
 		payment.CreatedAt,
+		payment.RequestedAt,
+		payment.LatencyMs,
+		payment.Failover,
 	).Scan(&payment.ID)
 	
+	var pqErr *pq.Error
+	if errors.As(err, &pqErr) && pqErr.Code == pqUniqueViolation {
+		return fmt.Errorf("%w: %s", ErrDuplicate, payment.PaymentID)
+	}
 	if err != nil {
 		return fmt.Errorf("erro ao salvar pagamento: %v", err)
 	}
@@ -87,15 +143,17 @@ func (r *PostgreSQLPaymentRepository) Save(payment *Payment) error {
 }
 
 // FindByID busca um pagamento pelo PaymentID
-func (r *PostgreSQLPaymentRepository) FindByID(paymentID string) (*Payment, error) {
+func (r *PostgreSQLPaymentRepository) FindByID(ctx context.Context, paymentID string) (*Payment, error) {
 	query := `
 		SELECT id, payment_id, correlation_id, payment_processor, amount,
-			   status, fee, error_message, processed_at, created_at
+			   status, fee, error_message, processed_at, created_at,
+			   COALESCE(requested_at, created_at), resolved_at, COALESCE(resolution, ''),
+			   latency_ms, failover
 		FROM payments 
 		WHERE payment_id = $1`
 	
 	payment := &Payment{}
-	err := r.db.QueryRow(query, paymentID).Scan(
+	err := r.db.QueryRowContext(ctx, query, paymentID).Scan(
 		&payment.ID,
 		&payment.PaymentID,
 		&payment.CorrelationID,
@@ -106,10 +164,15 @@ func (r *PostgreSQLPaymentRepository) FindByID(paymentID string) (*Payment, erro
 		&payment.ErrorMessage,
 		&payment.ProcessedAt,
 		&payment.CreatedAt,
+		&payment.RequestedAt,
+		&payment.ResolvedAt,
+		&payment.Resolution,
+		&payment.LatencyMs,
+		&payment.Failover,
 	)
 	
 	if err == sql.ErrNoRows {
-		return nil, fmt.Errorf("pagamento não encontrado: %s", paymentID)
+		return nil, fmt.Errorf("%w: %s", ErrNotFound, paymentID)
 	}
 	if err != nil {
 		return nil, fmt.Errorf("erro ao buscar pagamento: %v", err)
@@ -119,15 +182,17 @@ func (r *PostgreSQLPaymentRepository) FindByID(paymentID string) (*Payment, erro
 }
 
 // FindByCorrelationID busca um pagamento pelo CorrelationID
-func (r *PostgreSQLPaymentRepository) FindByCorrelationID(correlationID string) (*Payment, error) {
+func (r *PostgreSQLPaymentRepository) FindByCorrelationID(ctx context.Context, correlationID string) (*Payment, error) {
 	query := `
 		SELECT id, payment_id, correlation_id, payment_processor, amount,
-			   status, fee, error_message, processed_at, created_at
+			   status, fee, error_message, processed_at, created_at,
+			   COALESCE(requested_at, created_at), resolved_at, COALESCE(resolution, ''),
+			   latency_ms, failover
 		FROM payments 
 		WHERE correlation_id = $1`
 	
 	payment := &Payment{}
-	err := r.db.QueryRow(query, correlationID).Scan(
+	err := r.db.QueryRowContext(ctx, query, correlationID).Scan(
 		&payment.ID,
 		&payment.PaymentID,
 		&payment.CorrelationID,
@@ -138,10 +203,15 @@ func (r *PostgreSQLPaymentRepository) FindByCorrelationID(correlationID string)
 		&payment.ErrorMessage,
 		&payment.ProcessedAt,
 		&payment.CreatedAt,
+		&payment.RequestedAt,
+		&payment.ResolvedAt,
+		&payment.Resolution,
+		&payment.LatencyMs,
+		&payment.Failover,
 	)
 	
 	if err == sql.ErrNoRows {
-		return nil, fmt.Errorf("pagamento não encontrado: %s", correlationID)
+		return nil, fmt.Errorf("%w: %s", ErrNotFound, correlationID)
 	}
 	if err != nil {
 		return nil, fmt.Errorf("erro ao buscar pagamento: %v", err)
@@ -150,16 +220,53 @@ func (r *PostgreSQLPaymentRepository) FindByCorrelationID(correlationID string)
 	return payment, nil
 }
 
+// FindSucceeded busca o pagamento com sucesso do correlationId, ou nil se não houver
+func (r *PostgreSQLPaymentRepository) FindSucceeded(ctx context.Context, correlationID string) (*Payment, error) {
+	query := `
+		SELECT id, payment_id, correlation_id, payment_processor, amount,
+			   status, fee, processed_at, created_at, COALESCE(requested_at, created_at),
+			   latency_ms, failover
+		FROM payments 
+		WHERE correlation_id = $1 AND status != 'failed'
+		ORDER BY created_at ASC 
+		LIMIT 1`
+
+	payment := &Payment{}
+	err := r.db.QueryRowContext(ctx, query, correlationID).Scan(
+		&payment.ID,
+		&payment.PaymentID,
+		&payment.CorrelationID,
+		&payment.PaymentProcessor,
+		&payment.Amount,
+		&payment.Status,
+		&payment.Fee,
+		&payment.ProcessedAt,
+		&payment.CreatedAt,
+		&payment.RequestedAt,
+		&payment.LatencyMs,
+		&payment.Failover,
+	)
+	if err == sql.ErrNoRows {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("erro ao buscar pagamento com sucesso: %v", err)
+	}
+	return payment, nil
+}
+
 // FindAll busca todos os pagamentos com limite
-func (r *PostgreSQLPaymentRepository) FindAll(limit int) ([]*Payment, error) {
+func (r *PostgreSQLPaymentRepository) FindAll(ctx context.Context, limit int) ([]*Payment, error) {
 	query := `
 		SELECT id, payment_id, correlation_id, payment_processor, amount,
-			   status, fee, error_message, processed_at, created_at
+			   status, fee, error_message, processed_at, created_at,
+			   COALESCE(requested_at, created_at), resolved_at, COALESCE(resolution, ''),
+			   latency_ms, failover
 		FROM payments 
 		ORDER BY created_at DESC 
 		LIMIT $1`
 	
-	rows, err := r.db.Query(query, limit)
+	rows, err := r.db.QueryContext(ctx, query, limit)
 	if err != nil {
 		return nil, fmt.Errorf("erro ao buscar pagamentos: %v", err)
 	}
@@ -179,6 +286,11 @@ func (r *PostgreSQLPaymentRepository) FindAll(limit int) ([]*Payment, error) {
 			&payment.ErrorMessage,
 			&payment.ProcessedAt,
 			&payment.CreatedAt,
+			&payment.RequestedAt,
+			&payment.ResolvedAt,
+			&payment.Resolution,
+			&payment.LatencyMs,
+			&payment.Failover,
 		)
 		if err != nil {
 			return nil, fmt.Errorf("erro ao escanear pagamento: %v", err)
@@ -189,49 +301,71 @@ func (r *PostgreSQLPaymentRepository) FindAll(limit int) ([]*Payment, error) {
 	return payments, nil
 }
 
-// GetProcessorStats retorna estatísticas de uso dos processors
-func (r *PostgreSQLPaymentRepository) GetProcessorStats() map[string]int {
+// GetProcessorStats retorna as estatísticas por processor no período de requested_at
+func (r *PostgreSQLPaymentRepository) GetProcessorStats(ctx context.Context, from, to time.Time) (map[string]*ProcessorStats, error) {
 	query := `
-		SELECT payment_processor, COUNT(*) as count
+		SELECT 
+			payment_processor,
+			COUNT(*),
+			COUNT(*) FILTER (WHERE status != 'failed'),
+			COUNT(*) FILTER (WHERE status = 'failed'),
+			COALESCE(SUM(amount) FILTER (WHERE status != 'failed'), 0),
+			COALESCE(SUM(amount) FILTER (WHERE status = 'failed'), 0),
+			COALESCE(SUM(fee) FILTER (WHERE status != 'failed'), 0),
+			COALESCE(SUM(amount) FILTER (WHERE status != 'failed' AND fee = 0), 0),
+			COALESCE(AVG(latency_ms) FILTER (WHERE latency_ms > 0), 0),
+			COUNT(*) FILTER (WHERE failover)
 		FROM payments 
+		WHERE requested_at >= $1 AND requested_at <= $2
 		GROUP BY payment_processor`
 	
-	rows, err := r.db.Query(query)
+	rows, err := r.db.QueryContext(ctx, query, from, to)
 	if err != nil {
-		log.Printf("Erro ao buscar estatísticas: %v", err)
-		return make(map[string]int)
+		return nil, fmt.Errorf("erro ao buscar estatísticas: %v", err)
 	}
 	defer rows.Close()
 	
-	stats := make(map[string]int)
+	stats := make(map[string]*ProcessorStats)
 	for rows.Next() {
 		var processor string
-		var count int
-		
-		if err := rows.Scan(&processor, &count); err != nil {
-			log.Printf("Erro ao escanear estatística: %v", err)
-			continue
+		processorStats := &ProcessorStats{}
+		if err := rows.Scan(
+			&processor,
+			&processorStats.Total,
+			&processorStats.Succeeded,
+			&processorStats.Failed,
+			&processorStats.TotalAmount,
+			&processorStats.FailedAmount,
+			&processorStats.TotalFees,
+			&processorStats.AmountWithoutFee,
+			&processorStats.AvgLatencyMs,
+			&processorStats.Failovers,
+		); err != nil {
+			return nil, fmt.Errorf("erro ao escanear estatística: %v", err)
 		}
-		
-		stats[processor] = count
+		stats[processor] = processorStats
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("erro ao iterar estatísticas: %v", err)
 	}
 	
-	return stats
+	return stats, nil
 }
 
-// GetPaymentsSummary retorna estatísticas de pagamentos por processor em um período
-func (r *PostgreSQLPaymentRepository) GetPaymentsSummary(from, to time.Time) (*PaymentSummary, error) {
+// GetPaymentsSummary retorna estatísticas de pagamentos por processor em um período.
+// O período é aplicado ao requested_at, o mesmo instante que o processor usa no resumo dele.
+func (r *PostgreSQLPaymentRepository) GetPaymentsSummary(ctx context.Context, from, to time.Time) (*PaymentSummary, error) {
 	query := `
 		SELECT 
 			payment_processor,
 			COUNT(*) as total_requests,
 			COALESCE(SUM(amount), 0) as total_amount
 		FROM payments 
-		WHERE created_at >= $1 AND created_at <= $2
+		WHERE requested_at >= $1 AND requested_at <= $2
 			AND status != 'failed'
 		GROUP BY payment_processor`
 	
-	rows, err := r.db.Query(query, from, to)
+	rows, err := r.db.QueryContext(ctx, query, from, to)
 	if err != nil {
 		return nil, fmt.Errorf("erro ao buscar resumo de pagamentos: %v", err)
 	}
@@ -273,6 +407,87 @@ func (r *PostgreSQLPaymentRepository) GetPaymentsSummary(from, to time.Time) (*P
 	return summary, nil
 }
 
+// FindFailed retorna as dead letters: tentativas com falha ainda não resolvidas, mais antigas primeiro
+func (r *PostgreSQLPaymentRepository) FindFailed(ctx context.Context, limit int) ([]*Payment, error) {
+	query := `
+		SELECT id, payment_id, correlation_id, payment_processor, amount,
+			   status, fee, COALESCE(error_message, ''), processed_at, created_at,
+			   COALESCE(requested_at, created_at), latency_ms, failover
+		FROM payments 
+		WHERE status = 'failed' AND resolved_at IS NULL
+		ORDER BY created_at ASC 
+		LIMIT $1`
+
+	rows, err := r.db.QueryContext(ctx, query, limit)
+	if err != nil {
+		return nil, fmt.Errorf("erro ao buscar pagamentos com falha: %v", err)
+	}
+	defer rows.Close()
+
+	var payments []*Payment
+	for rows.Next() {
+		payment := &Payment{}
+		err := rows.Scan(
+			&payment.ID,
+			&payment.PaymentID,
+			&payment.CorrelationID,
+			&payment.PaymentProcessor,
+			&payment.Amount,
+			&payment.Status,
+			&payment.Fee,
+			&payment.ErrorMessage,
+			&payment.ProcessedAt,
+			&payment.CreatedAt,
+			&payment.RequestedAt,
+			&payment.LatencyMs,
+			&payment.Failover,
+		)
+		if err != nil {
+			return nil, fmt.Errorf("erro ao escanear pagamento com falha: %v", err)
+		}
+		payments = append(payments, payment)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("erro ao iterar pagamentos com falha: %v", err)
+	}
+
+	return payments, nil
+}
+
+// MarkResolved marca uma dead letter como resolvida
+func (r *PostgreSQLPaymentRepository) MarkResolved(ctx context.Context, paymentID, resolution string) error {
+	query := `
+		UPDATE payments 
+		SET resolved_at = $2, resolution = $3
+		WHERE payment_id = $1 AND status = 'failed' AND resolved_at IS NULL`
+
+	result, err := r.db.ExecContext(ctx, query, paymentID, time.Now(), resolution)
+	if err != nil {
+		return fmt.Errorf("erro ao resolver pagamento com falha: %v", err)
+	}
+	if affected, err := result.RowsAffected(); err == nil && affected == 0 {
+		return fmt.Errorf("%w: %s", ErrNotPending, paymentID)
+	}
+	return nil
+}
+
+// Purge remove todos os pagamentos e retorna quantos foram apagados
+func (r *PostgreSQLPaymentRepository) Purge(ctx context.Context) (int64, error) {
+	result, err := r.db.ExecContext(ctx, `DELETE FROM payments`)
+	if err != nil {
+		return 0, fmt.Errorf("erro ao apagar pagamentos: %v", err)
+	}
+	return result.RowsAffected()
+}
+
+// Ping verifica se o banco de dados está respondendo
+func (r *PostgreSQLPaymentRepository) Ping(ctx context.Context) error {
+	if err := r.db.PingContext(ctx); err != nil {
+		return fmt.Errorf("erro ao pingar banco: %v", err)
+	}
+	return nil
+}
+
 // InitDatabase inicializa o banco de dados e cria as tabelas necessárias
 func InitDatabase(databaseURL string) (*sql.DB, error) {
 	db, err := sql.Open("postgres", databaseURL)
@@ -308,13 +523,32 @@ func createPaymentsTable(db *sql.DB) error {
 			fee DECIMAL(10,2) DEFAULT 0,
 			error_message TEXT,
 			processed_at TIMESTAMP NOT NULL,
-			created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP
+			created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+			requested_at TIMESTAMP,
+			latency_ms DOUBLE PRECISION NOT NULL DEFAULT 0,
+			failover BOOLEAN NOT NULL DEFAULT false
 		);`
 	
 	_, err := db.Exec(tableQuery)
 	if err != nil {
 		return fmt.Errorf("erro ao criar tabela payments: %v", err)
 	}
+
+	// Migração de tabelas antigas: requested_at passa a ser a referência dos resumos.
+	// Registros anteriores não têm o requestedAt enviado ao processor; usam created_at.
+	migrations := []string{
+		"ALTER TABLE payments ADD COLUMN IF NOT EXISTS requested_at TIMESTAMP;",
+		"UPDATE payments SET requested_at = created_at WHERE requested_at IS NULL;",
+		"ALTER TABLE payments ADD COLUMN IF NOT EXISTS resolved_at TIMESTAMP;",
+		"ALTER TABLE payments ADD COLUMN IF NOT EXISTS resolution VARCHAR(20);",
+		"ALTER TABLE payments ADD COLUMN IF NOT EXISTS latency_ms DOUBLE PRECISION NOT NULL DEFAULT 0;",
+		"ALTER TABLE payments ADD COLUMN IF NOT EXISTS failover BOOLEAN NOT NULL DEFAULT false;",
+	}
+	for _, migration := range migrations {
+		if _, err := db.Exec(migration); err != nil {
+			return fmt.Errorf("erro ao migrar tabela payments: %v", err)
+		}
+	}
 	
 	// Criação dos índices (um por vez para evitar conflitos)
 	indexes := []string{
@@ -322,6 +556,8 @@ func createPaymentsTable(db *sql.DB) error {
 		"CREATE INDEX IF NOT EXISTS idx_payments_status ON payments(status);", 
 		"CREATE INDEX IF NOT EXISTS idx_payments_correlation_id ON payments(correlation_id);",
 		"CREATE INDEX IF NOT EXISTS idx_payments_created_at ON payments(created_at);",
+		"CREATE INDEX IF NOT EXISTS idx_payments_requested_at ON payments(requested_at);",
+		"CREATE INDEX IF NOT EXISTS idx_payments_dead_letters ON payments(created_at) WHERE status = 'failed' AND resolved_at IS NULL;",
 	}
 	
 	for _, indexQuery := range indexes {
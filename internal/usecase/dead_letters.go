@@ -0,0 +1,147 @@
+package usecase
+
+import (
+	"context"
+	"fmt"
+	"log"
+
+	"rinha-de-backend-2025/internal/payment"
+	"rinha-de-backend-2025/internal/repository"
+)
+
+// RedriveResult é o resultado do re-drive de uma dead letter
+type RedriveResult struct {
+	PaymentID     string `json:"payment_id"`
+	CorrelationID string `json:"correlation_id"`
+	Resolved      bool   `json:"resolved"`
+	Resolution    string `json:"resolution,omitempty"`
+	ProcessorUsed string `json:"processor_used,omitempty"`
+	Error         string `json:"error,omitempty"`
+}
+
+// RedriveReport resume um re-drive em lote
+type RedriveReport struct {
+	Attempted int             `json:"attempted"`
+	Resolved  int             `json:"resolved"`
+	Failed    int             `json:"failed"`
+	Results   []RedriveResult `json:"results"`
+}
+
+// GetDeadLetters lista as tentativas com falha ainda não resolvidas
+func (uc *PaymentUseCase) GetDeadLetters(ctx context.Context, limit int) ([]*repository.Payment, error) {
+	return uc.paymentRepo.FindFailed(ctx, limit)
+}
+
+// RedriveDeadLetter reenvia uma dead letter pelo roteamento atual. Em caso de
+// sucesso o pagamento é gravado normalmente e a dead letter é marcada como resolvida;
+// em caso de falha ela continua pendente, sem gerar um novo registro "failed".
+func (uc *PaymentUseCase) RedriveDeadLetter(ctx context.Context, paymentID string) (*RedriveResult, error) {
+	deadLetter, err := uc.paymentRepo.FindByID(ctx, paymentID)
+	if err != nil {
+		return nil, err
+	}
+	if deadLetter.Status != "failed" || deadLetter.ResolvedAt != nil {
+		return nil, fmt.Errorf("%w: %s", repository.ErrNotPending, paymentID)
+	}
+	return uc.redrive(ctx, deadLetter), nil
+}
+
+// RedriveDeadLetters reenvia até limit dead letters, da mais antiga para a mais nova.
+// Registros repetidos de um correlationId já reprocessado são marcados como duplicados.
+func (uc *PaymentUseCase) RedriveDeadLetters(ctx context.Context, limit int) (*RedriveReport, error) {
+	deadLetters, err := uc.paymentRepo.FindFailed(ctx, limit)
+	if err != nil {
+		return nil, err
+	}
+
+	report := &RedriveReport{Results: make([]RedriveResult, 0, len(deadLetters))}
+	redriven := make(map[string]bool)
+	for _, deadLetter := range deadLetters {
+		if ctx.Err() != nil {
+			break
+		}
+
+		var result *RedriveResult
+		if redriven[deadLetter.CorrelationID] {
+			result = uc.resolve(ctx, deadLetter, repository.RESOLUTION_DUPLICATE)
+		} else {
+			result = uc.redrive(ctx, deadLetter)
+		}
+
+		report.Attempted++
+		if result.Resolved {
+			report.Resolved++
+			redriven[deadLetter.CorrelationID] = true
+		} else {
+			report.Failed++
+		}
+		report.Results = append(report.Results, *result)
+	}
+
+	log.Printf("🔁 Re-drive de dead letters: %d tentadas, %d resolvidas, %d com falha",
+		report.Attempted, report.Resolved, report.Failed)
+	return report, nil
+}
+
+// ResolveDeadLetter descarta uma dead letter sem reprocessá-la
+func (uc *PaymentUseCase) ResolveDeadLetter(ctx context.Context, paymentID string) error {
+	return uc.paymentRepo.MarkResolved(ctx, paymentID, repository.RESOLUTION_DISCARDED)
+}
+
+// redrive reenvia a dead letter, a menos que o correlationId já tenha um pagamento com
+// sucesso gravado (ex.: a tentativa perdedora de um hedge ou um sucesso tardio): nesse
+// caso reenviar cobraria duas vezes, então a dead letter só é resolvida.
+func (uc *PaymentUseCase) redrive(ctx context.Context, deadLetter *repository.Payment) *RedriveResult {
+	succeeded, err := uc.paymentRepo.FindSucceeded(ctx, deadLetter.CorrelationID)
+	if err != nil {
+		// Sem saber se já foi pago, não reenvia
+		return &RedriveResult{
+			PaymentID:     deadLetter.PaymentID,
+			CorrelationID: deadLetter.CorrelationID,
+			Error:         err.Error(),
+		}
+	}
+	if succeeded != nil {
+		log.Printf("🔁 Dead letter %s não reenviada: correlationId %s já pago pelo %s (%s)",
+			deadLetter.PaymentID, deadLetter.CorrelationID, succeeded.PaymentProcessor, succeeded.PaymentID)
+		result := uc.resolve(ctx, deadLetter, repository.RESOLUTION_SUCCEEDED)
+		result.ProcessorUsed = succeeded.PaymentProcessor
+		return result
+	}
+
+	// Um requestedAt novo: é uma nova requisição para o processor
+	req := payment.PaymentRequest{CorrelationID: deadLetter.CorrelationID, Amount: deadLetter.Amount}
+	processed := uc.processPayment(ctx, req, false, true)
+
+	if !processed.Success {
+		return &RedriveResult{
+			PaymentID:     deadLetter.PaymentID,
+			CorrelationID: deadLetter.CorrelationID,
+			ProcessorUsed: processed.ProcessorUsed,
+			Error:         processed.Error,
+		}
+	}
+
+	result := uc.resolve(ctx, deadLetter, repository.RESOLUTION_REDRIVEN)
+	result.ProcessorUsed = processed.ProcessorUsed
+	return result
+}
+
+func (uc *PaymentUseCase) resolve(ctx context.Context, deadLetter *repository.Payment, resolution string) *RedriveResult {
+	result := &RedriveResult{
+		PaymentID:     deadLetter.PaymentID,
+		CorrelationID: deadLetter.CorrelationID,
+		Resolved:      true,
+		Resolution:    resolution,
+	}
+
+	ctx, cancel := persistContext(ctx)
+	defer cancel()
+
+	if err := uc.paymentRepo.MarkResolved(ctx, deadLetter.PaymentID, resolution); err != nil {
+		log.Printf("ERRO: Falha ao resolver dead letter %s: %v", deadLetter.PaymentID, err)
+		result.Resolved = false
+		result.Error = err.Error()
+	}
+	return result
+}
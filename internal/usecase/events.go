@@ -0,0 +1,10 @@
+package usecase
+
+import "rinha-de-backend-2025/internal/events"
+
+// WithEvents publica o resultado de cada pagamento no bus de eventos (webhooks, streams)
+func WithEvents(bus *events.Bus) Option {
+	return func(uc *PaymentUseCase) {
+		uc.events = bus
+	}
+}
@@ -0,0 +1,123 @@
+package usecase
+
+import (
+	"context"
+	"time"
+
+	"rinha-de-backend-2025/internal/repository"
+)
+
+// FeeRates são as taxas por transação de cada processor (ex: 0.05 = 5%), usadas para
+// estimar a taxa de pagamentos cuja resposta não trouxe fee e no cenário só-default
+type FeeRates struct {
+	Default  float64 `json:"default"`
+	Fallback float64 `json:"fallback"`
+}
+
+// DefaultFeeRates são as taxas da Rinha de Backend 2025
+var DefaultFeeRates = FeeRates{Default: 0.05, Fallback: 0.15}
+
+func (r FeeRates) of(processor string) float64 {
+	switch processor {
+	case "default":
+		return r.Default
+	case "fallback":
+		return r.Fallback
+	}
+	return 0
+}
+
+// WithFeeRates define as taxas configuradas dos processors
+func WithFeeRates(rates FeeRates) Option {
+	return func(uc *PaymentUseCase) {
+		uc.feeRates = rates
+	}
+}
+
+// ProcessorFees é o bruto, a taxa e o líquido dos pagamentos processados
+type ProcessorFees struct {
+	Payments      int64   `json:"payments"`
+	GrossAmount   float64 `json:"gross_amount"`
+	Fees          float64 `json:"fees"`
+	EstimatedFees float64 `json:"estimated_fees"` // Parte de fees calculada pela taxa configurada
+	Net           float64 `json:"net"`
+	EffectiveRate float64 `json:"effective_rate"`
+}
+
+// FeeReport é o relatório de taxas por processor em uma janela de requestedAt, com o
+// que teria sido pago se todos os pagamentos tivessem ido para o default
+type FeeReport struct {
+	From       time.Time                 `json:"from"`
+	To         time.Time                 `json:"to"`
+	Rates      FeeRates                  `json:"rates"`
+	Processors map[string]*ProcessorFees `json:"processors"`
+	Total      ProcessorFees             `json:"total"`
+	AllDefault ProcessorFees             `json:"all_default"`
+	// FailoverCost é quanto as decisões de roteamento custaram a mais que o cenário só-default
+	FailoverCost float64 `json:"failover_cost"`
+}
+
+// GetFeeReport calcula bruto, taxas e líquido por processor no período. Taxas não
+// informadas pelo processor são estimadas por FeeRates; o cenário só-default usa a taxa
+// observada no default quando há taxas registradas, senão a configurada.
+func (uc *PaymentUseCase) GetFeeReport(ctx context.Context, from, to time.Time) (*FeeReport, error) {
+	stats, err := uc.paymentRepo.GetProcessorStats(ctx, from, to)
+	if err != nil {
+		return nil, err
+	}
+
+	report := &FeeReport{
+		From:       from,
+		To:         to,
+		Rates:      uc.feeRates,
+		Processors: make(map[string]*ProcessorFees),
+	}
+
+	// Somas em centavos, como no resumo
+	var grossCents, feeCents, estimatedCents int64
+	for processor, processorStats := range stats {
+		if processorStats.Succeeded == 0 {
+			continue // "none" e processors só com falhas não cobram taxa
+		}
+		estimated := repository.ToCents(processorStats.AmountWithoutFee * uc.feeRates.of(processor))
+		fees := feeSummary(
+			processorStats.Succeeded,
+			repository.ToCents(processorStats.TotalAmount),
+			repository.ToCents(processorStats.TotalFees)+estimated,
+			estimated,
+		)
+		report.Processors[processor] = &fees
+
+		report.Total.Payments += fees.Payments
+		grossCents += repository.ToCents(fees.GrossAmount)
+		feeCents += repository.ToCents(fees.Fees)
+		estimatedCents += estimated
+	}
+	report.Total = feeSummary(report.Total.Payments, grossCents, feeCents, estimatedCents)
+
+	defaultRate := uc.feeRates.Default
+	if defaultStats, ok := stats["default"]; ok {
+		if billed := defaultStats.TotalAmount - defaultStats.AmountWithoutFee; billed > 0 {
+			defaultRate = defaultStats.TotalFees / billed
+		}
+	}
+	allDefaultCents := repository.ToCents(report.Total.GrossAmount * defaultRate)
+	report.AllDefault = feeSummary(report.Total.Payments, grossCents, allDefaultCents, allDefaultCents)
+	report.FailoverCost = repository.FromCents(feeCents - allDefaultCents)
+
+	return report, nil
+}
+
+func feeSummary(payments, grossCents, feeCents, estimatedCents int64) ProcessorFees {
+	fees := ProcessorFees{
+		Payments:      payments,
+		GrossAmount:   repository.FromCents(grossCents),
+		Fees:          repository.FromCents(feeCents),
+		EstimatedFees: repository.FromCents(estimatedCents),
+		Net:           repository.FromCents(grossCents - feeCents),
+	}
+	if grossCents > 0 {
+		fees.EffectiveRate = float64(feeCents) / float64(grossCents)
+	}
+	return fees
+}
@@ -0,0 +1,168 @@
+package usecase
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"time"
+
+	"rinha-de-backend-2025/internal/gateway"
+	"rinha-de-backend-2025/internal/payment"
+)
+
+// HedgeConfig configura o envio de uma segunda tentativa ao fallback
+// quando o default demora mais que o esperado
+type HedgeConfig struct {
+	Enabled    bool
+	Delay      time.Duration // Atraso fixo usado até haver amostras suficientes
+	Percentile float64       // Percentil da latência do default usado como atraso (0 = sempre Delay)
+	MinSamples int           // Amostras mínimas antes de usar o percentil observado
+}
+
+// Validate rejeita percentis fora de [0, 1] (ex.: 95 em vez de 0.95) e NaN
+func (cfg HedgeConfig) Validate() error {
+	if !(cfg.Percentile >= 0 && cfg.Percentile <= 1) {
+		return fmt.Errorf("percentil de hedging inválido: %v (use um valor em (0, 1], ou 0 para sempre usar o atraso fixo)", cfg.Percentile)
+	}
+	return nil
+}
+
+// Option configura comportamentos opcionais do PaymentUseCase
+type Option func(*PaymentUseCase)
+
+// WithHedging habilita hedged requests para o fallback
+func WithHedging(cfg HedgeConfig) Option {
+	return func(uc *PaymentUseCase) {
+		if !cfg.Enabled {
+			return
+		}
+		uc.hedge = cfg
+		uc.defaultLatency = newLatencyTracker(1024, cfg.Percentile)
+		log.Printf("🏁 Hedging habilitado: atraso inicial=%v, percentil=%.2f, amostras mínimas=%d",
+			cfg.Delay, cfg.Percentile, cfg.MinSamples)
+	}
+}
+
+// attempt é o resultado de uma chamada a um processor
+type attempt struct {
+	processor *gateway.ProcessorInfo
+	response  *payment.PaymentResponse
+	err       error
+	latency   time.Duration
+}
+
+// hedgeDelay retorna o atraso antes de disparar a segunda tentativa
+func (uc *PaymentUseCase) hedgeDelay() time.Duration {
+	if uc.hedge.Percentile > 0 {
+		if observed, samples := uc.defaultLatency.Percentile(); samples >= uc.hedge.MinSamples && observed > 0 {
+			return observed
+		}
+	}
+	return uc.hedge.Delay
+}
+
+// callProcessor chama o processor escolhido e, com hedging habilitado, dispara a
+// mesma requisição (mesmo correlationId) no fallback se o default passar do atraso.
+// Vence a primeira resposta de sucesso. A outra tentativa só é cancelada se ainda
+// estiver na fila do limitador: depois de enviada ela vai até o fim, e se o processor
+// também a aceitar o pagamento é gravado para ele (settleLoser).
+func (uc *PaymentUseCase) callProcessor(
+	ctx context.Context,
+	primary *gateway.ProcessorInfo,
+	req payment.PaymentRequest,
+) (*attempt, bool) {
+	secondary := uc.gateway.OtherProcessor(primary.Name)
+	// Com override force/exclude ativo, o hedge não pode usar o outro processor
+	if !uc.hedge.Enabled || !primary.IsDefault || !uc.gateway.Allows(secondary.Name) {
+		return uc.sendWithReroute(ctx, primary, req), false
+	}
+
+	// waitCtx cancela a espera no limitador; a chamada em si usa callCtx, que não é
+	// cancelado pelo vencedor nem pelo fim do request (limitada por PAYMENT_TIMEOUT)
+	waitCtx, cancel := context.WithCancel(ctx)
+	callCtx := context.WithoutCancel(ctx)
+	results := make(chan *attempt, 2)
+	launch := func(processor *gateway.ProcessorInfo) {
+		go func() { results <- uc.sendWith(waitCtx, callCtx, processor, req) }()
+	}
+
+	launch(primary)
+	timer := time.NewTimer(uc.hedgeDelay())
+	defer timer.Stop()
+
+	pending := 1
+	hedged := false
+	var lastFailure *attempt
+
+	for pending > 0 {
+		select {
+		case <-timer.C:
+			log.Printf("🏁 Default lento, disparando hedge no %s: correlationId=%s", secondary.Name, req.CorrelationID)
+			launch(secondary)
+			hedged = true
+			pending++
+
+		case result := <-results:
+			pending--
+			if result.err == nil {
+				cancel()
+				if pending > 0 {
+					go uc.settleLoser(ctx, results, primary, req)
+				}
+				return result, hedged
+			}
+
+			lastFailure = result
+			if !hedged {
+				// O default falhou antes do atraso: segue o fluxo normal de fail safe
+				cancel()
+				return result, false
+			}
+		}
+	}
+
+	cancel()
+	return lastFailure, hedged
+}
+
+// send executa uma chamada ao processor, respeitando o limitador de concorrência e medindo a latência
+func (uc *PaymentUseCase) send(ctx context.Context, processor *gateway.ProcessorInfo, req payment.PaymentRequest) *attempt {
+	return uc.sendWith(ctx, ctx, processor, req)
+}
+
+// sendWith espera o limitador com waitCtx e chama o processor com callCtx
+func (uc *PaymentUseCase) sendWith(waitCtx, callCtx context.Context, processor *gateway.ProcessorInfo, req payment.PaymentRequest) *attempt {
+	token, err := uc.acquire(waitCtx, processor)
+	if err != nil {
+		return &attempt{processor: processor, err: err}
+	}
+
+	start := time.Now()
+	resp, err := uc.paymentClient.ProcessPaymentWithURL(callCtx, processor.URL, req)
+	result := &attempt{processor: processor, response: resp, err: err, latency: time.Since(start)}
+	release(callCtx, token, result)
+
+	// A tentativa cancelada pelo cliente não diz nada sobre o processor
+	if !errors.Is(callCtx.Err(), context.Canceled) {
+		uc.metrics.ObservePayment(processor.Name, result.latency, err)
+	}
+
+	if err == nil && processor.IsDefault && uc.defaultLatency != nil {
+		uc.defaultLatency.Record(result.latency)
+	}
+	return result
+}
+
+// settleLoser aguarda a tentativa perdedora do hedge. Se o processor também a aceitou,
+// o pagamento foi cobrado nos dois: o aceite é gravado como um registro próprio, para
+// que o resumo de cada processor continue batendo com o dele.
+func (uc *PaymentUseCase) settleLoser(ctx context.Context, results <-chan *attempt, primary *gateway.ProcessorInfo, req payment.PaymentRequest) {
+	loser := <-results
+	if loser.err != nil {
+		return
+	}
+	log.Printf("⚠️ Hedge: %s também aceitou correlationId=%s após o vencedor; gravando a cobrança duplicada",
+		loser.processor.Name, req.CorrelationID)
+	uc.savePaymentInfo(ctx, req, loser.processor.Name, loser.latency, loser.processor.Name != primary.Name)
+}
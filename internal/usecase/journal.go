@@ -0,0 +1,105 @@
+package usecase
+
+import (
+	"context"
+	"log"
+	"time"
+
+	"rinha-de-backend-2025/internal/gateway"
+	"rinha-de-backend-2025/internal/payment"
+)
+
+// Journal registra a aceitação de um pagamento antes da chamada ao processor,
+// para que pagamentos interrompidos por um crash possam ser retomados
+type Journal interface {
+	RecordAccepted(req payment.PaymentRequest) error
+}
+
+// WithJournal registra cada pagamento aceito no journal (ex.: wal.Repository)
+func WithJournal(journal Journal) Option {
+	return func(uc *PaymentUseCase) {
+		uc.journal = journal
+	}
+}
+
+// recordAccepted grava a aceitação no journal. Uma falha no journal não bloqueia o
+// pagamento: o resultado ainda é gravado no repositório.
+func (uc *PaymentUseCase) recordAccepted(req payment.PaymentRequest) {
+	if uc.journal == nil {
+		return
+	}
+	if err := uc.journal.RecordAccepted(req); err != nil {
+		log.Printf("⚠️ Falha ao registrar aceitação no journal: correlationId=%s, erro=%v", req.CorrelationID, err)
+	}
+}
+
+// ResumePending reprocessa os pagamentos aceitos que não chegaram a um resultado
+// antes da última parada da instância. O processor pode ter aceitado o pagamento antes
+// do crash: o que ele já tem é gravado como sucesso em vez de reenviado.
+func (uc *PaymentUseCase) ResumePending(ctx context.Context, pending []payment.PaymentRequest) {
+	if len(pending) == 0 {
+		return
+	}
+
+	log.Printf("🔁 Retomando %d pagamentos sem resultado...", len(pending))
+	succeeded, reconciled := 0, 0
+	for _, req := range pending {
+		if !uc.waitWhilePaused(ctx) {
+			return
+		}
+		if processor := uc.reconcile(ctx, req); processor != nil {
+			uc.savePaymentInfo(ctx, req, processor.Name, 0, false)
+			succeeded++
+			reconciled++
+			continue
+		}
+		if result := uc.resume(ctx, req); result.Success {
+			succeeded++
+		}
+	}
+	log.Printf("🔁 Retomada concluída: %d de %d pagamentos processados com sucesso (%d já estavam no processor)",
+		succeeded, len(pending), reconciled)
+}
+
+// resume reenvia um pagamento retomado pelo fluxo normal (com journal e fail safe)
+func (uc *PaymentUseCase) resume(ctx context.Context, req payment.PaymentRequest) *PaymentResult {
+	uc.inFlight.Add(1)
+	defer uc.inFlight.Add(-1)
+	return uc.processPayment(ctx, req, true, true)
+}
+
+// reconcile consulta os processors pelo correlationId e retorna o que já tem o
+// pagamento, ou nil. Se a consulta falhar, o reenvio ainda trata o 422 de
+// correlationId repetido como sucesso desse processor.
+func (uc *PaymentUseCase) reconcile(ctx context.Context, req payment.PaymentRequest) *gateway.ProcessorInfo {
+	for _, processor := range uc.gateway.Processors() {
+		found, err := uc.paymentClient.FindPayment(ctx, processor.URL, req.CorrelationID)
+		if err != nil {
+			log.Printf("⚠️ Retomada: erro ao consultar o %s pelo correlationId=%s: %v", processor.Name, req.CorrelationID, err)
+			continue
+		}
+		if found {
+			log.Printf("🔁 %s já tinha o correlationId=%s antes do crash: gravando como sucesso", processor.Name, req.CorrelationID)
+			return processor
+		}
+	}
+	return nil
+}
+
+// waitWhilePaused segura a retomada enquanto houver um override de pausa, para que os
+// pagamentos pendentes não sejam descartados. Retorna false se o ctx for cancelado.
+func (uc *PaymentUseCase) waitWhilePaused(ctx context.Context) bool {
+	logged := false
+	for uc.gateway.Paused() {
+		if !logged {
+			log.Printf("⏸️ Entrada pausada pelo admin, retomada aguardando...")
+			logged = true
+		}
+		select {
+		case <-ctx.Done():
+			return false
+		case <-time.After(time.Second):
+		}
+	}
+	return ctx.Err() == nil
+}
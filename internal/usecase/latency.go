@@ -0,0 +1,61 @@
+package usecase
+
+import (
+	"sort"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// latencyTracker guarda as últimas latências de um processor em um buffer circular
+// e recalcula o percentil configurado a cada recomputeEvery amostras
+type latencyTracker struct {
+	mu         sync.Mutex
+	samples    []time.Duration
+	next       int
+	count      int
+	percentile float64
+	cached     atomic.Int64
+}
+
+const recomputeEvery = 64
+
+func newLatencyTracker(size int, percentile float64) *latencyTracker {
+	return &latencyTracker{
+		samples:    make([]time.Duration, size),
+		percentile: percentile,
+	}
+}
+
+// Record adiciona uma amostra de latência
+func (t *latencyTracker) Record(latency time.Duration) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	t.samples[t.next] = latency
+	t.next = (t.next + 1) % len(t.samples)
+	if t.count < len(t.samples) {
+		t.count++
+	}
+
+	if t.next%recomputeEvery == 0 {
+		sorted := make([]time.Duration, t.count)
+		copy(sorted, t.samples[:t.count])
+		sort.Slice(sorted, func(i, j int) bool { return sorted[i] < sorted[j] })
+		index := int(float64(len(sorted)-1) * t.percentile)
+		if index < 0 {
+			index = 0
+		} else if index > len(sorted)-1 {
+			index = len(sorted) - 1
+		}
+		t.cached.Store(int64(sorted[index]))
+	}
+}
+
+// Percentile retorna o último percentil calculado e quantas amostras existem
+func (t *latencyTracker) Percentile() (time.Duration, int) {
+	t.mu.Lock()
+	count := t.count
+	t.mu.Unlock()
+	return time.Duration(t.cached.Load()), count
+}
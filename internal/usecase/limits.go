@@ -0,0 +1,79 @@
+package usecase
+
+import (
+	"context"
+	"errors"
+	"log"
+
+	"rinha-de-backend-2025/internal/events"
+	"rinha-de-backend-2025/internal/gateway"
+	"rinha-de-backend-2025/internal/limiter"
+	"rinha-de-backend-2025/internal/payment"
+)
+
+// WithConcurrencyLimits limita as chamadas simultâneas a cada processor (por nome)
+func WithConcurrencyLimits(limiters map[string]*limiter.Limiter) Option {
+	return func(uc *PaymentUseCase) {
+		uc.limiters = limiters
+		for name, l := range limiters {
+			stats := l.Stats()
+			log.Printf("🚦 Limitador de concorrência do %s: limite inicial=%d", name, stats.Limit)
+			// uc.events é lido na transição: a ordem das opções não importa
+			l.OnStateChange(func(state limiter.State) {
+				log.Printf("🚦 Limitador do %s: estrangulado=%t, limite=%d", state.Processor, state.Throttled, state.Limit)
+				uc.events.Publish(events.LIMITER_STATE_CHANGED, state)
+			})
+		}
+	}
+}
+
+// acquire reserva uma vaga no limitador do processor (nil se não houver limitador)
+func (uc *PaymentUseCase) acquire(ctx context.Context, processor *gateway.ProcessorInfo) (*limiter.Token, error) {
+	l, ok := uc.limiters[processor.Name]
+	if !ok {
+		return nil, nil
+	}
+	return l.Acquire(ctx)
+}
+
+// release devolve a vaga informando se a chamada indicou sobrecarga do processor.
+// Cancelamentos feitos por nós (cliente desconectou, perdedor de hedge) não contam.
+func release(ctx context.Context, token *limiter.Token, result *attempt) {
+	if token == nil {
+		return
+	}
+	overloaded := ctx.Err() == nil && payment.IsOverload(result.err)
+	token.Release(result.latency, overloaded)
+}
+
+// sendWithReroute envia ao processor escolhido; se a fila do limitador estourar,
+// desvia o pagamento para o outro processor em vez de acumular goroutines
+func (uc *PaymentUseCase) sendWithReroute(
+	ctx context.Context,
+	processor *gateway.ProcessorInfo,
+	req payment.PaymentRequest,
+) *attempt {
+	result := uc.send(ctx, processor, req)
+	if !errors.Is(result.err, limiter.ErrLimitExceeded) {
+		return result
+	}
+
+	other := uc.gateway.OtherProcessor(processor.Name)
+	if !uc.gateway.Allows(other.Name) {
+		return result
+	}
+	log.Printf("🚦 Limite do %s atingido, desviando correlationId=%s para %s",
+		processor.Name, req.CorrelationID, other.Name)
+	return uc.send(ctx, other, req)
+}
+
+// LimiterStats retorna o estado dos limitadores de concorrência
+func (uc *PaymentUseCase) LimiterStats() []limiter.Stats {
+	stats := make([]limiter.Stats, 0, len(uc.limiters))
+	for _, name := range []string{"default", "fallback"} {
+		if l, ok := uc.limiters[name]; ok {
+			stats = append(stats, l.Stats())
+		}
+	}
+	return stats
+}
@@ -0,0 +1,23 @@
+package usecase
+
+import (
+	"context"
+
+	"rinha-de-backend-2025/internal/metrics"
+)
+
+// WithMetrics faz cada chamada de pagamento alimentar as janelas de métricas dos processors
+func WithMetrics(registry *metrics.Registry) Option {
+	return func(uc *PaymentUseCase) {
+		uc.metrics = registry
+	}
+}
+
+// MetricsReport retorna as janelas de métricas da instância e do cluster (nil sem métricas)
+func (uc *PaymentUseCase) MetricsReport(ctx context.Context) *metrics.Report {
+	if uc.metrics == nil {
+		return nil
+	}
+	report := uc.metrics.Report(ctx)
+	return &report
+}
@@ -1,11 +1,17 @@
 package usecase
 
 import (
+	"context"
+	"errors"
 	"fmt"
 	"log"
+	"sync/atomic"
 	"time"
 
+	"rinha-de-backend-2025/internal/events"
 	"rinha-de-backend-2025/internal/gateway"
+	"rinha-de-backend-2025/internal/limiter"
+	"rinha-de-backend-2025/internal/metrics"
 	"rinha-de-backend-2025/internal/payment"
 	"rinha-de-backend-2025/internal/repository"
 )
@@ -15,6 +21,14 @@ type PaymentUseCase struct {
 	gateway        *gateway.ProcessorGateway
 	paymentClient  *payment.Client
 	paymentRepo    repository.PaymentRepository
+	hedge          HedgeConfig
+	defaultLatency *latencyTracker
+	limiters       map[string]*limiter.Limiter
+	journal        Journal
+	metrics        *metrics.Registry
+	feeRates       FeeRates
+	events         *events.Bus
+	inFlight       atomic.Int64
 }
 
 // PaymentResult representa o resultado do processamento
@@ -25,47 +39,99 @@ type PaymentResult struct {
 	ProcessingTime  time.Duration           `json:"processing_time"`
 	Error           string                   `json:"error,omitempty"`
 	SavedToDB       bool                     `json:"saved_to_db"`
+	Hedged          bool                     `json:"hedged,omitempty"`
+	Paused          bool                     `json:"paused,omitempty"`
 }
 
+// persistTimeout limita a gravação de um pagamento já decidido pelo processor
+const persistTimeout = 5 * time.Second
+
 // NewPaymentUseCase cria uma nova instância do use case
 func NewPaymentUseCase(
 	gateway *gateway.ProcessorGateway,
 	paymentClient *payment.Client,
 	paymentRepo repository.PaymentRepository,
+	opts ...Option,
 ) *PaymentUseCase {
-	return &PaymentUseCase{
+	uc := &PaymentUseCase{
 		gateway:       gateway,
 		paymentClient: paymentClient,
 		paymentRepo:   paymentRepo,
+		feeRates:      DefaultFeeRates,
+	}
+	for _, opt := range opts {
+		opt(uc)
 	}
+	return uc
 }
 
 // ProcessPayment executa todo o fluxo de processamento conforme Arquitetura 1
-func (uc *PaymentUseCase) ProcessPayment(req payment.PaymentRequest) *PaymentResult {
+func (uc *PaymentUseCase) ProcessPayment(ctx context.Context, req payment.PaymentRequest) *PaymentResult {
+	uc.inFlight.Add(1)
+	defer uc.inFlight.Add(-1)
+	return uc.processPayment(ctx, req, true, false)
+}
+
+// InFlight retorna quantos pagamentos estão em processamento (re-drives de dead letters não entram)
+func (uc *PaymentUseCase) InFlight() int64 {
+	return uc.inFlight.Load()
+}
+
+// processPayment executa o fluxo. Com failSafe=false (re-drive de dead letters), a
+// aceitação não vai ao journal e uma nova falha não gera outro registro "failed".
+// Com retried=true (retomada do WAL, re-drive), o pagamento pode já ter sido aceito
+// antes: o 422 de correlationId repetido conta como sucesso no processor que o recusou.
+func (uc *PaymentUseCase) processPayment(ctx context.Context, req payment.PaymentRequest, failSafe, retried bool) *PaymentResult {
 	startTime := time.Now()
 	result := &PaymentResult{}
-	
-	log.Printf("Iniciando processamento de pagamento: correlationId=%s, amount=%.2f", 
-		req.CorrelationID, req.Amount)
+
+	// Pausa do admin: recusa antes do journal, o cliente deve tentar de novo depois
+	if uc.gateway.Paused() {
+		result.Paused = true
+		result.Error = gateway.ErrPaused.Error()
+		return result
+	}
+
+	// requestedAt é gerado uma única vez: o mesmo valor vai ao processor (inclusive no
+	// hedge) e ao banco, para que os resumos usem a mesma referência de tempo
+	if req.RequestedAt.IsZero() {
+		req.RequestedAt = payment.NewRequestedAt()
+	}
+	if failSafe {
+		uc.recordAccepted(req)
+	}
 	
 	// 1. Decide Processor Gateway
-	processorInfo, err := uc.gateway.DecideProcessor()
+	processorInfo, err := uc.gateway.DecideProcessor(ctx)
 	if err != nil {
 		log.Printf("ERRO: Falha na decisão do processor: %v", err)
 		result.Success = false
+		result.Paused = errors.Is(err, gateway.ErrPaused)
 		result.Error = fmt.Sprintf("Nenhum processor disponível: %v", err)
 		result.ProcessingTime = time.Since(startTime)
 		
-		// Fail Safe: Salva tentativa mesmo com erro
-		uc.failSafe(req, "none", err)
+		// Fail Safe: Salva tentativa mesmo com erro (a pausa não é uma falha do pagamento)
+		if failSafe && !result.Paused {
+			uc.failSafe(ctx, req, "none", err, 0)
+		}
 		return result
 	}
 	
 	result.ProcessorUsed = processorInfo.Name
-	log.Printf("Processor selecionado: %s (%s)", processorInfo.Name, processorInfo.URL)
 	
-	// 2. Process Payment
-	paymentResp, err := uc.paymentClient.ProcessPaymentWithURL(processorInfo.URL, req)
+	// 2. Process Payment (com hedge opcional para o fallback)
+	processed, hedged := uc.callProcessor(ctx, processorInfo, req)
+	// Failover: o hedge ou o desvio do limitador concluiu em outro processor
+	failover := processed.processor.Name != processorInfo.Name
+	processorInfo = processed.processor
+	paymentResp, err := processed.response, processed.err
+	result.ProcessorUsed = processorInfo.Name
+	result.Hedged = hedged
+	if retried && payment.IsDuplicate(err) {
+		// Ack perdido (crash ou timeout após o aceite): o processor já cobrou esse pagamento
+		log.Printf("🔁 %s já tinha o correlationId=%s: gravando como sucesso", processorInfo.Name, req.CorrelationID)
+		err = nil
+	}
 	if err != nil {
 		log.Printf("ERRO: Falha no processamento do pagamento: %v", err)
 		result.Success = false
@@ -73,7 +139,9 @@ func (uc *PaymentUseCase) ProcessPayment(req payment.PaymentRequest) *PaymentRes
 		result.ProcessingTime = time.Since(startTime)
 		
 		// Fail Safe: Salva tentativa com erro
-		uc.failSafe(req, processorInfo.Name, err)
+		if failSafe {
+			uc.failSafe(ctx, req, processorInfo.Name, err, processed.latency)
+		}
 		return result
 	}
 	
@@ -82,48 +150,69 @@ func (uc *PaymentUseCase) ProcessPayment(req payment.PaymentRequest) *PaymentRes
 	result.Payment = paymentResp
 	result.ProcessingTime = time.Since(startTime)
 	
-	log.Printf("Pagamento processado com sucesso: ID=%s, CorrelationID=%s, Status=%s", 
-		paymentResp.ID, paymentResp.CorrelationID, paymentResp.Status)
-	
 	// 4. Save Payment Info
-	saved := uc.savePaymentInfo(req, paymentResp, processorInfo.Name)
+	saved := uc.savePaymentInfo(ctx, req, processorInfo.Name, processed.latency, failover)
 	result.SavedToDB = saved
 	
 	return result
 }
 
-// savePaymentInfo salva as informações do pagamento no banco
+// persistContext desacopla a gravação do cancelamento do request: se o processor
+// já respondeu, o pagamento precisa ser gravado mesmo que o cliente tenha desconectado
+func persistContext(ctx context.Context) (context.Context, context.CancelFunc) {
+	return context.WithTimeout(context.WithoutCancel(ctx), persistTimeout)
+}
+
+// savePaymentInfo salva as informações do pagamento no banco. O registro sai do request
+// e do processor usado: a resposta do processor oficial não traz id, status nem fee
+// (a taxa é estimada por FeeRates no relatório de taxas).
 func (uc *PaymentUseCase) savePaymentInfo(
+	ctx context.Context,
 	req payment.PaymentRequest, 
-	resp *payment.PaymentResponse, 
 	processorName string,
+	latency time.Duration,
+	failover bool,
 ) bool {
 	paymentRecord := &repository.Payment{
-		PaymentID:       resp.ID,
+		PaymentID:       successPaymentID(processorName, req.CorrelationID),
 		CorrelationID:   req.CorrelationID,
 		PaymentProcessor: processorName,
 		Amount:          req.Amount,
-		Status:          resp.Status,
-		Fee:             resp.Fee,
+		Status:          "processed",
 		ProcessedAt:     time.Now(),
 		CreatedAt:       time.Now(),
+		RequestedAt:     req.RequestedAt,
+		LatencyMs:       float64(latency.Microseconds()) / 1000,
+		Failover:        failover,
 	}
 	
-	if err := uc.paymentRepo.Save(paymentRecord); err != nil {
-		log.Printf("ERRO: Falha ao salvar pagamento no banco: %v", err)
+	ctx, cancel := persistContext(ctx)
+	defer cancel()
+
+	saveErr := uc.paymentRepo.Save(ctx, paymentRecord)
+	// O processor já aceitou: o evento sai mesmo que a gravação falhe
+	uc.events.Publish(events.PAYMENT_SUCCEEDED, paymentRecord)
+	if saveErr != nil {
+		log.Printf("ERRO: Falha ao salvar pagamento no banco: %v", saveErr)
 		return false
 	}
-	
-	log.Printf("Pagamento salvo com sucesso no banco: ID=%s, CorrelationID=%s", 
-		resp.ID, req.CorrelationID)
 	return true
 }
 
+// successPaymentID gera o payment_id de um pagamento aceito. O processor não devolve
+// id (só "message") e recusa correlationId repetido, então o par processor/correlationId
+// identifica o pagamento: o mesmo correlationId só se repete entre processors diferentes.
+func successPaymentID(processorName, correlationID string) string {
+	return processorName + "_" + correlationID
+}
+
 // failSafe implementa o mecanismo de fail safe da arquitetura
 func (uc *PaymentUseCase) failSafe(
+	ctx context.Context,
 	req payment.PaymentRequest, 
 	processorName string, 
 	err error,
+	latency time.Duration,
 ) {
 	log.Printf("Executando Fail Safe para pagamento: CorrelationID=%s", req.CorrelationID)
 	
@@ -137,40 +226,73 @@ func (uc *PaymentUseCase) failSafe(
 		ErrorMessage:    err.Error(),
 		ProcessedAt:     time.Now(),
 		CreatedAt:       time.Now(),
+		RequestedAt:     req.RequestedAt,
+		LatencyMs:       float64(latency.Microseconds()) / 1000,
 	}
 	
-	if saveErr := uc.paymentRepo.Save(failRecord); saveErr != nil {
+	ctx, cancel := persistContext(ctx)
+	defer cancel()
+
+	if saveErr := uc.paymentRepo.Save(ctx, failRecord); saveErr != nil {
 		log.Printf("ERRO CRÍTICO: Falha no Fail Safe: %v", saveErr)
 	} else {
 		log.Printf("Fail Safe executado com sucesso: ID=%s, CorrelationID=%s", 
 			failRecord.PaymentID, req.CorrelationID)
 	}
+	uc.events.Publish(events.PAYMENT_FAILED, failRecord)
 }
 
 // GetPaymentHistory busca o histórico de pagamentos
-func (uc *PaymentUseCase) GetPaymentHistory(limit int) ([]*repository.Payment, error) {
-	return uc.paymentRepo.FindAll(limit)
+func (uc *PaymentUseCase) GetPaymentHistory(ctx context.Context, limit int) ([]*repository.Payment, error) {
+	return uc.paymentRepo.FindAll(ctx, limit)
+}
+
+// PurgePayments apaga todos os pagamentos registrados (reset entre execuções de teste)
+func (uc *PaymentUseCase) PurgePayments(ctx context.Context) (int64, error) {
+	purged, err := uc.paymentRepo.Purge(ctx)
+	if err != nil {
+		return 0, err
+	}
+	log.Printf("🧹 %d pagamentos apagados", purged)
+	return purged, nil
 }
 
 // GetPaymentByCorrelationID busca um pagamento específico pelo CorrelationID
-func (uc *PaymentUseCase) GetPaymentByCorrelationID(correlationID string) (*repository.Payment, error) {
-	return uc.paymentRepo.FindByCorrelationID(correlationID)
+func (uc *PaymentUseCase) GetPaymentByCorrelationID(ctx context.Context, correlationID string) (*repository.Payment, error) {
+	return uc.paymentRepo.FindByCorrelationID(ctx, correlationID)
 }
 
-// GetProcessorStats retorna estatísticas dos processors
-func (uc *PaymentUseCase) GetProcessorStats() map[string]interface{} {
-	stats := uc.paymentRepo.GetProcessorStats()
-	status := uc.gateway.GetProcessorStatus()
+// GetProcessorStats retorna as estatísticas por processor no período de requested_at,
+// junto com o status atual, os limitadores e as métricas em janela deslizante
+func (uc *PaymentUseCase) GetProcessorStats(ctx context.Context, from, to time.Time) (map[string]interface{}, error) {
+	stats, err := uc.paymentRepo.GetProcessorStats(ctx, from, to)
+	if err != nil {
+		return nil, err
+	}
+	status := uc.gateway.GetProcessorStatus(ctx)
+
+	// processor_usage mantém o formato anterior (quantidade de registros por processor)
+	usage := make(map[string]int64, len(stats))
+	for processor, processorStats := range stats {
+		usage[processor] = processorStats.Total
+	}
 	
-	return map[string]interface{}{
-		"processor_usage": stats,
+	response := map[string]interface{}{
+		"window": map[string]interface{}{"from": from, "to": to},
+		"processors": stats,
+		"processor_usage": usage,
 		"processor_status": status,
+		"concurrency_limits": uc.LimiterStats(),
 		"timestamp": time.Now(),
 	}
-} 
+	if uc.metrics != nil {
+		response["processor_metrics"] = uc.metrics.Report(ctx)
+	}
+	return response, nil
+}
 
 // GetPaymentsSummary retorna resumo de pagamentos por processor no período especificado
-func (uc *PaymentUseCase) GetPaymentsSummary(from, to time.Time) (*repository.PaymentSummary, error) {
+func (uc *PaymentUseCase) GetPaymentsSummary(ctx context.Context, from, to time.Time) (*repository.PaymentSummary, error) {
 	log.Printf("Buscando resumo de pagamentos de %s até %s", from.Format(time.RFC3339), to.Format(time.RFC3339))
 	
 	// Validar período
@@ -183,7 +305,7 @@ func (uc *PaymentUseCase) GetPaymentsSummary(from, to time.Time) (*repository.Pa
 		return nil, fmt.Errorf("período máximo permitido é de 1 ano")
 	}
 	
-	summary, err := uc.paymentRepo.GetPaymentsSummary(from, to)
+	summary, err := uc.paymentRepo.GetPaymentsSummary(ctx, from, to)
 	if err != nil {
 		log.Printf("Erro ao buscar resumo: %v", err)
 		return nil, fmt.Errorf("falha ao gerar resumo: %v", err)
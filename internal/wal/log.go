@@ -0,0 +1,461 @@
+package wal
+
+import (
+	"bufio"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"hash/crc32"
+	"log"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Tipos de evento registrados no log
+const (
+	EVENT_ACCEPTED = "accepted" // Pagamento aceito, antes da chamada ao processor
+	EVENT_SUCCESS  = "success"  // Processor confirmou o pagamento
+	EVENT_FAILURE  = "failure"  // Tentativa com erro registrada pelo fail safe
+	EVENT_RESOLVED = "resolved" // Dead letter resolvida (reprocessada ou descartada)
+	EVENT_PURGED   = "purged"   // Ledger esvaziado pelo admin; tudo antes dele é descartado
+)
+
+const (
+	segmentPrefix = "wal-"
+	segmentSuffix = ".log"
+)
+
+// Record é uma entrada do write-ahead log
+type Record struct {
+	Type          string    `json:"type"`
+	CorrelationID string    `json:"correlationId"`
+	Amount        float64   `json:"amount"`
+	PaymentID     string    `json:"paymentId,omitempty"`
+	Processor     string    `json:"processor,omitempty"`
+	Status        string    `json:"status,omitempty"`
+	Fee           float64   `json:"fee,omitempty"`
+	Error         string    `json:"error,omitempty"`
+	Resolution    string    `json:"resolution,omitempty"`
+	ProcessedAt   time.Time `json:"processedAt"`
+	CreatedAt     time.Time `json:"createdAt"`
+	RequestedAt   time.Time `json:"requestedAt"`
+	LatencyMs     float64   `json:"latencyMs,omitempty"`
+	Failover      bool      `json:"failover,omitempty"`
+}
+
+// Config define rotação de segmentos, agrupamento de fsync e retenção
+type Config struct {
+	Dir          string        // Diretório dos segmentos
+	SegmentSize  int64         // Tamanho a partir do qual um novo segmento é aberto
+	SyncInterval time.Duration // Janela de agrupamento de escritas por fsync
+	// Retention apaga os segmentos fechados cujos registros já foram persistidos no
+	// repositório. Só vale com um repositório durável: com o ledger em memória o WAL
+	// é a única cópia dos pagamentos e precisa ser mantido inteiro.
+	Retention bool
+}
+
+// DefaultConfig retorna a configuração padrão do WAL
+func DefaultConfig(dir string) Config {
+	return Config{
+		Dir:          dir,
+		SegmentSize:  16 * 1024 * 1024,
+		SyncInterval: 2 * time.Millisecond,
+	}
+}
+
+// batch agrupa as escritas que serão confirmadas pelo mesmo fsync
+type batch struct {
+	done chan struct{}
+	err  error
+}
+
+func newBatch() *batch {
+	return &batch{done: make(chan struct{})}
+}
+
+// Log é um write-ahead log append-only em segmentos. Cada linha tem o formato
+// "<crc32 em hex> <json>\n"; Append só retorna depois do fsync do lote em que a
+// escrita entrou, então vários pagamentos simultâneos dividem o mesmo fsync.
+type Log struct {
+	cfg Config
+
+	mu      sync.Mutex
+	file    *os.File
+	writer  *bufio.Writer
+	size    int64
+	seq     int
+	current *batch
+	closed  bool
+	// Registros ainda não persistidos no repositório, por segmento (ver AppendPinned)
+	pins map[int]int
+
+	kick chan struct{}
+	stop chan struct{}
+	wg   sync.WaitGroup
+}
+
+// Open abre o WAL no diretório, em um segmento novo após os existentes
+func Open(cfg Config) (*Log, error) {
+	if err := os.MkdirAll(cfg.Dir, 0o755); err != nil {
+		return nil, fmt.Errorf("erro ao criar diretório do WAL: %v", err)
+	}
+
+	segments, err := listSegments(cfg.Dir)
+	if err != nil {
+		return nil, err
+	}
+
+	l := &Log{
+		cfg:     cfg,
+		current: newBatch(),
+		pins:    make(map[int]int),
+		kick:    make(chan struct{}, 1),
+		stop:    make(chan struct{}),
+	}
+
+	// Cada execução começa um segmento novo: se o último terminou com uma escrita
+	// interrompida por crash, os registros novos não ficam depois da linha corrompida
+	seq := 1
+	if len(segments) > 0 {
+		seq = segments[len(segments)-1].seq + 1
+	}
+	if err := l.openSegment(seq); err != nil {
+		return nil, err
+	}
+
+	l.wg.Add(1)
+	go l.syncLoop()
+
+	return l, nil
+}
+
+// Append grava o registro e espera o fsync do lote. Com Retention, o segmento pode ser
+// apagado na próxima rotação: registros que ainda vão ao repositório usam AppendPinned.
+func (l *Log) Append(record Record) error {
+	_, err := l.append(record, false)
+	return err
+}
+
+// AppendPinned grava o registro como Append e retorna o segmento em que ele entrou.
+// O segmento (e os seguintes) não é apagado pelo Checkpoint até o Unpin, chamado
+// quando o registro estiver persistido no repositório.
+func (l *Log) AppendPinned(record Record) (int, error) {
+	return l.append(record, true)
+}
+
+func (l *Log) append(record Record, pin bool) (int, error) {
+	payload, err := json.Marshal(record)
+	if err != nil {
+		return 0, fmt.Errorf("erro ao serializar registro do WAL: %v", err)
+	}
+
+	l.mu.Lock()
+	if l.closed {
+		l.mu.Unlock()
+		return 0, errors.New("WAL fechado")
+	}
+	n, err := writeLine(l.writer, payload)
+	l.size += int64(n)
+	seq := l.seq
+	if pin && err == nil {
+		l.pins[seq]++ // Ainda com o lock: a rotação não apaga o segmento antes do pin
+	}
+	b := l.current
+	l.mu.Unlock()
+
+	if err != nil {
+		return 0, fmt.Errorf("erro ao escrever no WAL: %v", err)
+	}
+
+	select {
+	case l.kick <- struct{}{}:
+	default:
+	}
+
+	<-b.done
+	if b.err != nil && pin {
+		l.Unpin(seq)
+	}
+	return seq, b.err
+}
+
+// Pin mantém o segmento até o Unpin (usado pelo Recover para os pagamentos sem resultado)
+func (l *Log) Pin(seq int) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	l.pins[seq]++
+}
+
+// Unpin libera um registro de AppendPinned ou Pin
+func (l *Log) Unpin(seq int) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	if l.pins[seq] <= 1 {
+		delete(l.pins, seq)
+		return
+	}
+	l.pins[seq]--
+}
+
+// Checkpoint apaga os segmentos fechados anteriores ao segmento mais antigo com registros
+// ainda não persistidos (ou ao segmento atual, se não houver nenhum). Sem Retention não
+// faz nada. Roda a cada rotação e no fim do Recover; retorna quantos segmentos apagou.
+func (l *Log) Checkpoint() (int, error) {
+	if !l.cfg.Retention {
+		return 0, nil
+	}
+
+	l.mu.Lock()
+	oldest := l.seq
+	for seq := range l.pins {
+		if seq < oldest {
+			oldest = seq
+		}
+	}
+	l.mu.Unlock()
+
+	segments, err := listSegments(l.cfg.Dir)
+	if err != nil {
+		return 0, err
+	}
+
+	// Só segmentos fechados e sem pins: nenhum Append ou Pin novo cai neles
+	removed := 0
+	for _, segment := range segments {
+		if segment.seq >= oldest {
+			break
+		}
+		if err := os.Remove(segment.path); err != nil && !errors.Is(err, os.ErrNotExist) {
+			return removed, fmt.Errorf("erro ao apagar segmento do WAL: %v", err)
+		}
+		removed++
+	}
+	if removed > 0 {
+		log.Printf("📒 WAL: %d segmentos já persistidos apagados (mantendo a partir do %d)", removed, oldest)
+	}
+	return removed, nil
+}
+
+// syncLoop confirma os lotes: espera a janela de agrupamento, descarrega o buffer e faz fsync
+func (l *Log) syncLoop() {
+	defer l.wg.Done()
+	for {
+		select {
+		case <-l.stop:
+			return
+		case <-l.kick:
+		}
+
+		if l.cfg.SyncInterval > 0 {
+			time.Sleep(l.cfg.SyncInterval)
+		}
+		l.commit()
+	}
+}
+
+// commit confirma o lote atual. O fsync roda fora do lock, exceto na rotação de segmento.
+func (l *Log) commit() {
+	l.mu.Lock()
+	b := l.current
+	l.current = newBatch()
+
+	err := l.writer.Flush()
+	file := l.file
+	rotate := err == nil && l.size >= l.cfg.SegmentSize
+	if rotate {
+		err = l.rotate()
+	}
+	l.mu.Unlock()
+
+	if err == nil && !rotate {
+		err = file.Sync()
+	}
+	if err != nil {
+		err = fmt.Errorf("erro ao sincronizar WAL: %v", err)
+	}
+
+	b.err = err
+	close(b.done)
+
+	if rotate && err == nil {
+		if _, err := l.Checkpoint(); err != nil {
+			log.Printf("⚠️ WAL: erro no checkpoint: %v", err)
+		}
+	}
+}
+
+// rotate fecha o segmento atual (com fsync) e abre o próximo. Chamado com o lock.
+func (l *Log) rotate() error {
+	if err := l.file.Sync(); err != nil {
+		return err
+	}
+	if err := l.file.Close(); err != nil {
+		return err
+	}
+	log.Printf("📒 WAL: segmento %d completo (%d bytes), abrindo o próximo", l.seq, l.size)
+	return l.openSegment(l.seq + 1)
+}
+
+func (l *Log) openSegment(seq int) error {
+	path := segmentPath(l.cfg.Dir, seq)
+	file, err := os.OpenFile(path, os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0o644)
+	if err != nil {
+		return fmt.Errorf("erro ao abrir segmento do WAL: %v", err)
+	}
+	info, err := file.Stat()
+	if err != nil {
+		file.Close()
+		return fmt.Errorf("erro ao abrir segmento do WAL: %v", err)
+	}
+
+	l.file = file
+	l.writer = bufio.NewWriterSize(file, 64*1024)
+	l.size = info.Size()
+	l.seq = seq
+	return nil
+}
+
+// Close confirma as escritas pendentes e fecha o segmento atual
+func (l *Log) Close() error {
+	l.mu.Lock()
+	l.closed = true
+	l.mu.Unlock()
+
+	close(l.stop)
+	l.wg.Wait()
+	l.commit()
+
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	return l.file.Close()
+}
+
+// Replay lê todos os segmentos em ordem. Uma linha corrompida (escrita interrompida
+// por um crash) encerra a leitura daquele segmento.
+func Replay(dir string, fn func(Record) error) error {
+	return replay(dir, func(seq int, record Record) error {
+		return fn(record)
+	})
+}
+
+// replay é o Replay informando o segmento de cada registro
+func replay(dir string, fn func(seq int, record Record) error) error {
+	segments, err := listSegments(dir)
+	if err != nil {
+		return err
+	}
+
+	for _, segment := range segments {
+		seq := segment.seq
+		err := replaySegment(segment.path, func(record Record) error {
+			return fn(seq, record)
+		})
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func replaySegment(path string, fn func(Record) error) error {
+	file, err := os.Open(path)
+	if err != nil {
+		return fmt.Errorf("erro ao abrir segmento do WAL: %v", err)
+	}
+	defer file.Close()
+
+	scanner := bufio.NewScanner(file)
+	scanner.Buffer(make([]byte, 0, 64*1024), 1024*1024)
+	line := 0
+	for scanner.Scan() {
+		line++
+		record, ok := parseLine(scanner.Bytes())
+		if !ok {
+			log.Printf("⚠️ WAL: registro corrompido em %s:%d, ignorando o restante do segmento", path, line)
+			return nil
+		}
+		if err := fn(record); err != nil {
+			return err
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return fmt.Errorf("erro ao ler segmento do WAL %s: %v", path, err)
+	}
+	return nil
+}
+
+func writeLine(w *bufio.Writer, payload []byte) (int, error) {
+	var checksum [8]byte
+	sum := crc32.ChecksumIEEE(payload)
+	hex.Encode(checksum[:], []byte{byte(sum >> 24), byte(sum >> 16), byte(sum >> 8), byte(sum)})
+
+	w.Write(checksum[:])
+	w.WriteByte(' ')
+	w.Write(payload)
+	if err := w.WriteByte('\n'); err != nil {
+		return 0, err
+	}
+	return len(checksum) + len(payload) + 2, nil
+}
+
+func parseLine(line []byte) (Record, bool) {
+	var record Record
+	if len(line) < 10 || line[8] != ' ' {
+		return record, false
+	}
+
+	var sum [4]byte
+	if _, err := hex.Decode(sum[:], line[:8]); err != nil {
+		return record, false
+	}
+	payload := line[9:]
+	expected := uint32(sum[0])<<24 | uint32(sum[1])<<16 | uint32(sum[2])<<8 | uint32(sum[3])
+	if crc32.ChecksumIEEE(payload) != expected {
+		return record, false
+	}
+
+	if err := json.Unmarshal(payload, &record); err != nil {
+		return record, false
+	}
+	return record, true
+}
+
+type segment struct {
+	seq  int
+	path string
+}
+
+func listSegments(dir string) ([]segment, error) {
+	entries, err := os.ReadDir(dir)
+	if errors.Is(err, os.ErrNotExist) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("erro ao listar segmentos do WAL: %v", err)
+	}
+
+	var segments []segment
+	for _, entry := range entries {
+		name := entry.Name()
+		if entry.IsDir() || !strings.HasPrefix(name, segmentPrefix) || !strings.HasSuffix(name, segmentSuffix) {
+			continue
+		}
+		var seq int
+		if _, err := fmt.Sscanf(strings.TrimSuffix(strings.TrimPrefix(name, segmentPrefix), segmentSuffix), "%d", &seq); err != nil {
+			continue
+		}
+		segments = append(segments, segment{seq: seq, path: filepath.Join(dir, name)})
+	}
+
+	sort.Slice(segments, func(i, j int) bool { return segments[i].seq < segments[j].seq })
+	return segments, nil
+}
+
+func segmentPath(dir string, seq int) string {
+	return filepath.Join(dir, fmt.Sprintf("%s%08d%s", segmentPrefix, seq, segmentSuffix))
+}
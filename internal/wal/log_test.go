@@ -0,0 +1,233 @@
+package wal
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"os"
+	"testing"
+	"time"
+)
+
+func testConfig(t *testing.T) Config {
+	t.Helper()
+	cfg := DefaultConfig(t.TempDir())
+	cfg.SyncInterval = 0
+	return cfg
+}
+
+func openLog(t *testing.T, cfg Config) *Log {
+	t.Helper()
+	l, err := Open(cfg)
+	if err != nil {
+		t.Fatalf("Open: %v", err)
+	}
+	return l
+}
+
+func accepted(correlationID string) Record {
+	return Record{Type: EVENT_ACCEPTED, CorrelationID: correlationID, Amount: 19.9, RequestedAt: time.Unix(1750000000, 0).UTC()}
+}
+
+func replayIDs(t *testing.T, dir string) []string {
+	t.Helper()
+	var ids []string
+	err := Replay(dir, func(record Record) error {
+		ids = append(ids, record.CorrelationID)
+		return nil
+	})
+	if err != nil {
+		t.Fatalf("Replay: %v", err)
+	}
+	return ids
+}
+
+func segmentSeqs(t *testing.T, dir string) []int {
+	t.Helper()
+	segments, err := listSegments(dir)
+	if err != nil {
+		t.Fatalf("listSegments: %v", err)
+	}
+	seqs := make([]int, len(segments))
+	for i, segment := range segments {
+		seqs[i] = segment.seq
+	}
+	return seqs
+}
+
+func equalStrings(a, b []string) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	for i := range a {
+		if a[i] != b[i] {
+			return false
+		}
+	}
+	return true
+}
+
+func TestReplayStopsAtCorruptedLine(t *testing.T) {
+	valid := func(correlationID string) string {
+		line, err := encodeLine(accepted(correlationID))
+		if err != nil {
+			t.Fatalf("encodeLine: %v", err)
+		}
+		return line
+	}
+	flipped := []byte(valid("b"))
+	flipped[20] ^= 0x01 // Payload alterado: o CRC não confere
+
+	tests := []struct {
+		name    string
+		content string
+		want    []string
+	}{
+		{"íntegro", valid("a") + valid("b"), []string{"a", "b"}},
+		{"última linha cortada no meio", valid("a") + valid("b")[:30], []string{"a"}},
+		{"crc não confere", valid("a") + string(flipped) + valid("c"), []string{"a"}},
+		{"crc não é hex", valid("a") + "zzzzzzzz" + valid("b")[8:] + valid("c"), []string{"a"}},
+		{"sem separador", valid("a") + "0000000000" + "\n" + valid("c"), []string{"a"}},
+		{"json inválido com crc correto", valid("a") + rawLine(`{"type":`) + valid("c"), []string{"a"}},
+		{"linha vazia", valid("a") + "\n" + valid("c"), []string{"a"}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			dir := t.TempDir()
+			if err := os.WriteFile(segmentPath(dir, 1), []byte(tt.content), 0o644); err != nil {
+				t.Fatal(err)
+			}
+			// O segmento seguinte é lido normalmente: a corrupção só encerra o próprio segmento
+			if err := os.WriteFile(segmentPath(dir, 2), []byte(valid("z")), 0o644); err != nil {
+				t.Fatal(err)
+			}
+
+			got := replayIDs(t, dir)
+			want := append(append([]string(nil), tt.want...), "z")
+			if !equalStrings(got, want) {
+				t.Fatalf("Replay = %v, want %v", got, want)
+			}
+		})
+	}
+}
+
+func TestRotationAndReplay(t *testing.T) {
+	cfg := testConfig(t)
+	cfg.SegmentSize = 1 // Cada lote confirmado fecha o segmento
+
+	l := openLog(t, cfg)
+	var want []string
+	for i := 0; i < 5; i++ {
+		id := fmt.Sprintf("p%d", i)
+		if err := l.Append(accepted(id)); err != nil {
+			t.Fatalf("Append: %v", err)
+		}
+		want = append(want, id)
+	}
+	if err := l.Close(); err != nil {
+		t.Fatalf("Close: %v", err)
+	}
+
+	if seqs := segmentSeqs(t, cfg.Dir); len(seqs) < 5 {
+		t.Fatalf("segmentos = %v, want ao menos 5 após a rotação", seqs)
+	}
+	if got := replayIDs(t, cfg.Dir); !equalStrings(got, want) {
+		t.Fatalf("Replay = %v, want %v", got, want)
+	}
+
+	// Reabrir começa um segmento novo depois dos existentes, sem reescrever os antigos
+	last := segmentSeqs(t, cfg.Dir)
+	l = openLog(t, cfg)
+	if err := l.Append(accepted("after-restart")); err != nil {
+		t.Fatalf("Append: %v", err)
+	}
+	l.Close()
+
+	if l.seq <= last[len(last)-1] {
+		t.Fatalf("segmento após reabrir = %d, want > %d", l.seq, last[len(last)-1])
+	}
+	want = append(want, "after-restart")
+	if got := replayIDs(t, cfg.Dir); !equalStrings(got, want) {
+		t.Fatalf("Replay = %v, want %v", got, want)
+	}
+}
+
+func TestCheckpointKeepsPinnedSegments(t *testing.T) {
+	cfg := testConfig(t)
+	cfg.SegmentSize = 1
+	cfg.Retention = true
+
+	l := openLog(t, cfg)
+	defer l.Close()
+
+	if err := l.Append(accepted("persisted")); err != nil {
+		t.Fatalf("Append: %v", err)
+	}
+	pinned, err := l.AppendPinned(accepted("pending"))
+	if err != nil {
+		t.Fatalf("AppendPinned: %v", err)
+	}
+	if err := l.Append(accepted("later")); err != nil {
+		t.Fatalf("Append: %v", err)
+	}
+
+	// A rotação já rodou o checkpoint: só o que veio antes do pin foi apagado
+	if got := replayIDs(t, cfg.Dir); !equalStrings(got, []string{"pending", "later"}) {
+		t.Fatalf("Replay com pin = %v, want [pending later]", got)
+	}
+	if seqs := segmentSeqs(t, cfg.Dir); seqs[0] != pinned {
+		t.Fatalf("segmento mais antigo = %d, want %d (o do pin)", seqs[0], pinned)
+	}
+
+	l.Unpin(pinned)
+	if _, err := l.Checkpoint(); err != nil {
+		t.Fatalf("Checkpoint: %v", err)
+	}
+	if got := replayIDs(t, cfg.Dir); len(got) != 0 {
+		t.Fatalf("Replay após Unpin = %v, want vazio", got)
+	}
+	if seqs := segmentSeqs(t, cfg.Dir); len(seqs) != 1 || seqs[0] != l.seq {
+		t.Fatalf("segmentos = %v, want só o atual (%d)", seqs, l.seq)
+	}
+}
+
+func TestCheckpointWithoutRetentionKeepsEverything(t *testing.T) {
+	cfg := testConfig(t)
+	cfg.SegmentSize = 1
+
+	l := openLog(t, cfg)
+	for i := 0; i < 3; i++ {
+		if err := l.Append(accepted(fmt.Sprintf("p%d", i))); err != nil {
+			t.Fatalf("Append: %v", err)
+		}
+	}
+	removed, err := l.Checkpoint()
+	l.Close()
+
+	if err != nil || removed != 0 {
+		t.Fatalf("Checkpoint = %d, %v; want 0, nil", removed, err)
+	}
+	if got := replayIDs(t, cfg.Dir); len(got) != 3 {
+		t.Fatalf("Replay = %v, want os 3 registros", got)
+	}
+}
+
+// encodeLine gera a linha do WAL como o Append a grava
+func encodeLine(record Record) (string, error) {
+	payload, err := json.Marshal(record)
+	if err != nil {
+		return "", err
+	}
+	return rawLine(string(payload)), nil
+}
+
+// rawLine gera uma linha com CRC correto para um payload qualquer
+func rawLine(payload string) string {
+	var buf bytes.Buffer
+	w := bufio.NewWriter(&buf)
+	writeLine(w, []byte(payload))
+	w.Flush()
+	return buf.String()
+}
@@ -0,0 +1,239 @@
+package wal
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"sync"
+
+	"rinha-de-backend-2025/internal/payment"
+	"rinha-de-backend-2025/internal/repository"
+)
+
+// Repository grava cada evento no WAL antes de repassá-lo ao repositório real.
+// Se o processo cair entre o ack do processor e a gravação no repositório, o
+// pagamento é reaplicado pelo Recover na próxima inicialização. Os segmentos ficam
+// presos (pins) até os seus registros chegarem ao repositório; só então o
+// Checkpoint pode apagá-los.
+type Repository struct {
+	repository.PaymentRepository
+
+	log *Log
+
+	mu sync.Mutex
+	// Segmento do "accepted" de cada pagamento que ainda não teve resultado
+	accepted map[string]int
+}
+
+// NewRepository envolve o repositório com o write-ahead log
+func NewRepository(inner repository.PaymentRepository, l *Log) *Repository {
+	return &Repository{PaymentRepository: inner, log: l, accepted: make(map[string]int)}
+}
+
+// RecordAccepted registra que o pagamento foi aceito, antes da chamada ao processor.
+// O segmento fica preso até o Save do resultado.
+func (r *Repository) RecordAccepted(req payment.PaymentRequest) error {
+	seq, err := r.log.AppendPinned(Record{
+		Type:          EVENT_ACCEPTED,
+		CorrelationID: req.CorrelationID,
+		Amount:        req.Amount,
+		RequestedAt:   req.RequestedAt,
+	})
+	if err != nil {
+		return err
+	}
+	r.trackAccepted(req.CorrelationID, seq)
+	return nil
+}
+
+// Save registra o sucesso ou a falha no WAL (com fsync) e depois no repositório real
+func (r *Repository) Save(ctx context.Context, p *repository.Payment) error {
+	seq, err := r.log.AppendPinned(recordFromPayment(p))
+	if err != nil {
+		return err
+	}
+	r.settleAccepted(p.CorrelationID)
+	return r.persisted(seq, r.PaymentRepository.Save(ctx, p))
+}
+
+// MarkResolved registra a resolução da dead letter no WAL e depois no repositório real.
+// Só dead letters pendentes chegam ao WAL: um id desconhecido ou já resolvido é recusado antes.
+func (r *Repository) MarkResolved(ctx context.Context, paymentID, resolution string) error {
+	deadLetter, err := r.PaymentRepository.FindByID(ctx, paymentID)
+	if errors.Is(err, repository.ErrNotFound) || err == nil && (deadLetter.Status != "failed" || deadLetter.ResolvedAt != nil) {
+		return fmt.Errorf("%w: %s", repository.ErrNotPending, paymentID)
+	}
+	if err != nil {
+		return err
+	}
+
+	seq, err := r.log.AppendPinned(Record{Type: EVENT_RESOLVED, PaymentID: paymentID, Resolution: resolution})
+	if err != nil {
+		return err
+	}
+	return r.persisted(seq, r.PaymentRepository.MarkResolved(ctx, paymentID, resolution))
+}
+
+// Purge registra o purge no WAL, para que o Recover não restaure os pagamentos apagados
+func (r *Repository) Purge(ctx context.Context) (int64, error) {
+	seq, err := r.log.AppendPinned(Record{Type: EVENT_PURGED})
+	if err != nil {
+		return 0, err
+	}
+	deleted, err := r.PaymentRepository.Purge(ctx)
+	return deleted, r.persisted(seq, err)
+}
+
+// persisted solta o segmento do registro quando o repositório confirmou a escrita ou a
+// recusou (o registro não tem nada a reaplicar). Se o repositório falhou, o segmento
+// fica preso até a próxima inicialização reaplicá-lo.
+func (r *Repository) persisted(seq int, err error) error {
+	if err == nil || rejected(err) {
+		r.log.Unpin(seq)
+	}
+	return err
+}
+
+// rejected indica que o repositório recusou a escrita (erro do chamador), em oposição
+// a uma falha de armazenamento que o Recover deve repetir
+func rejected(err error) bool {
+	return errors.Is(err, repository.ErrDuplicate) ||
+		errors.Is(err, repository.ErrNotFound) ||
+		errors.Is(err, repository.ErrNotPending)
+}
+
+// trackAccepted prende o segmento do aceite até o resultado (o primeiro aceite vale)
+func (r *Repository) trackAccepted(correlationID string, seq int) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if _, ok := r.accepted[correlationID]; ok {
+		r.log.Unpin(seq)
+		return
+	}
+	r.accepted[correlationID] = seq
+}
+
+// settleAccepted solta o segmento do aceite: o resultado já está no WAL
+func (r *Repository) settleAccepted(correlationID string) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if seq, ok := r.accepted[correlationID]; ok {
+		delete(r.accepted, correlationID)
+		r.log.Unpin(seq)
+	}
+}
+
+// Recover reaplica o WAL no repositório real e retorna os pagamentos aceitos que
+// nunca chegaram a um resultado (sucesso ou falha), para serem retomados. No fim,
+// o Checkpoint apaga os segmentos que não têm mais nada a reaplicar ou retomar.
+func (r *Repository) Recover(ctx context.Context) ([]payment.PaymentRequest, error) {
+	pending := make(map[string]payment.PaymentRequest)
+	pendingSeq := make(map[string]int)
+	var order []string
+	restored := 0
+
+	err := replay(r.log.cfg.Dir, func(seq int, record Record) error {
+		switch record.Type {
+		case EVENT_ACCEPTED:
+			if _, ok := pending[record.CorrelationID]; !ok {
+				order = append(order, record.CorrelationID)
+				pendingSeq[record.CorrelationID] = seq
+			}
+			// O requestedAt original é mantido: a retomada cai na mesma janela do resumo
+			pending[record.CorrelationID] = payment.PaymentRequest{
+				CorrelationID: record.CorrelationID,
+				Amount:        record.Amount,
+				RequestedAt:   record.RequestedAt,
+			}
+			return nil
+		case EVENT_SUCCESS, EVENT_FAILURE:
+			delete(pending, record.CorrelationID)
+			delete(pendingSeq, record.CorrelationID)
+		case EVENT_RESOLVED:
+			// Já resolvida no repositório (ou repetida no WAL): nada a reaplicar
+			r.PaymentRepository.MarkResolved(ctx, record.PaymentID, record.Resolution)
+			return nil
+		case EVENT_PURGED:
+			// O purge pode não ter chegado ao repositório antes do crash: repete e esquece o que veio antes
+			if _, err := r.PaymentRepository.Purge(ctx); err != nil {
+				return err
+			}
+			pending = make(map[string]payment.PaymentRequest)
+			pendingSeq = make(map[string]int)
+			order = nil
+			restored = 0
+			return nil
+		default:
+			return nil
+		}
+
+		if _, err := r.PaymentRepository.FindByID(ctx, record.PaymentID); err == nil {
+			return nil
+		}
+		if err := r.PaymentRepository.Save(ctx, paymentFromRecord(record)); err != nil {
+			return err
+		}
+		restored++
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	unfinished := make([]payment.PaymentRequest, 0, len(pending))
+	for _, correlationID := range order {
+		if req, ok := pending[correlationID]; ok {
+			unfinished = append(unfinished, req)
+			// O aceite é a única cópia do pagamento retomado até o seu resultado
+			r.log.Pin(pendingSeq[correlationID])
+			r.trackAccepted(correlationID, pendingSeq[correlationID])
+		}
+	}
+
+	log.Printf("📒 WAL: %d pagamentos restaurados no repositório, %d pagamentos sem resultado", restored, len(unfinished))
+
+	if _, err := r.log.Checkpoint(); err != nil {
+		log.Printf("⚠️ WAL: erro no checkpoint: %v", err)
+	}
+	return unfinished, nil
+}
+
+func recordFromPayment(p *repository.Payment) Record {
+	eventType := EVENT_SUCCESS
+	if p.Status == "failed" {
+		eventType = EVENT_FAILURE
+	}
+	return Record{
+		Type:          eventType,
+		CorrelationID: p.CorrelationID,
+		Amount:        p.Amount,
+		PaymentID:     p.PaymentID,
+		Processor:     p.PaymentProcessor,
+		Status:        p.Status,
+		Fee:           p.Fee,
+		Error:         p.ErrorMessage,
+		ProcessedAt:   p.ProcessedAt,
+		CreatedAt:     p.CreatedAt,
+		RequestedAt:   p.RequestedAt,
+		LatencyMs:     p.LatencyMs,
+		Failover:      p.Failover,
+	}
+}
+
+func paymentFromRecord(record Record) *repository.Payment {
+	return &repository.Payment{
+		PaymentID:        record.PaymentID,
+		CorrelationID:    record.CorrelationID,
+		PaymentProcessor: record.Processor,
+		Amount:           record.Amount,
+		Status:           record.Status,
+		Fee:              record.Fee,
+		ErrorMessage:     record.Error,
+		ProcessedAt:      record.ProcessedAt,
+		CreatedAt:        record.CreatedAt,
+		RequestedAt:      record.RequestedAt,
+		LatencyMs:        record.LatencyMs,
+		Failover:         record.Failover,
+	}
+}
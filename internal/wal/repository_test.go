@@ -0,0 +1,197 @@
+package wal
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"rinha-de-backend-2025/internal/payment"
+	"rinha-de-backend-2025/internal/repository"
+)
+
+func paymentRecord(correlationID, status string) *repository.Payment {
+	now := time.Unix(1750000000, 0).UTC()
+	return &repository.Payment{
+		PaymentID:        "pay-" + correlationID,
+		CorrelationID:    correlationID,
+		PaymentProcessor: "default",
+		Amount:           19.9,
+		Status:           status,
+		ProcessedAt:      now,
+		CreatedAt:        now,
+		RequestedAt:      now,
+	}
+}
+
+func request(correlationID string) payment.PaymentRequest {
+	return payment.PaymentRequest{CorrelationID: correlationID, Amount: 19.9, RequestedAt: time.Unix(1750000000, 0).UTC()}
+}
+
+// crashedRun grava o WAL de uma execução que caiu antes de qualquer registro chegar ao
+// repositório: "ok" teve sucesso, "pending" ficou sem resultado e "dead" falhou. Os pins
+// da execução não sobrevivem ao crash, então ela grava sem Retention.
+func crashedRun(t *testing.T, cfg Config) {
+	t.Helper()
+	cfg.Retention = false
+	l := openLog(t, cfg)
+	defer l.Close()
+
+	for _, record := range []Record{
+		accepted("ok"),
+		recordFromPayment(paymentRecord("ok", "processed")),
+		accepted("pending"),
+		accepted("dead"),
+		recordFromPayment(paymentRecord("dead", "failed")),
+	} {
+		if err := l.Append(record); err != nil {
+			t.Fatalf("Append: %v", err)
+		}
+	}
+}
+
+func recoverRun(t *testing.T, cfg Config, inner repository.PaymentRepository) (*Repository, []payment.PaymentRequest) {
+	t.Helper()
+	journal := NewRepository(inner, openLog(t, cfg))
+	pending, err := journal.Recover(context.Background())
+	if err != nil {
+		t.Fatalf("Recover: %v", err)
+	}
+	return journal, pending
+}
+
+func TestRecoverReplaysIntoRepository(t *testing.T) {
+	cfg := testConfig(t)
+	cfg.SegmentSize = 1
+	crashedRun(t, cfg)
+
+	inner := repository.NewMemoryPaymentRepository()
+	journal, pending := recoverRun(t, cfg, inner)
+	defer journal.log.Close()
+
+	if len(pending) != 1 || pending[0].CorrelationID != "pending" {
+		t.Fatalf("pendentes = %+v, want só o pending", pending)
+	}
+	for _, id := range []string{"pay-ok", "pay-dead"} {
+		if _, err := inner.FindByID(context.Background(), id); err != nil {
+			t.Fatalf("%s não restaurado no repositório: %v", id, err)
+		}
+	}
+
+	// Sem Retention (ledger em memória) nenhum segmento é apagado
+	if got := replayIDs(t, cfg.Dir); len(got) != 5 {
+		t.Fatalf("Replay = %v, want os 5 registros", got)
+	}
+}
+
+func TestRecoverCheckpointsPersistedSegments(t *testing.T) {
+	cfg := testConfig(t)
+	cfg.SegmentSize = 1
+	cfg.Retention = true
+	crashedRun(t, cfg)
+
+	inner := repository.NewMemoryPaymentRepository()
+	journal, pending := recoverRun(t, cfg, inner)
+	if len(pending) != 1 {
+		t.Fatalf("pendentes = %+v, want 1", pending)
+	}
+
+	// O "ok" já está no repositório; o aceite do pending (e o que vem depois) fica
+	if got := replayIDs(t, cfg.Dir); !equalStrings(got, []string{"pending", "dead", "dead"}) {
+		t.Fatalf("Replay após o checkpoint = %v, want [pending dead dead]", got)
+	}
+
+	// A retomada conclui o pending: nada mais a reaplicar
+	if err := journal.Save(context.Background(), paymentRecord("pending", "processed")); err != nil {
+		t.Fatalf("Save: %v", err)
+	}
+	if _, err := journal.log.Checkpoint(); err != nil {
+		t.Fatalf("Checkpoint: %v", err)
+	}
+	if got := replayIDs(t, cfg.Dir); len(got) != 0 {
+		t.Fatalf("Replay após a retomada = %v, want vazio", got)
+	}
+
+	// A próxima inicialização não retoma nem duplica nada
+	journal.log.Close()
+	journal, pending = recoverRun(t, cfg, inner)
+	defer journal.log.Close()
+	if len(pending) != 0 {
+		t.Fatalf("pendentes na reinicialização = %+v, want nenhum", pending)
+	}
+}
+
+func TestRepositoryKeepsSegmentsUntilResult(t *testing.T) {
+	cfg := testConfig(t)
+	cfg.SegmentSize = 1
+	cfg.Retention = true
+
+	journal, _ := recoverRun(t, cfg, repository.NewMemoryPaymentRepository())
+	defer journal.log.Close()
+	ctx := context.Background()
+
+	if err := journal.RecordAccepted(request("slow")); err != nil {
+		t.Fatalf("RecordAccepted: %v", err)
+	}
+	for _, id := range []string{"a", "b"} {
+		if err := journal.RecordAccepted(request(id)); err != nil {
+			t.Fatalf("RecordAccepted: %v", err)
+		}
+		if err := journal.Save(ctx, paymentRecord(id, "processed")); err != nil {
+			t.Fatalf("Save: %v", err)
+		}
+	}
+
+	// O aceite do slow segura o seu segmento e os seguintes
+	if got := replayIDs(t, cfg.Dir); len(got) == 0 || got[0] != "slow" {
+		t.Fatalf("Replay = %v, want começando pelo aceite do slow", got)
+	}
+
+	if err := journal.Save(ctx, paymentRecord("slow", "processed")); err != nil {
+		t.Fatalf("Save: %v", err)
+	}
+	if _, err := journal.log.Checkpoint(); err != nil {
+		t.Fatalf("Checkpoint: %v", err)
+	}
+	if got := replayIDs(t, cfg.Dir); len(got) != 0 {
+		t.Fatalf("Replay = %v, want vazio", got)
+	}
+}
+
+func TestRejectedWritesDoNotHoldSegments(t *testing.T) {
+	cfg := testConfig(t)
+	cfg.SegmentSize = 1
+	cfg.Retention = true
+
+	journal, _ := recoverRun(t, cfg, repository.NewMemoryPaymentRepository())
+	defer journal.log.Close()
+	ctx := context.Background()
+
+	if err := journal.Save(ctx, paymentRecord("dead", "failed")); err != nil {
+		t.Fatalf("Save: %v", err)
+	}
+	if err := journal.MarkResolved(ctx, "pay-dead", repository.RESOLUTION_DISCARDED); err != nil {
+		t.Fatalf("MarkResolved: %v", err)
+	}
+
+	// Id desconhecido e dead letter já resolvida: recusados antes de chegar ao WAL
+	for _, id := range []string{"pay-unknown", "pay-dead"} {
+		if err := journal.MarkResolved(ctx, id, repository.RESOLUTION_DISCARDED); !errors.Is(err, repository.ErrNotPending) {
+			t.Fatalf("MarkResolved(%s) = %v, want ErrNotPending", id, err)
+		}
+	}
+	// Save repetido: vai ao WAL, o repositório recusa e o segmento é solto
+	if err := journal.Save(ctx, paymentRecord("dead", "failed")); !errors.Is(err, repository.ErrDuplicate) {
+		t.Fatalf("Save repetido = %v, want ErrDuplicate", err)
+	}
+
+	if _, err := journal.log.Checkpoint(); err != nil {
+		t.Fatalf("Checkpoint: %v", err)
+	}
+	if got := replayIDs(t, cfg.Dir); len(got) != 0 {
+		t.Fatalf("Replay após as escritas recusadas = %v, want vazio", got)
+	}
+	if len(journal.log.pins) != 0 {
+		t.Fatalf("pins = %v, want nenhum", journal.log.pins)
+	}
+}
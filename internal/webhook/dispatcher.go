@@ -0,0 +1,408 @@
+package webhook
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+	"sort"
+	"sync"
+	"time"
+
+	"rinha-de-backend-2025/internal/cache"
+	"rinha-de-backend-2025/internal/events"
+)
+
+// Config define a entrega assíncrona dos webhooks
+type Config struct {
+	Workers     int           // Entregas simultâneas por instância
+	QueueSize   int           // Entregas pendentes antes de descartar
+	MaxAttempts int           // Tentativas por entrega (a primeira inclusa)
+	Backoff     time.Duration // Espera antes da 2ª tentativa; dobra a cada falha
+	MaxBackoff  time.Duration // Limite da espera entre tentativas
+	Timeout     time.Duration // Deadline de cada POST ao receptor (via context)
+	LogSize     int           // Tentativas mantidas no log de entregas
+}
+
+// DefaultConfig retorna a configuração padrão dos webhooks
+func DefaultConfig() Config {
+	return Config{
+		Workers:     4,
+		QueueSize:   1024,
+		MaxAttempts: 5,
+		Backoff:     time.Second,
+		MaxBackoff:  time.Minute,
+		Timeout:     5 * time.Second,
+		LogSize:     1000,
+	}
+}
+
+// refreshInterval relê as inscrições caso uma mensagem de pub/sub tenha se perdido
+const refreshInterval = 30 * time.Second
+
+// job é uma entrega de um evento a uma inscrição
+type job struct {
+	id      string
+	sub     *Subscription
+	event   events.Event
+	body    []byte
+	attempt int
+}
+
+// Dispatcher entrega os eventos do bus às inscrições, com retentativas e backoff
+// exponencial, e registra cada tentativa no log de entregas do store. Cada instância
+// entrega os eventos que ela mesma publicou; as inscrições são compartilhadas.
+type Dispatcher struct {
+	cfg        Config
+	store      Store
+	broker     events.Broker
+	client     *http.Client
+	instanceID string
+
+	mu            sync.RWMutex
+	subscriptions []*Subscription
+
+	ctx   context.Context
+	queue chan *job
+}
+
+// NewDispatcher cria o dispatcher de webhooks. O broker avisa as demais instâncias
+// quando as inscrições mudam; o httpClient é o client compartilhado (httpclient.New),
+// sem Timeout global.
+func NewDispatcher(cfg Config, store Store, broker events.Broker, httpClient *http.Client, instanceID string) *Dispatcher {
+	return &Dispatcher{
+		cfg:        cfg,
+		store:      store,
+		broker:     broker,
+		client:     httpClient,
+		instanceID: instanceID,
+		queue:      make(chan *job, cfg.QueueSize),
+	}
+}
+
+// Pending retorna quantas entregas aguardam na fila (retentativas agendadas não entram)
+func (d *Dispatcher) Pending() int {
+	return len(d.queue)
+}
+
+// Start carrega as inscrições e passa a entregar os eventos do bus até o ctx ser cancelado.
+// Retentativas ainda agendadas no encerramento são perdidas.
+func (d *Dispatcher) Start(ctx context.Context, bus *events.Bus) {
+	d.ctx = ctx
+	if err := d.refresh(ctx); err != nil {
+		log.Printf("⚠️ Erro ao carregar webhooks: %v", err)
+	}
+
+	changes, err := d.broker.Subscribe(ctx, cache.CHANNEL_WEBHOOK_CHANGES)
+	if err != nil {
+		log.Printf("⚠️ Pub/sub de webhooks indisponível, relendo a cada %v: %v", refreshInterval, err)
+	}
+	go d.watch(ctx, changes)
+
+	for i := 0; i < d.cfg.Workers; i++ {
+		go d.worker(ctx)
+	}
+
+	received := bus.Subscribe(ctx, d.cfg.QueueSize)
+	go func() {
+		for event := range received.C {
+			d.dispatch(event)
+		}
+	}()
+
+	log.Printf("🪝 Webhooks: %d workers, %d tentativas, backoff %v (máx. %v)",
+		d.cfg.Workers, d.cfg.MaxAttempts, d.cfg.Backoff, d.cfg.MaxBackoff)
+}
+
+// watch relê as inscrições quando outra instância avisa uma mudança (ou periodicamente)
+func (d *Dispatcher) watch(ctx context.Context, changes <-chan []byte) {
+	ticker := time.NewTicker(refreshInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case _, ok := <-changes:
+			if !ok {
+				changes = nil // Canal fechado: segue só com a releitura periódica
+				continue
+			}
+		case <-ticker.C:
+		}
+		if err := d.refresh(ctx); err != nil {
+			log.Printf("⚠️ Erro ao recarregar webhooks: %v", err)
+		}
+	}
+}
+
+// refresh relê as inscrições do store
+func (d *Dispatcher) refresh(ctx context.Context) error {
+	subscriptions, err := d.load(ctx)
+	if err != nil {
+		return err
+	}
+
+	d.mu.Lock()
+	d.subscriptions = subscriptions
+	d.mu.Unlock()
+	return nil
+}
+
+func (d *Dispatcher) load(ctx context.Context) ([]*Subscription, error) {
+	stored, err := d.store.GetWebhooks(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	subscriptions := make([]*Subscription, 0, len(stored))
+	for id, data := range stored {
+		var sub Subscription
+		if err := json.Unmarshal(data, &sub); err != nil {
+			log.Printf("⚠️ Webhook %s inválido no store: %v", id, err)
+			continue
+		}
+		subscriptions = append(subscriptions, &sub)
+	}
+	sort.Slice(subscriptions, func(i, j int) bool { return subscriptions[i].CreatedAt.Before(subscriptions[j].CreatedAt) })
+	return subscriptions, nil
+}
+
+// dispatch enfileira o evento para cada inscrição interessada
+func (d *Dispatcher) dispatch(event events.Event) {
+	d.mu.RLock()
+	subscriptions := d.subscriptions
+	d.mu.RUnlock()
+
+	var body []byte
+	for _, sub := range subscriptions {
+		if !sub.Wants(event.Type) {
+			continue
+		}
+		if body == nil {
+			var err error
+			if body, err = json.Marshal(event); err != nil {
+				log.Printf("❌ Erro ao serializar evento %s: %v", event.ID, err)
+				return
+			}
+		}
+		d.enqueue(&job{id: events.NewID(), sub: sub, event: event, body: body, attempt: 1})
+	}
+}
+
+// enqueue coloca a entrega na fila sem bloquear; com a fila cheia ela é descartada
+func (d *Dispatcher) enqueue(j *job) {
+	select {
+	case d.queue <- j:
+	default:
+		log.Printf("⚠️ Fila de webhooks cheia, entrega %s descartada", j.id)
+		d.record(j, STATUS_DROPPED, 0, fmt.Errorf("fila de entregas cheia"), 0, nil)
+	}
+}
+
+func (d *Dispatcher) worker(ctx context.Context) {
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case j := <-d.queue:
+			d.deliver(ctx, j)
+		}
+	}
+}
+
+// deliver faz uma tentativa de entrega e agenda a próxima em caso de falha
+func (d *Dispatcher) deliver(ctx context.Context, j *job) {
+	start := time.Now()
+	statusCode, err := d.post(ctx, j)
+	duration := time.Since(start)
+
+	if err == nil {
+		d.record(j, STATUS_DELIVERED, statusCode, nil, duration, nil)
+		return
+	}
+	if j.attempt >= d.cfg.MaxAttempts {
+		log.Printf("❌ Webhook %s: entrega %s do evento %s falhou após %d tentativas: %v",
+			j.sub.ID, j.id, j.event.Type, j.attempt, err)
+		d.record(j, STATUS_FAILED, statusCode, err, duration, nil)
+		return
+	}
+
+	wait := d.backoff(j.attempt)
+	nextRetry := time.Now().Add(wait)
+	d.record(j, STATUS_RETRYING, statusCode, err, duration, &nextRetry)
+
+	retry := &job{id: j.id, sub: j.sub, event: j.event, body: j.body, attempt: j.attempt + 1}
+	time.AfterFunc(wait, func() {
+		if ctx.Err() == nil {
+			d.enqueue(retry)
+		}
+	})
+}
+
+// backoff retorna a espera após a tentativa informada: Backoff, 2×Backoff, 4×... até MaxBackoff
+func (d *Dispatcher) backoff(attempt int) time.Duration {
+	wait := d.cfg.Backoff
+	for i := 1; i < attempt && wait < d.cfg.MaxBackoff; i++ {
+		wait *= 2
+	}
+	if wait > d.cfg.MaxBackoff {
+		wait = d.cfg.MaxBackoff
+	}
+	return wait
+}
+
+// post envia o evento assinado; respostas fora de 2xx contam como falha
+func (d *Dispatcher) post(ctx context.Context, j *job) (int, error) {
+	ctx, cancel := context.WithTimeout(ctx, d.cfg.Timeout)
+	defer cancel()
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, j.sub.URL, bytes.NewReader(j.body))
+	if err != nil {
+		return 0, fmt.Errorf("erro ao criar request: %v", err)
+	}
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set(HEADER_SIGNATURE, Sign(j.sub.Secret, time.Now(), j.body))
+	req.Header.Set(HEADER_EVENT, j.event.Type)
+	req.Header.Set(HEADER_DELIVERY, j.id)
+	req.Header.Set(HEADER_WEBHOOK, j.sub.ID)
+
+	resp, err := d.client.Do(req)
+	if err != nil {
+		return 0, fmt.Errorf("erro na requisição HTTP: %v", err)
+	}
+	defer resp.Body.Close()
+	io.Copy(io.Discard, io.LimitReader(resp.Body, 64*1024))
+
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		return resp.StatusCode, fmt.Errorf("receptor respondeu status %d", resp.StatusCode)
+	}
+	return resp.StatusCode, nil
+}
+
+// record grava a tentativa no log de entregas
+func (d *Dispatcher) record(j *job, status string, statusCode int, err error, duration time.Duration, nextRetry *time.Time) {
+	delivery := Delivery{
+		ID:         j.id,
+		WebhookID:  j.sub.ID,
+		URL:        j.sub.URL,
+		EventID:    j.event.ID,
+		EventType:  j.event.Type,
+		Attempt:    j.attempt,
+		Status:     status,
+		StatusCode: statusCode,
+		DurationMs: float64(duration.Microseconds()) / 1000,
+		NextRetry:  nextRetry,
+		Instance:   d.instanceID,
+		Time:       time.Now(),
+	}
+	if err != nil {
+		delivery.Error = err.Error()
+	}
+
+	data, marshalErr := json.Marshal(delivery)
+	if marshalErr != nil {
+		log.Printf("❌ Erro ao serializar entrega de webhook: %v", marshalErr)
+		return
+	}
+
+	// O log usa o contexto da aplicação: a entrega pode ter sido disparada por um request já encerrado
+	ctx, cancel := context.WithTimeout(context.WithoutCancel(d.ctx), 2*time.Second)
+	defer cancel()
+	if err := d.store.AppendWebhookDelivery(ctx, data, d.cfg.LogSize); err != nil {
+		log.Printf("⚠️ Erro ao registrar entrega de webhook: %v", err)
+	}
+}
+
+// Create valida e grava uma nova inscrição, gerando o segredo se não foi informado.
+// A inscrição retornada é a única que traz o segredo.
+func (d *Dispatcher) Create(ctx context.Context, sub Subscription) (*Subscription, error) {
+	if err := sub.Validate(); err != nil {
+		return nil, err
+	}
+	sub.ID = events.NewID()
+	if sub.Secret == "" {
+		sub.Secret = events.NewID() + events.NewID()
+	}
+	sub.CreatedAt = time.Now()
+
+	data, err := json.Marshal(sub)
+	if err != nil {
+		return nil, fmt.Errorf("erro ao serializar webhook: %v", err)
+	}
+	if err := d.store.SetWebhook(ctx, sub.ID, data); err != nil {
+		return nil, err
+	}
+	d.changed(ctx)
+
+	log.Printf("🪝 Webhook %s registrado: %s %v", sub.ID, sub.URL, sub.Events)
+	return &sub, nil
+}
+
+// Delete remove uma inscrição; retorna false se ela não existia
+func (d *Dispatcher) Delete(ctx context.Context, id string) (bool, error) {
+	removed, err := d.store.DeleteWebhook(ctx, id)
+	if err != nil || !removed {
+		return removed, err
+	}
+	d.changed(ctx)
+
+	log.Printf("🪝 Webhook %s removido", id)
+	return true, nil
+}
+
+// changed recarrega as inscrições locais e avisa as demais instâncias
+func (d *Dispatcher) changed(ctx context.Context) {
+	if err := d.refresh(ctx); err != nil {
+		log.Printf("⚠️ Erro ao recarregar webhooks: %v", err)
+	}
+	if err := d.broker.Publish(ctx, cache.CHANNEL_WEBHOOK_CHANGES, []byte("changed")); err != nil {
+		log.Printf("⚠️ Erro ao avisar mudança de webhooks: %v", err)
+	}
+}
+
+// List retorna as inscrições, sem os segredos
+func (d *Dispatcher) List(ctx context.Context) ([]Subscription, error) {
+	subscriptions, err := d.load(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	listed := make([]Subscription, len(subscriptions))
+	for i, sub := range subscriptions {
+		listed[i] = *sub
+		listed[i].Secret = ""
+	}
+	return listed, nil
+}
+
+// Deliveries retorna as últimas tentativas de entrega, mais recentes primeiro,
+// opcionalmente só as de uma inscrição
+func (d *Dispatcher) Deliveries(ctx context.Context, limit int, webhookID string) ([]Delivery, error) {
+	fetch := limit
+	if webhookID != "" {
+		fetch = d.cfg.LogSize // O filtro é aplicado depois da leitura
+	}
+	stored, err := d.store.GetWebhookDeliveries(ctx, fetch)
+	if err != nil {
+		return nil, err
+	}
+
+	deliveries := make([]Delivery, 0, limit)
+	for _, data := range stored {
+		if len(deliveries) >= limit {
+			break
+		}
+		var delivery Delivery
+		if err := json.Unmarshal(data, &delivery); err != nil {
+			continue
+		}
+		if webhookID == "" || delivery.WebhookID == webhookID {
+			deliveries = append(deliveries, delivery)
+		}
+	}
+	return deliveries, nil
+}
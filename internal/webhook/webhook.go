@@ -0,0 +1,136 @@
+package webhook
+
+import (
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+
+	"rinha-de-backend-2025/internal/events"
+)
+
+// Headers enviados em cada entrega
+const (
+	HEADER_SIGNATURE = "X-Rinha-Signature" // t=<unix>,v1=<hex do HMAC-SHA256 de "<unix>.<corpo>">
+	HEADER_EVENT     = "X-Rinha-Event"     // Tipo do evento
+	HEADER_DELIVERY  = "X-Rinha-Delivery"  // ID da entrega (igual em todas as tentativas)
+	HEADER_WEBHOOK   = "X-Rinha-Webhook"   // ID da inscrição
+)
+
+// Status de uma tentativa de entrega no log
+const (
+	STATUS_DELIVERED = "delivered" // Receptor respondeu 2xx
+	STATUS_RETRYING  = "retrying"  // Falhou; nova tentativa agendada
+	STATUS_FAILED    = "failed"    // Falhou na última tentativa
+	STATUS_DROPPED   = "dropped"   // Fila de entregas cheia
+)
+
+// Store guarda as inscrições e o log de entregas (cache.RedisWebhookStore ou
+// cache.MemoryWebhookStore). As mudanças são avisadas pelo events.Broker.
+type Store interface {
+	SetWebhook(ctx context.Context, id string, data []byte) error
+	GetWebhooks(ctx context.Context) (map[string][]byte, error)
+	DeleteWebhook(ctx context.Context, id string) (bool, error)
+	AppendWebhookDelivery(ctx context.Context, data []byte, max int) error
+	GetWebhookDeliveries(ctx context.Context, limit int) ([][]byte, error)
+}
+
+// Subscription é a inscrição de um receptor nos eventos informados (todos, se vazio)
+type Subscription struct {
+	ID          string    `json:"id"`
+	URL         string    `json:"url"`
+	Events      []string  `json:"events,omitempty"`
+	Description string    `json:"description,omitempty"`
+	Secret      string    `json:"secret,omitempty"` // Só retornado na criação
+	CreatedAt   time.Time `json:"created_at"`
+}
+
+// Validate confere a URL e os tipos de evento da inscrição
+func (s *Subscription) Validate() error {
+	parsed, err := url.Parse(s.URL)
+	if err != nil || (parsed.Scheme != "http" && parsed.Scheme != "https") || parsed.Host == "" {
+		return fmt.Errorf("url do webhook inválida: %q (use http:// ou https://)", s.URL)
+	}
+	for _, eventType := range s.Events {
+		if !events.ValidType(eventType) {
+			return fmt.Errorf("tipo de evento desconhecido: %q (use %s)", eventType, strings.Join(events.Types, ", "))
+		}
+	}
+	return nil
+}
+
+// Wants indica se a inscrição recebe o tipo de evento
+func (s *Subscription) Wants(eventType string) bool {
+	if len(s.Events) == 0 {
+		return true
+	}
+	for _, wanted := range s.Events {
+		if wanted == eventType {
+			return true
+		}
+	}
+	return false
+}
+
+// Delivery é uma tentativa de entrega registrada no log
+type Delivery struct {
+	ID         string     `json:"id"`
+	WebhookID  string     `json:"webhook_id"`
+	URL        string     `json:"url"`
+	EventID    string     `json:"event_id"`
+	EventType  string     `json:"event_type"`
+	Attempt    int        `json:"attempt"`
+	Status     string     `json:"status"`
+	StatusCode int        `json:"status_code,omitempty"`
+	Error      string     `json:"error,omitempty"`
+	DurationMs float64    `json:"duration_ms"`
+	NextRetry  *time.Time `json:"next_retry,omitempty"`
+	Instance   string     `json:"instance"`
+	Time       time.Time  `json:"time"`
+}
+
+// Sign monta o header X-Rinha-Signature do corpo no instante informado
+func Sign(secret string, timestamp time.Time, body []byte) string {
+	unix := strconv.FormatInt(timestamp.Unix(), 10)
+	return "t=" + unix + ",v1=" + signature(secret, unix, body)
+}
+
+// Verify confere o header X-Rinha-Signature recebido, recusando assinaturas mais
+// antigas que tolerance (proteção contra replay). Para uso dos receptores.
+func Verify(secret, header string, body []byte, tolerance time.Duration) error {
+	var unix, received string
+	for _, part := range strings.Split(header, ",") {
+		key, value, _ := strings.Cut(part, "=")
+		switch key {
+		case "t":
+			unix = value
+		case "v1":
+			received = value
+		}
+	}
+
+	seconds, err := strconv.ParseInt(unix, 10, 64)
+	if err != nil || received == "" {
+		return fmt.Errorf("assinatura mal formada: %q", header)
+	}
+	if age := time.Since(time.Unix(seconds, 0)); age > tolerance || age < -tolerance {
+		return fmt.Errorf("assinatura fora da tolerância: %v", age.Round(time.Second))
+	}
+	if !hmac.Equal([]byte(received), []byte(signature(secret, unix, body))) {
+		return fmt.Errorf("assinatura inválida")
+	}
+	return nil
+}
+
+func signature(secret, unix string, body []byte) string {
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write([]byte(unix))
+	mac.Write([]byte("."))
+	mac.Write(body)
+	return hex.EncodeToString(mac.Sum(nil))
+}